@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/dataset"
+	"github.com/JosephJoshua/rvm/backend/internal/dataset/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/env"
+)
+
+// exportCommandName is the subcommand that writes a training dataset instead of running the server.
+const exportCommandName = "export-dataset"
+
+const exportDateLayout = "2006-01-02"
+
+type exportOptions struct {
+	path    string
+	format  string
+	from    string
+	to      string
+	sources string
+	machine int
+}
+
+// runExportCommand writes a training dataset of labeled images to a zip archive,
+// given the arguments after the subcommand's name: flags followed by the archive's path.
+func runExportCommand(args []string) error {
+	opts, err := parseExportArgs(args)
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): %w", err)
+	}
+
+	format, err := domain.NewFormat(opts.format)
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): %w", err)
+	}
+
+	filter, err := parseExportFilter(opts)
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): %w", err)
+	}
+
+	dbHandle, err := openDB()
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): failed to initialize db: %w", err)
+	}
+
+	defer dbHandle.Close()
+
+	migrator, err := db.NewMigrator(dbHandle)
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): failed to initialize migrator: %w", err)
+	}
+
+	if _, err = migrator.Up(); err != nil {
+		return fmt.Errorf("runExportCommand(): failed to migrate db: %w", err)
+	}
+
+	blobStore, err := blob.NewFSStore(env.GetBlobStoreDir())
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): failed to initialize blob store: %w", err)
+	}
+
+	e := dataset.NewExporter(dataset.NewSQLRepository(dbHandle), blobStore)
+
+	f, err := os.Create(opts.path)
+	if err != nil {
+		return fmt.Errorf("runExportCommand(): failed to create %s: %w", opts.path, err)
+	}
+
+	m, err := e.Export(context.Background(), f, format, filter)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close %s: %w", opts.path, closeErr)
+	}
+
+	if err != nil {
+		// A partial archive would look like a smaller dataset rather than a failed export.
+		os.Remove(opts.path)
+		return fmt.Errorf("runExportCommand(): %w", err)
+	}
+
+	slog.Default().Info(
+		"exported dataset",
+		slog.String("path", opts.path),
+		slog.Int("images", len(m.Files)),
+		slog.Int("classes", len(m.Classes)),
+		slog.Int("missing", m.Missing),
+	)
+
+	return nil
+}
+
+// parseExportArgs parses the subcommand's own flags. The dates are inclusive, the sources are
+// comma-separated and a machine of 0 exports every machine.
+func parseExportArgs(args []string) (exportOptions, error) {
+	var opts exportOptions
+
+	fs := flag.NewFlagSet(exportCommandName, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: backend %s [flags] <zip file>\n", exportCommandName)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&opts.format, "format", "folder", "dataset format: folder (one folder per class) or coco")
+	fs.StringVar(&opts.from, "from", "", "only exports images captured on or after this date (YYYY-MM-DD)")
+	fs.StringVar(&opts.to, "to", "", "only exports images captured on or before this date (YYYY-MM-DD)")
+	fs.StringVar(
+		&opts.sources,
+		"sources",
+		"approved,relabeled",
+		"comma-separated label sources to export: approved, relabeled and/or classifier",
+	)
+	fs.IntVar(&opts.machine, "machine", 0, "only exports images from the machine with this id")
+
+	if err := fs.Parse(args); err != nil {
+		return exportOptions{}, err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exportOptions{}, fmt.Errorf("expected the zip file's path, got %d arguments", fs.NArg())
+	}
+
+	opts.path = fs.Arg(0)
+
+	return opts, nil
+}
+
+func parseExportFilter(opts exportOptions) (domain.Filter, error) {
+	var filter domain.Filter
+
+	if opts.from != "" {
+		from, err := time.ParseInLocation(exportDateLayout, opts.from, time.Local)
+		if err != nil {
+			return domain.Filter{}, fmt.Errorf("invalid from date: %w", err)
+		}
+
+		filter.From = &from
+	}
+
+	if opts.to != "" {
+		to, err := time.ParseInLocation(exportDateLayout, opts.to, time.Local)
+		if err != nil {
+			return domain.Filter{}, fmt.Errorf("invalid to date: %w", err)
+		}
+
+		// The filter's upper bound is exclusive, so include the whole of the last day.
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+
+	if opts.machine != 0 {
+		machineID := opts.machine
+		filter.MachineID = &machineID
+	}
+
+	for _, s := range strings.Split(opts.sources, ",") {
+		source, err := domain.NewLabelSource(strings.TrimSpace(s))
+		if err != nil {
+			return domain.Filter{}, err
+		}
+
+		filter.Sources = append(filter.Sources, source)
+	}
+
+	return filter, nil
+}
@@ -4,26 +4,43 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
 	"github.com/JosephJoshua/rvm/backend/internal/auth"
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/capture"
+	capturedomain "github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/classification"
 	"github.com/JosephJoshua/rvm/backend/internal/db"
 	"github.com/JosephJoshua/rvm/backend/internal/env"
 	"github.com/JosephJoshua/rvm/backend/internal/firebase"
+	"github.com/JosephJoshua/rvm/backend/internal/firmware"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/item"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
 	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/JosephJoshua/rvm/backend/internal/machine"
+	"github.com/JosephJoshua/rvm/backend/internal/merchant"
+	"github.com/JosephJoshua/rvm/backend/internal/openapi"
+	"github.com/JosephJoshua/rvm/backend/internal/pubsub"
+	"github.com/JosephJoshua/rvm/backend/internal/review"
+	"github.com/JosephJoshua/rvm/backend/internal/reward"
 	"github.com/JosephJoshua/rvm/backend/internal/transaction"
+	transactiondomain "github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
 	"github.com/JosephJoshua/rvm/backend/internal/user"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/httplog/v2"
-	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 )
 
@@ -31,15 +48,30 @@ const ReadHeaderTimeoutSecs = 3
 const GracefulTimeoutSecs = 30
 const CORSMaxAge = 300
 
+// TransactionEventBufferSize is how many events a transaction's subscriber can fall behind before it is dropped.
+const TransactionEventBufferSize = 16
+
 func main() {
 	loadDotEnv()
 
+	// Subcommands have flags of their own, so they're dispatched before the server's flags are parsed.
+	if len(os.Args) > 1 && os.Args[1] == exportCommandName {
+		if err := runExportCommand(os.Args[2:]); err != nil {
+			slog.Default().Error("failed to export dataset", logging.ErrAttr(err))
+		}
+
+		return
+	}
+
 	seedFlag := flag.Bool("seed", false, "seeds the database with initial data")
+	migrateFlag := flag.String("migrate", "", "runs a migration command (up, down or status) and exits")
+	reconcileFlag := flag.Bool("reconcile", false, "checks point balances against the ledger and exits")
+
 	flag.Parse()
 
 	slog.Default().Info("initializing db..")
 
-	dbHandle, err := db.NewDB(env.GetDBPath())
+	dbHandle, err := openDB()
 	if err != nil {
 		slog.Default().Error("failed to initialize db", logging.ErrAttr(err))
 		return
@@ -48,13 +80,37 @@ func main() {
 	slog.Default().Info("initialized db")
 	defer dbHandle.Close()
 
+	migrator, err := db.NewMigrator(dbHandle)
+	if err != nil {
+		slog.Default().Error("failed to initialize migrator", logging.ErrAttr(err))
+		return
+	}
+
+	if *migrateFlag != "" {
+		if err = runMigrateCommand(migrator, *migrateFlag); err != nil {
+			slog.Default().Error("failed to run migrate command", logging.ErrAttr(err))
+		}
+
+		return
+	}
+
 	slog.Default().Info("migrating db schema..")
-	if err = db.MigrateDB(dbHandle); err != nil {
+
+	applied, err := migrator.Up()
+	if err != nil {
 		slog.Default().Error("failed to migrate db", logging.ErrAttr(err))
 		return
 	}
 
-	slog.Default().Info("migrated db schema")
+	slog.Default().Info("migrated db schema", slog.Int("applied", len(applied)))
+
+	if *reconcileFlag {
+		if err = runReconcileCommand(ledger.NewService(ledger.NewSQLRepository(dbHandle))); err != nil {
+			slog.Default().Error("failed to reconcile points", logging.ErrAttr(err))
+		}
+
+		return
+	}
 
 	if *seedFlag {
 		slog.Default().Info("seeding db..")
@@ -79,17 +135,258 @@ func main() {
 		return
 	}
 
+	machineService, err := newMachineService(dbHandle)
+	if err != nil {
+		slog.Default().Error("failed to initialize machine service", logging.ErrAttr(err))
+		return
+	}
+
+	transactionEvents := pubsub.NewHub[transactiondomain.Event](TransactionEventBufferSize)
+
+	transactionService, err := newTransactionService(dbHandle, machineService, transactionEvents)
+	if err != nil {
+		slog.Default().Error("failed to initialize transaction service", logging.ErrAttr(err))
+		return
+	}
+
+	classifier, err := newClassifier()
+	if err != nil {
+		slog.Default().Error("failed to initialize classifier", logging.ErrAttr(err))
+		return
+	}
+
+	captureBridge, closeCaptureBridge, err := newCaptureBridge()
+	if err != nil {
+		slog.Default().Error("failed to initialize capture bridge", logging.ErrAttr(err))
+		return
+	}
+
+	defer closeCaptureBridge()
+
+	stopWorkers, err := startWorkers(transactionService, machineService)
+	if err != nil {
+		slog.Default().Error("failed to start background workers", logging.ErrAttr(err))
+		return
+	}
+
+	fusionStrategy, err := capturedomain.NewFusionStrategy(env.GetCaptureFusionStrategy())
+	if err != nil {
+		slog.Default().Error("failed to get capture fusion strategy", logging.ErrAttr(err))
+		return
+	}
+
+	blobStore, err := blob.NewFSStore(env.GetBlobStoreDir())
+	if err != nil {
+		slog.Default().Error("failed to initialize blob store", logging.ErrAttr(err))
+		return
+	}
+
+	reviewThreshold, err := env.GetReviewConfidenceThreshold()
+	if err != nil {
+		slog.Default().Error("failed to get review confidence threshold", logging.ErrAttr(err))
+		return
+	}
+
+	router, err := getRouter(
+		dbHandle,
+		firebaseApp,
+		transactionService,
+		machineService,
+		classifier,
+		captureBridge,
+		fusionStrategy,
+		blobStore,
+		reviewThreshold,
+	)
+	if err != nil {
+		slog.Default().Error("failed to initialize router", logging.ErrAttr(err))
+		return
+	}
+
 	server := &http.Server{
-		Handler:           getRouter(dbHandle, firebaseApp),
+		Handler:           router,
 		Addr:              "0.0.0.0:3123",
 		ReadHeaderTimeout: ReadHeaderTimeoutSecs * time.Second,
 	}
 
+	// Event streams never end on their own, so they'd hold up the graceful shutdown.
+	server.RegisterOnShutdown(transactionEvents.Close)
+
 	slog.Default().Info("running server..", slog.String("addr", server.Addr))
-	runServer(server)
+	runServer(server, stopWorkers)
 }
 
-func runServer(server *http.Server) {
+func openDB() (*db.DB, error) {
+	dialect, err := db.NewDialect(env.GetDBDriver())
+	if err != nil {
+		return nil, fmt.Errorf("openDB(): failed to get dialect: %w", err)
+	}
+
+	dsn := env.GetDBPath()
+
+	if dialect == db.DialectPostgres {
+		dsn, err = env.GetDBURL()
+		if err != nil {
+			return nil, fmt.Errorf("openDB(): failed to get db url: %w", err)
+		}
+	}
+
+	dbHandle, err := db.NewDB(dialect, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("openDB(): %w", err)
+	}
+
+	return dbHandle, nil
+}
+
+func newTransactionService(
+	dbHandle *db.DB,
+	machineService *machine.Service,
+	events *pubsub.Hub[transactiondomain.Event],
+) (*transaction.Service, error) {
+	claimWindow, err := env.GetTransactionClaimWindow()
+	if err != nil {
+		return nil, fmt.Errorf("newTransactionService(): failed to get claim window: %w", err)
+	}
+
+	sessionTimeout, err := env.GetTransactionSessionTimeout()
+	if err != nil {
+		return nil, fmt.Errorf("newTransactionService(): failed to get session timeout: %w", err)
+	}
+
+	return transaction.NewService(
+		transaction.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) transaction.Repositories {
+			return transaction.Repositories{
+				Transactions: transaction.NewSQLRepository(q),
+				Ledger:       ledger.NewSQLRepository(q),
+			}
+		}),
+		transaction.NewUUIDIDGenerator(),
+		machineService,
+		events,
+		transaction.Config{
+			ClaimWindow:    claimWindow,
+			SessionTimeout: sessionTimeout,
+		},
+	), nil
+}
+
+func newMachineService(dbHandle *db.DB) (*machine.Service, error) {
+	gracePeriod, err := env.GetMachineHeartbeatGracePeriod()
+	if err != nil {
+		return nil, fmt.Errorf("newMachineService(): failed to get heartbeat grace period: %w", err)
+	}
+
+	binAlertThreshold, err := env.GetBinAlertThreshold()
+	if err != nil {
+		return nil, fmt.Errorf("newMachineService(): failed to get bin alert threshold: %w", err)
+	}
+
+	return machine.NewService(
+		machine.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) machine.Repository {
+			return machine.NewSQLRepository(q)
+		}),
+		apitoken.NewRandomTokenGenerator(),
+		machine.Config{
+			HeartbeatGracePeriod: gracePeriod,
+			BinAlertThreshold:    binAlertThreshold,
+		},
+	), nil
+}
+
+func newClassifier() (classification.Classifier, error) {
+	switch name := env.GetClassifier(); name {
+	case "stub":
+		itemIDs, err := env.GetStubClassifierItemIDs()
+		if err != nil {
+			return nil, fmt.Errorf("newClassifier(): failed to get stub item ids: %w", err)
+		}
+
+		c, err := classification.NewStubClassifier(itemIDs)
+		if err != nil {
+			return nil, fmt.Errorf("newClassifier(): %w", err)
+		}
+
+		return c, nil
+	default:
+		return nil, fmt.Errorf("newClassifier(): unknown classifier %q", name)
+	}
+}
+
+// newCaptureBridge connects to the camera modules' MQTT broker and returns the bridge along with
+// a function that disconnects it. The bridge is nil when no broker is configured.
+func newCaptureBridge() (*capture.Bridge, func(), error) {
+	brokerURL := env.GetMQTTBrokerURL()
+	if brokerURL == "" {
+		slog.Default().Info("MQTT_BROKER_URL not set, capturing is disabled")
+		return nil, func() {}, nil
+	}
+
+	timeout, err := env.GetCaptureTimeout()
+	if err != nil {
+		return nil, nil, fmt.Errorf("newCaptureBridge(): failed to get capture timeout: %w", err)
+	}
+
+	client, err := capture.NewPahoClient(capture.PahoConfig{
+		BrokerURL: brokerURL,
+		ClientID:  env.GetMQTTClientID(),
+		Username:  env.GetMQTTUsername(),
+		Password:  env.GetMQTTPassword(),
+	}, slog.Default())
+	if err != nil {
+		return nil, nil, fmt.Errorf("newCaptureBridge(): %w", err)
+	}
+
+	bridge, err := capture.NewBridge(
+		client,
+		capture.BridgeConfig{Sides: env.GetCaptureSides(), Timeout: timeout},
+		slog.Default(),
+	)
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("newCaptureBridge(): %w", err)
+	}
+
+	return bridge, client.Close, nil
+}
+
+// startWorkers starts the background workers and returns a function that stops them
+// and waits for them to finish.
+func startWorkers(transactionService *transaction.Service, machineService *machine.Service) (func(), error) {
+	sweepInterval, err := env.GetTransactionSweepInterval()
+	if err != nil {
+		return nil, fmt.Errorf("startWorkers(): failed to get sweep interval: %w", err)
+	}
+
+	monitorInterval, err := env.GetMachineMonitorInterval()
+	if err != nil {
+		return nil, fmt.Errorf("startWorkers(): failed to get machine monitor interval: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		transaction.NewSweeper(transactionService, sweepInterval, slog.Default()).Run(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		machine.NewMonitor(machineService, monitorInterval, slog.Default()).Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}, nil
+}
+
+func runServer(server *http.Server, stopWorkers func()) {
 	serverCtx, stopServerCtx := context.WithCancel(context.Background())
 
 	sig := make(chan os.Signal, 1)
@@ -113,6 +410,9 @@ func runServer(server *http.Server) {
 			slog.Default().Error("failed to shutdown server", logging.ErrAttr(err))
 		}
 
+		slog.Default().Info("stopping background workers..")
+		stopWorkers()
+
 		stopServerCtx()
 	}()
 
@@ -123,15 +423,30 @@ func runServer(server *http.Server) {
 	<-serverCtx.Done()
 }
 
-func getRouter(dbHandle *sqlx.DB, firebaseApp *firebase.App) http.Handler {
+func getRouter(
+	dbHandle *db.DB,
+	firebaseApp *firebase.App,
+	transactionService *transaction.Service,
+	machineService *machine.Service,
+	classifier classification.Classifier,
+	captureBridge *capture.Bridge,
+	fusionStrategy capturedomain.FusionStrategy,
+	blobStore blob.Store,
+	reviewThreshold float64,
+) (http.Handler, error) {
 	logger := logging.NewRequestLogger(env.GetAppEnv())
 
-	r := chi.NewRouter()
+	spec, err := openapi.NewSpec()
+	if err != nil {
+		return nil, fmt.Errorf("getRouter(): %w", err)
+	}
+
+	r := httputils.NewRouter()
 
 	r.Use(middleware.StripSlashes)
 	r.Use(httplog.RequestLogger(logger, []string{"/ping"}))
 	r.Use(middleware.Heartbeat("/ping"))
-	r.Use(middleware.SetHeader("Content-Type", "text/plain"))
+	r.Use(httputils.JSONFormMiddleware)
 
 	r.Use(cors.Handler(cors.Options{
 		// TODO: change this to the actual frontend url
@@ -143,6 +458,10 @@ func getRouter(dbHandle *sqlx.DB, firebaseApp *firebase.App) http.Handler {
 		MaxAge:           CORSMaxAge,
 	}))
 
+	// Each group validates requests only after authenticating them, so that unauthenticated clients
+	// get a 401 instead of learning what the spec expects from a 400.
+	validRequest := openapi.ValidRequestMiddleware(spec)
+
 	authService := auth.NewService(
 		auth.NewSQLRepository(dbHandle),
 		auth.NewFirebaseAuthProvider(firebaseApp),
@@ -152,32 +471,140 @@ func getRouter(dbHandle *sqlx.DB, firebaseApp *firebase.App) http.Handler {
 		user.NewSQLRepository(dbHandle),
 	)
 
-	transactionService := transaction.NewService(
-		transaction.NewSQLRepository(dbHandle),
-		transaction.NewUUIDIDGenerator(),
-	)
-
 	apiTokenService := apitoken.NewService(
 		apitoken.NewSQLRepository(dbHandle),
 	)
 
+	rewardService := reward.NewService(
+		reward.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) reward.Repositories {
+			return reward.Repositories{
+				Rewards: reward.NewSQLRepository(q),
+				Ledger:  ledger.NewSQLRepository(q),
+			}
+		}),
+		reward.NewRandomVoucherCodeGenerator(),
+	)
+
+	itemService := item.NewService(
+		item.NewSQLRepository(dbHandle),
+	)
+
+	voucherService := voucher.NewService(
+		voucher.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) voucher.Repository {
+			return voucher.NewSQLRepository(q)
+		}),
+	)
+
+	merchantService := merchant.NewService(
+		merchant.NewSQLRepository(dbHandle),
+		apitoken.NewRandomTokenGenerator(),
+	)
+
+	classificationService := classification.NewService(classifier)
+
+	captureService := capture.NewService(
+		capture.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) capture.Repository {
+			return capture.NewSQLRepository(q)
+		}),
+		captureBridge,
+		classificationService,
+		blobStore,
+		fusionStrategy,
+		slog.Default(),
+	)
+
+	reviewService := review.NewService(
+		review.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) review.Repositories {
+			return review.Repositories{
+				Reviews: review.NewSQLRepository(q),
+				Ledger:  ledger.NewSQLRepository(q),
+			}
+		}),
+		blobStore,
+		review.Config{ConfidenceThreshold: reviewThreshold},
+	)
+
+	firmwareService := firmware.NewService(
+		firmware.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) firmware.Repository {
+			return firmware.NewSQLRepository(q)
+		}),
+		blobStore,
+	)
+
 	transactionHandler := transaction.NewHTTPHandler(transactionService)
 	authHandler := auth.NewHTTPHandler(authService)
 	userHandler := user.NewHTTPHandler(userService)
+	rewardHandler := reward.NewHTTPHandler(rewardService)
+	redemptionHandler := reward.NewRedemptionHTTPHandler(rewardService)
+	voucherHandler := voucher.NewHTTPHandler(voucherService)
+	merchantHandler := merchant.NewHTTPHandler(merchantService)
+	itemHandler := item.NewHTTPHandler(itemService)
+	machineHandler := machine.NewHTTPHandler(machineService)
+	heartbeatHandler := machine.NewHeartbeatHTTPHandler(machineService)
+	machineConfigHandler := machine.NewConfigHTTPHandler(machineService)
+	classificationHandler := classification.NewHTTPHandler(classificationService)
+	captureHandler := capture.NewHTTPHandler(captureService)
+	captureAuditHandler := capture.NewAuditHTTPHandler(captureService)
+	reviewHandler := review.NewHTTPHandler(reviewService)
+	firmwareHandler := firmware.NewHTTPHandler(firmwareService)
+	firmwareUpdateHandler := firmware.NewUpdateHTTPHandler(firmwareService)
+
+	r.Group(func(r chi.Router) {
+		r.Use(validRequest)
+		r.Mount("/openapi.json", openapi.NewHTTPHandler(spec))
+		r.Mount("/auth", authHandler)
 
-	r.Mount("/auth", authHandler)
+		// The camera module can't send credentials, so this is left open like /auth.
+		r.Mount("/image-classification", classificationHandler)
+	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(auth.LoggedInMiddleware(authService))
+		r.Use(validRequest)
 		r.Mount("/users", userHandler)
+		r.Mount("/users/rewards", rewardHandler)
+		r.Mount("/users/redemptions", redemptionHandler)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.LoggedInMiddleware(authService))
+		r.Use(auth.AdminOnlyMiddleware(authService))
+		r.Use(validRequest)
+		r.Mount("/items", itemHandler)
+		r.Mount("/machines", machineHandler)
+		r.Mount("/captures/audit", captureAuditHandler)
+		r.Mount("/reviews", reviewHandler)
+		r.Mount("/firmware", firmwareHandler)
+		r.Mount("/merchants", merchantHandler)
 	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(apitoken.ValidTokenMiddleware(apiTokenService))
+		r.Use(validRequest)
 		r.Mount("/transactions", transactionHandler)
+		r.Mount("/captures", captureHandler)
+		r.Mount("/machines/heartbeat", heartbeatHandler)
+		r.Mount("/machines/config", machineConfigHandler)
+		r.Mount("/firmware/updates", firmwareUpdateHandler)
 	})
 
-	return r
+	r.Group(func(r chi.Router) {
+		r.Use(apitoken.MerchantTokenMiddleware(apiTokenService))
+		r.Use(validRequest)
+		r.Mount("/vouchers", voucherHandler)
+	})
+
+	// Startup fails rather than serving routes the apps and the camera module don't know about.
+	if err = spec.CheckRoutes(r); err != nil {
+		return nil, fmt.Errorf("getRouter(): %w", err)
+	}
+
+	return r, nil
 }
 
 func loadDotEnv() {
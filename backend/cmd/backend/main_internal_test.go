@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/classification"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/openapi"
+	"github.com/JosephJoshua/rvm/backend/internal/pubsub"
+	transactiondomain "github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestRouter builds the router the server runs, without Firebase or a capture broker, and returns it
+// along with a token for one of its machines.
+func newTestRouter(t *testing.T) (http.Handler, string) {
+	t.Helper()
+
+	dbHandle := dbtest.Open(t)
+
+	machineService, err := newMachineService(dbHandle)
+	if err != nil {
+		t.Fatalf("failed to create machine service: %v", err)
+	}
+
+	transactionService, err := newTransactionService(
+		dbHandle,
+		machineService,
+		pubsub.NewHub[transactiondomain.Event](TransactionEventBufferSize),
+	)
+	if err != nil {
+		t.Fatalf("failed to create transaction service: %v", err)
+	}
+
+	classifier, err := classification.NewStubClassifier([]int{1})
+	if err != nil {
+		t.Fatalf("failed to create classifier: %v", err)
+	}
+
+	blobStore, err := blob.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	router, err := getRouter(
+		dbHandle,
+		nil,
+		transactionService,
+		machineService,
+		classifier,
+		nil,
+		domain.FusionStrategyMajority,
+		blobStore,
+		0.8,
+	)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+
+	m, err := machineService.CreateMachine("Test machine", "Test location")
+	if err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	token, err := machineService.IssueToken(m.ID, nil)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	return router, token
+}
+
+func TestRouterMatchesSpec(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	spec, err := openapi.NewSpec()
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	routes, ok := router.(chi.Routes)
+	if !ok {
+		t.Fatalf("got router of type %T, want chi.Routes", router)
+	}
+
+	if err = spec.CheckRoutes(routes); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouterAuthenticatesBeforeValidating(t *testing.T) {
+	router, token := newTestRouter(t)
+
+	for _, tc := range []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{name: "unauthenticated item", path: "/items", want: http.StatusUnauthorized},
+		{
+			name: "unauthenticated transaction item",
+			path: "/transactions/7b0f3a52-5d8e-4c0e-9d59-3f1f0b6a4c21/items",
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:  "authenticated transaction item",
+			path:  "/transactions/7b0f3a52-5d8e-4c0e-9d59-3f1f0b6a4c21/items",
+			token: token,
+			want:  http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := url.Values{"item_id": {"abc"}, "points": {"abc"}}.Encode()
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
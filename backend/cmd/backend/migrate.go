@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+)
+
+func runMigrateCommand(migrator *db.Migrator, command string) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, mig := range applied {
+			slog.Default().Info("applied migration", slog.Int("version", mig.Version), slog.String("name", mig.Name))
+		}
+
+		if err != nil {
+			return fmt.Errorf("runMigrateCommand(): %w", err)
+		}
+
+		if len(applied) == 0 {
+			slog.Default().Info("db schema is already up to date")
+		}
+
+		return nil
+
+	case "down":
+		mig, err := migrator.Down()
+		if err != nil {
+			return fmt.Errorf("runMigrateCommand(): %w", err)
+		}
+
+		slog.Default().Info("reverted migration", slog.Int("version", mig.Version), slog.String("name", mig.Name))
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return fmt.Errorf("runMigrateCommand(): %w", err)
+		}
+
+		return printMigrationStatuses(statuses)
+	}
+
+	return fmt.Errorf("runMigrateCommand(): unknown command %q; expected up, down or status", command)
+}
+
+func printMigrationStatuses(statuses []db.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tCHECKSUM")
+
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		checksum := "ok"
+		if !s.ChecksumMatches {
+			checksum = "MISMATCH"
+		}
+
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, checksum)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("printMigrationStatuses(): failed to flush output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+)
+
+var errBalanceMismatch = errors.New("balances don't match the ledger")
+
+// runReconcileCommand prints every user whose points don't add up. It fails only when a balance
+// has drifted from the ledger; earnings that differ from what the transactions' items were credited
+// with are reported without failing.
+func runReconcileCommand(s *ledger.Service) error {
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		return fmt.Errorf("runReconcileCommand(): %w", err)
+	}
+
+	if len(discrepancies) == 0 {
+		slog.Default().Info("point balances are consistent with the ledger")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "USER\tBALANCE\tLEDGER\tEARNED\tDERIVED\tBALANCE CHECK")
+
+	mismatches := 0
+
+	for _, d := range discrepancies {
+		check := "ok"
+		if d.BalanceMismatch() {
+			check = "MISMATCH"
+			mismatches++
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n",
+			d.UserID, d.Balance, d.EntryTotal, d.EarningTotal, d.DerivedEarnings, check)
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("runReconcileCommand(): failed to flush output: %w", err)
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("runReconcileCommand(): %w for %d user(s)", errBalanceMismatch, mismatches)
+	}
+
+	return nil
+}
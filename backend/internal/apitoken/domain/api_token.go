@@ -1,19 +1,53 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 )
 
 type APIToken struct {
-	ID         string
+	ID   string
+	Kind TokenKind
+	// MerchantID is the merchant a merchant token belongs to; it is nil for every other kind.
+	MerchantID *int
+	// MachineID is the machine a machine token belongs to; it is nil for every other kind.
+	MachineID *int
+	// IsHashed is false for the machine tokens that were issued before tokens were hashed,
+	// whose ID is the token itself.
+	IsHashed   bool
 	ExpiringAt *time.Time
 	CreatedAt  time.Time
 }
 
-func NewAPIToken(id string, expiringAt *time.Time, createdAt time.Time) *APIToken {
+func NewAPIToken(
+	id string,
+	kind TokenKind,
+	merchantID *int,
+	machineID *int,
+	isHashed bool,
+	expiringAt *time.Time,
+	createdAt time.Time,
+) *APIToken {
 	return &APIToken{
 		ID:         id,
+		Kind:       kind,
+		MerchantID: merchantID,
+		MachineID:  machineID,
+		IsHashed:   isHashed,
 		ExpiringAt: expiringAt,
 		CreatedAt:  createdAt,
 	}
 }
+
+// IsValidAt reports whether the token has not expired at t.
+func (t *APIToken) IsValidAt(at time.Time) bool {
+	return t.ExpiringAt == nil || !t.ExpiringAt.Before(at)
+}
+
+// HashToken returns what a token is stored as. Only the hash is kept, so that the tokens
+// can't be read back from the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
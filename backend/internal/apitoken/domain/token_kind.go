@@ -0,0 +1,25 @@
+package domain
+
+import "fmt"
+
+type TokenKind string
+
+const (
+	// TokenKindMachine is used by the recycling machines to run transactions.
+	TokenKindMachine TokenKind = "machine"
+	// TokenKindMerchant is used by partner shops to verify and redeem vouchers.
+	TokenKindMerchant TokenKind = "merchant"
+)
+
+func NewTokenKind(value string) (TokenKind, error) {
+	switch k := TokenKind(value); k {
+	case TokenKindMachine, TokenKindMerchant:
+		return k, nil
+	}
+
+	return "", fmt.Errorf("unknown token kind %q", value)
+}
+
+func (k TokenKind) String() string {
+	return string(k)
+}
@@ -1,9 +1,11 @@
 package apitoken
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
 	"github.com/JosephJoshua/rvm/backend/internal/logging"
 	"github.com/go-chi/httplog/v2"
 )
@@ -13,44 +15,107 @@ const (
 	authHeaderParts = 2
 )
 
+type merchantIDCtxKey struct{}
+
+type machineIDCtxKey struct{}
+
+// ValidTokenMiddleware only lets through requests carrying a valid machine token,
+// making the token's machine available through MachineIDFromCtx.
 func ValidTokenMiddleware(s *Service) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		return httputils.HandlerFunc(func(w httputils.ResponseWriter, r *http.Request) {
 			oplog := httplog.LogEntry(r.Context())
 
 			if r.Method == http.MethodOptions {
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w.ResponseWriter, r)
 				return
 			}
 
-			authHeader := r.Header.Get("Authorization")
-			parts := strings.Split(authHeader, bearerPrefix)
-
-			if len(parts) != authHeaderParts {
+			token, ok := bearerToken(r)
+			if !ok {
 				oplog.Error("invalid authorization header format")
-				w.WriteHeader(http.StatusUnauthorized)
+				w.TryWriteProblem(&oplog, http.StatusUnauthorized, httputils.CodeUnauthorized, "missing bearer token")
 
 				return
 			}
 
-			token := strings.TrimSpace(parts[1])
-			ok, err := s.IsValidToken(token)
+			machineID, ok, err := s.GetMachineID(token)
 
 			if err != nil {
 				oplog.Error("failed to validate token", logging.ErrAttr(err))
-				w.WriteHeader(http.StatusInternalServerError)
+				w.TryWriteInternalError(&oplog)
 
 				return
 			}
 
 			if !ok {
 				oplog.Error("invalid token")
-				w.WriteHeader(http.StatusUnauthorized)
+				w.TryWriteProblem(&oplog, http.StatusUnauthorized, httputils.CodeUnauthorized, "invalid token")
+
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), machineIDCtxKey{}, machineID)
+			next.ServeHTTP(w.ResponseWriter, r.WithContext(ctx))
+		})
+	}
+}
+
+// MerchantTokenMiddleware only lets through requests carrying a valid merchant token,
+// making the token's merchant available through MerchantIDFromCtx.
+func MerchantTokenMiddleware(s *Service) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return httputils.HandlerFunc(func(w httputils.ResponseWriter, r *http.Request) {
+			oplog := httplog.LogEntry(r.Context())
+
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w.ResponseWriter, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				oplog.Error("invalid authorization header format")
+				w.TryWriteProblem(&oplog, http.StatusUnauthorized, httputils.CodeUnauthorized, "missing bearer token")
+
+				return
+			}
+
+			merchantID, ok, err := s.GetMerchantID(token)
+
+			if err != nil {
+				oplog.Error("failed to validate merchant token", logging.ErrAttr(err))
+				w.TryWriteInternalError(&oplog)
+
+				return
+			}
+
+			if !ok {
+				oplog.Error("invalid merchant token")
+				w.TryWriteProblem(&oplog, http.StatusUnauthorized, httputils.CodeUnauthorized, "invalid merchant token")
 
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), merchantIDCtxKey{}, merchantID)
+			next.ServeHTTP(w.ResponseWriter, r.WithContext(ctx))
 		})
 	}
 }
+
+func MerchantIDFromCtx(ctx context.Context) int {
+	return ctx.Value(merchantIDCtxKey{}).(int)
+}
+
+func MachineIDFromCtx(ctx context.Context) int {
+	return ctx.Value(machineIDCtxKey{}).(int)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.Split(r.Header.Get("Authorization"), bearerPrefix)
+	if len(parts) != authHeaderParts {
+		return "", false
+	}
+
+	return strings.TrimSpace(parts[1]), true
+}
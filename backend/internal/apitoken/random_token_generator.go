@@ -0,0 +1,25 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+const tokenBytes = 32
+
+// RandomTokenGenerator generates tokens with 256 random bits, hex encoded.
+type RandomTokenGenerator struct{}
+
+func NewRandomTokenGenerator() RandomTokenGenerator {
+	return RandomTokenGenerator{}
+}
+
+func (g RandomTokenGenerator) Generate() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("Generate(): failed to read random bytes: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -12,4 +12,5 @@ var (
 
 type Repository interface {
 	GetTokenByID(tokenID string) (*domain.APIToken, error)
+	IsMachineRetired(machineID int) (bool, error)
 }
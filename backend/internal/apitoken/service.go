@@ -4,6 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
+)
+
+var (
+	ErrMerchantTokenWithoutMerchant = fmt.Errorf("merchant token has no merchant")
+	ErrMachineTokenWithoutMachine   = fmt.Errorf("machine token has no machine")
 )
 
 type Service struct {
@@ -16,20 +23,91 @@ func NewService(r Repository) *Service {
 	}
 }
 
-func (s *Service) IsValidToken(tokenID string) (bool, error) {
-	token, err := s.r.GetTokenByID(tokenID)
+// GetMachineID returns the machine an unexpired machine token belongs to.
+// It reports false if the token doesn't exist, has expired, is not a machine token or its machine is retired.
+func (s *Service) GetMachineID(tokenID string) (int, bool, error) {
+	token, ok, err := s.getValidToken(tokenID, domain.TokenKindMachine)
+	if err != nil {
+		return 0, false, fmt.Errorf("GetMachineID(): %w", err)
+	}
+
+	if !ok {
+		return 0, false, nil
+	}
+
+	if token.MachineID == nil {
+		return 0, false, fmt.Errorf("GetMachineID(): %w", ErrMachineTokenWithoutMachine)
+	}
+
+	retired, err := s.r.IsMachineRetired(*token.MachineID)
+	if err != nil {
+		return 0, false, fmt.Errorf("GetMachineID(): failed to check machine status: %w", err)
+	}
+
+	if retired {
+		return 0, false, nil
+	}
+
+	return *token.MachineID, true, nil
+}
+
+// GetMerchantID returns the merchant an unexpired merchant token belongs to.
+// It reports false if the token doesn't exist, has expired or is not a merchant token.
+func (s *Service) GetMerchantID(tokenID string) (int, bool, error) {
+	token, ok, err := s.getValidToken(tokenID, domain.TokenKindMerchant)
+	if err != nil {
+		return 0, false, fmt.Errorf("GetMerchantID(): %w", err)
+	}
+
+	if !ok {
+		return 0, false, nil
+	}
+
+	if token.MerchantID == nil {
+		return 0, false, fmt.Errorf("GetMerchantID(): %w", ErrMerchantTokenWithoutMerchant)
+	}
+
+	return *token.MerchantID, true, nil
+}
+
+func (s *Service) getValidToken(tokenID string, kind domain.TokenKind) (*domain.APIToken, bool, error) {
+	token, err := s.findToken(tokenID)
 
 	if errors.Is(err, ErrTokenNotFound) {
-		return false, nil
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get token by id: %w", err)
+	}
+
+	if token.Kind != kind || !token.IsValidAt(time.Now()) {
+		return nil, false, nil
+	}
+
+	return token, true, nil
+}
+
+// findToken looks the token up by its hash, falling back to the token itself for the machine tokens
+// that were stored as is before tokens were hashed. A hash read from the database isn't accepted as a token.
+func (s *Service) findToken(tokenID string) (*domain.APIToken, error) {
+	token, err := s.r.GetTokenByID(domain.HashToken(tokenID))
+	if err != nil && !errors.Is(err, ErrTokenNotFound) {
+		return nil, err
+	}
+
+	if err == nil && token.IsHashed {
+		return token, nil
 	}
 
+	token, err = s.r.GetTokenByID(tokenID)
 	if err != nil {
-		return false, fmt.Errorf("IsValidToken(): failed to get token by id: %w", err)
+		return nil, err
 	}
 
-	if token.ExpiringAt != nil && token.ExpiringAt.Before(time.Now()) {
-		return false, nil
+	if token.IsHashed {
+		return nil, ErrTokenNotFound
 	}
 
-	return true, nil
+	return token, nil
 }
@@ -0,0 +1,65 @@
+package apitoken_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+)
+
+const (
+	hashedToken = "b5d4f3c2a1e0f9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4"
+	legacyToken = "0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9"
+)
+
+func TestGetMachineID(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := apitoken.NewService(apitoken.NewSQLRepository(dbHandle))
+
+	machineID := dbtest.CreateMachine(t, dbHandle)
+
+	for _, row := range []struct {
+		id       string
+		isHashed bool
+	}{
+		{id: domain.HashToken(hashedToken), isHashed: true},
+		{id: legacyToken, isHashed: false},
+	} {
+		if _, err := dbHandle.Exec(`
+			INSERT INTO
+				api_tokens (api_token_id, kind, machine_id, is_hashed, created_at)
+			VALUES
+				(?, ?, ?, ?, ?)
+		`, row.id, domain.TokenKindMachine, machineID, row.isHashed, time.Now()); err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{name: "hashed token", token: hashedToken, want: true},
+		{name: "legacy token stored as is", token: legacyToken, want: true},
+		{name: "stored hash", token: domain.HashToken(hashedToken), want: false},
+		{name: "unknown token", token: "unknown", want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok, err := s.GetMachineID(tc.token)
+			if err != nil {
+				t.Fatalf("failed to get machine: %v", err)
+			}
+
+			if ok != tc.want {
+				t.Fatalf("got ok=%v, want %v", ok, tc.want)
+			}
+
+			if ok && got != machineID {
+				t.Errorf("got machine %d, want %d", got, machineID)
+			}
+		})
+	}
+}
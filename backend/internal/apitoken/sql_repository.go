@@ -7,22 +7,27 @@ import (
 	"time"
 
 	"github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
-	"github.com/jmoiron/sqlx"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	machinedomain "github.com/JosephJoshua/rvm/backend/internal/machine/domain"
 )
 
 type apiToken struct {
-	APITokenID string       `db:"api_token_id"`
-	ExpiringAt sql.NullTime `db:"expiring_at"`
-	CreatedAt  sql.NullTime `db:"created_at"`
+	APITokenID string        `db:"api_token_id"`
+	Kind       string        `db:"kind"`
+	MerchantID sql.NullInt64 `db:"merchant_id"`
+	MachineID  sql.NullInt64 `db:"machine_id"`
+	IsHashed   bool          `db:"is_hashed"`
+	ExpiringAt sql.NullTime  `db:"expiring_at"`
+	CreatedAt  sql.NullTime  `db:"created_at"`
 }
 
 type SQLRepository struct {
-	db *sqlx.DB
+	db db.Queryer
 }
 
-func NewSQLRepository(db *sqlx.DB) *SQLRepository {
+func NewSQLRepository(q db.Queryer) *SQLRepository {
 	return &SQLRepository{
-		db: db,
+		db: q,
 	}
 }
 
@@ -30,7 +35,7 @@ func (r *SQLRepository) GetTokenByID(tokenID string) (*domain.APIToken, error) {
 	var rawToken apiToken
 	if err := r.db.Get(&rawToken, `
 		SELECT
-			api_token_id, expiring_at, created_at
+			api_token_id, kind, merchant_id, machine_id, is_hashed, expiring_at, created_at
 		FROM
 			api_tokens
 		WHERE
@@ -48,6 +53,48 @@ func (r *SQLRepository) GetTokenByID(tokenID string) (*domain.APIToken, error) {
 		expiringAt = &rawToken.ExpiringAt.Time
 	}
 
-	token := domain.NewAPIToken(rawToken.APITokenID, expiringAt, rawToken.CreatedAt.Time)
+	kind, err := domain.NewTokenKind(rawToken.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("GetTokenByID(): failed to parse kind: %w", err)
+	}
+
+	var merchantID *int
+	if rawToken.MerchantID.Valid {
+		id := int(rawToken.MerchantID.Int64)
+		merchantID = &id
+	}
+
+	var machineID *int
+	if rawToken.MachineID.Valid {
+		id := int(rawToken.MachineID.Int64)
+		machineID = &id
+	}
+
+	token := domain.NewAPIToken(
+		rawToken.APITokenID,
+		kind,
+		merchantID,
+		machineID,
+		rawToken.IsHashed,
+		expiringAt,
+		rawToken.CreatedAt.Time,
+	)
+
 	return token, nil
 }
+
+func (r *SQLRepository) IsMachineRetired(machineID int) (bool, error) {
+	var count int
+	if err := r.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			machines
+		WHERE
+			machine_id = ? AND status = ?
+	`, machineID, machinedomain.StatusRetired); err != nil {
+		return false, fmt.Errorf("IsMachineRetired(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
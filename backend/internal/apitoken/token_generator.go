@@ -0,0 +1,6 @@
+package apitoken
+
+// TokenGenerator generates the machine and merchant tokens that are handed out to clients.
+type TokenGenerator interface {
+	Generate() (string, error)
+}
@@ -18,10 +18,16 @@ const (
 	authHeaderParts = 2
 )
 
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeInvalidIDToken    = "invalid_id_token"
+	codeUserAlreadyExists = "user_already_exists"
+)
+
 type uidCtxKey struct{}
 
 type HTTPHandler struct {
-	http.Handler
+	chi.Router
 	s *Service
 }
 
@@ -30,10 +36,10 @@ type HTTPHandler struct {
 func NewHTTPHandler(s *Service) *HTTPHandler {
 	handler := &HTTPHandler{s: s}
 
-	r := chi.NewRouter()
+	r := httputils.NewRouter()
 	r.Post("/register", httputils.HandlerFunc(handler.register))
 
-	handler.Handler = r
+	handler.Router = r
 	return handler
 }
 
@@ -45,8 +51,8 @@ func (h *HTTPHandler) register(w httputils.ResponseWriter, r *http.Request) {
 	if idToken == "" {
 		oplog.Error("id_token is empty")
 
-		w.WriteHeader(http.StatusBadRequest)
-		w.TryWrite(&oplog, []byte("id_token is required"))
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, "id_token is required")
+		return
 	}
 
 	err := h.s.Register(idToken)
@@ -54,15 +60,14 @@ func (h *HTTPHandler) register(w httputils.ResponseWriter, r *http.Request) {
 		if errors.Is(err, ErrUserAlreadyExists) {
 			oplog.Info("user already exists", slog.String("id_token", idToken))
 
-			w.WriteHeader(http.StatusConflict)
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeUserAlreadyExists, "user already exists")
 			return
 		}
 
 		if errors.Is(err, ErrInvalidIDToken) {
 			oplog.Error("invalid id token", slog.String("id_token", idToken))
 
-			w.WriteHeader(http.StatusBadRequest)
-			w.TryWrite(&oplog, []byte("invalid id_token"))
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, codeInvalidIDToken, "invalid id_token")
 
 			return
 		}
@@ -70,15 +75,14 @@ func (h *HTTPHandler) register(w httputils.ResponseWriter, r *http.Request) {
 		if errors.Is(err, ErrGetUserFailed) {
 			oplog.Error("failed to get user", slog.String("id_token", idToken))
 
-			w.WriteHeader(http.StatusInternalServerError)
-			w.TryWrite(&oplog, []byte("failed to get user"))
+			w.TryWriteInternalError(&oplog)
 
 			return
 		}
 
 		oplog.Error("failed to register user", logging.ErrAttr(err), slog.String("id_token", idToken))
 
-		w.WriteHeader(http.StatusInternalServerError)
+		w.TryWriteInternalError(&oplog)
 		return
 	}
 
@@ -87,11 +91,11 @@ func (h *HTTPHandler) register(w httputils.ResponseWriter, r *http.Request) {
 
 func LoggedInMiddleware(s *Service) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		return httputils.HandlerFunc(func(w httputils.ResponseWriter, r *http.Request) {
 			oplog := httplog.LogEntry(r.Context())
 
 			if r.Method == http.MethodOptions {
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w.ResponseWriter, r)
 				return
 			}
 
@@ -100,7 +104,7 @@ func LoggedInMiddleware(s *Service) func(next http.Handler) http.Handler {
 
 			if len(parts) != authHeaderParts {
 				oplog.Error("invalid authorization header format")
-				w.WriteHeader(http.StatusUnauthorized)
+				w.TryWriteProblem(&oplog, http.StatusUnauthorized, httputils.CodeUnauthorized, "missing bearer token")
 
 				return
 			}
@@ -111,19 +115,52 @@ func LoggedInMiddleware(s *Service) func(next http.Handler) http.Handler {
 			if err != nil {
 				if errors.Is(err, ErrInvalidIDToken) {
 					oplog.Error("invalid token", slog.String("id_token", token))
-					w.WriteHeader(http.StatusUnauthorized)
+					w.TryWriteProblem(&oplog, http.StatusUnauthorized, codeInvalidIDToken, "invalid token")
 
 					return
 				}
 
 				oplog.Error("failed to get user", slog.String("id_token", token), logging.ErrAttr(err))
-				w.WriteHeader(http.StatusInternalServerError)
+				w.TryWriteInternalError(&oplog)
 
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), uidCtxKey{}, user.ID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w.ResponseWriter, r.WithContext(ctx))
+		})
+	}
+}
+
+// AdminOnlyMiddleware only lets through operators. It must run after LoggedInMiddleware.
+func AdminOnlyMiddleware(s *Service) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return httputils.HandlerFunc(func(w httputils.ResponseWriter, r *http.Request) {
+			oplog := httplog.LogEntry(r.Context())
+
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w.ResponseWriter, r)
+				return
+			}
+
+			uid := UIDFromCtx(r.Context())
+
+			ok, err := s.IsAdmin(uid)
+			if err != nil {
+				oplog.Error("failed to check if user is admin", slog.String("user_id", uid), logging.ErrAttr(err))
+				w.TryWriteInternalError(&oplog)
+
+				return
+			}
+
+			if !ok {
+				oplog.Error("user is not an admin", slog.String("user_id", uid))
+				w.TryWriteProblem(&oplog, http.StatusForbidden, httputils.CodeForbidden, "user is not an admin")
+
+				return
+			}
+
+			next.ServeHTTP(w.ResponseWriter, r)
 		})
 	}
 }
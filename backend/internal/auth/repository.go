@@ -3,4 +3,6 @@ package auth
 type Repository interface {
 	DoesUserExist(uid string) (bool, error)
 	CreateUser(uid string, fullName string, email string) error
+	// IsAdmin reports whether the user is an operator; users that don't exist are not.
+	IsAdmin(uid string) (bool, error)
 }
@@ -71,3 +71,12 @@ func (s *Service) GetUser(idToken string) (*User, error) {
 		Email:    info.Email,
 	}, nil
 }
+
+func (s *Service) IsAdmin(uid string) (bool, error) {
+	ok, err := s.r.IsAdmin(uid)
+	if err != nil {
+		return false, fmt.Errorf("IsAdmin(): failed to check if user is admin: %w", err)
+	}
+
+	return ok, nil
+}
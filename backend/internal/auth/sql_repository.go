@@ -3,16 +3,16 @@ package auth
 import (
 	"fmt"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
 )
 
 type SQLRepository struct {
-	db *sqlx.DB
+	db db.Queryer
 }
 
-func NewSQLRepository(db *sqlx.DB) *SQLRepository {
+func NewSQLRepository(q db.Queryer) *SQLRepository {
 	return &SQLRepository{
-		db: db,
+		db: q,
 	}
 }
 
@@ -44,3 +44,19 @@ func (ar *SQLRepository) CreateUser(uid string, fullName string, email string) e
 
 	return nil
 }
+
+func (ar *SQLRepository) IsAdmin(uid string) (bool, error) {
+	var count int
+	if err := ar.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			users
+		WHERE
+			user_id = ? AND is_admin = ?
+	`, uid, true); err != nil {
+		return false, fmt.Errorf("IsAdmin(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,93 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	fsStoreDirPerm  = 0o750
+	fsStoreFilePerm = 0o640
+)
+
+// FSStore keeps blobs as files under a directory on the local filesystem.
+type FSStore struct {
+	dir string
+}
+
+func NewFSStore(dir string) (*FSStore, error) {
+	if err := os.MkdirAll(dir, fsStoreDirPerm); err != nil {
+		return nil, fmt.Errorf("NewFSStore(): failed to create %s: %w", dir, err)
+	}
+
+	return &FSStore{dir: dir}, nil
+}
+
+// Put writes the blob to a temporary file first so that a half-written blob is never visible under its key.
+func (s *FSStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return fmt.Errorf("Put(): %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), fsStoreDirPerm); err != nil {
+		return fmt.Errorf("Put(): failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Put(): failed to create temporary file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Put(): failed to write blob: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("Put(): failed to close temporary file: %w", err)
+	}
+
+	if err = os.Chmod(tmp.Name(), fsStoreFilePerm); err != nil {
+		return fmt.Errorf("Put(): failed to set permissions: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("Put(): failed to move blob into place: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FSStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, fmt.Errorf("Get(): %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("Get(): %w: %s", ErrBlobDoesNotExist, key)
+		}
+
+		return nil, fmt.Errorf("Get(): failed to read blob: %w", err)
+	}
+
+	return data, nil
+}
+
+// path maps the key to a file under the store's directory, refusing keys that would escape it.
+func (s *FSStore) path(key string) (string, error) {
+	if key == "." || !fs.ValidPath(key) {
+		return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
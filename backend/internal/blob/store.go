@@ -0,0 +1,18 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	ErrBlobDoesNotExist = fmt.Errorf("blob does not exist")
+	ErrInvalidKey       = fmt.Errorf("invalid blob key")
+)
+
+// Store keeps opaque blobs, such as captured images, under slash-separated keys like "captures/<id>/top.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrBlobDoesNotExist if nothing is stored under the key.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
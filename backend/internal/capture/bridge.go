@@ -0,0 +1,197 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/google/uuid"
+)
+
+// These match the topics in the camera module's firmware. A camera answers a capture request by
+// publishing its JPEG to CompleteTopic/<side>/<request id>.
+const (
+	RequestTopic  = "capture/request"
+	CompleteTopic = "capture/complete"
+)
+
+var (
+	ErrNoSides         = fmt.Errorf("at least one camera side is required")
+	ErrCaptureTimedOut = fmt.Errorf("no camera answered the capture request in time")
+)
+
+type BridgeConfig struct {
+	// Sides are the camera sides that are expected to answer every capture request.
+	Sides []string
+	// Timeout is how long to wait for the cameras to answer.
+	Timeout time.Duration
+}
+
+// Bridge asks the machine's cameras for pictures over MQTT and matches their answers
+// to the request they belong to.
+type Bridge struct {
+	c      Client
+	cfg    BridgeConfig
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]chan domain.Image
+}
+
+// NewBridge creates a bridge and subscribes to the cameras' answers.
+func NewBridge(c Client, cfg BridgeConfig, logger *slog.Logger) (*Bridge, error) {
+	if len(cfg.Sides) == 0 {
+		return nil, fmt.Errorf("NewBridge(): %w", ErrNoSides)
+	}
+
+	b := &Bridge{
+		c:       c,
+		cfg:     cfg,
+		logger:  logger,
+		pending: make(map[string]chan domain.Image),
+	}
+
+	if err := c.Subscribe(CompleteTopic+"/+/+", b.handleImage); err != nil {
+		return nil, fmt.Errorf("NewBridge(): failed to subscribe to captured images: %w", err)
+	}
+
+	return b, nil
+}
+
+// Sides returns the camera sides that are expected to answer every capture request.
+func (b *Bridge) Sides() []string {
+	return b.cfg.Sides
+}
+
+// Capture publishes a capture request and waits until every side has answered or the timeout passes.
+// Sides that didn't answer in time are listed in the capture's Missing; if none did, it returns
+// ErrCaptureTimedOut.
+func (b *Bridge) Capture(ctx context.Context) (domain.Capture, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return domain.Capture{}, fmt.Errorf("Capture(): failed to generate request id: %w", err)
+	}
+
+	requestID := id.String()
+
+	// Every side answers at most once that we care about, so the handler never blocks on a full channel.
+	images := make(chan domain.Image, len(b.cfg.Sides))
+
+	b.mu.Lock()
+	b.pending[requestID] = images
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, requestID)
+		b.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+
+	if err = b.c.Publish(ctx, RequestTopic, []byte(requestID)); err != nil {
+		return domain.Capture{}, fmt.Errorf("Capture(): failed to publish capture request: %w", err)
+	}
+
+	c := domain.Capture{RequestID: requestID}
+	received := make(map[string]bool, len(b.cfg.Sides))
+
+	for len(received) < len(b.cfg.Sides) {
+		select {
+		case img := <-images:
+			if received[img.Side] {
+				continue
+			}
+
+			received[img.Side] = true
+			c.Images = append(c.Images, img)
+		case <-ctx.Done():
+			return b.partialCapture(c, received)
+		}
+	}
+
+	return c, nil
+}
+
+func (b *Bridge) partialCapture(c domain.Capture, received map[string]bool) (domain.Capture, error) {
+	for _, side := range b.cfg.Sides {
+		if !received[side] {
+			c.Missing = append(c.Missing, side)
+		}
+	}
+
+	if len(c.Images) == 0 {
+		return domain.Capture{}, fmt.Errorf("Capture(): %w: request %s", ErrCaptureTimedOut, c.RequestID)
+	}
+
+	b.logger.Warn(
+		"capture timed out waiting for some cameras",
+		slog.String("request_id", c.RequestID),
+		slog.Any("missing", c.Missing),
+	)
+
+	return c, nil
+}
+
+func (b *Bridge) handleImage(topic string, payload []byte) {
+	side, requestID, ok := parseCompleteTopic(topic)
+	if !ok {
+		b.logger.Warn("ignoring image on unexpected topic", slog.String("topic", topic))
+		return
+	}
+
+	if !b.isExpectedSide(side) {
+		b.logger.Warn("ignoring image from unknown camera side", slog.String("side", side))
+		return
+	}
+
+	b.mu.Lock()
+	images, ok := b.pending[requestID]
+	b.mu.Unlock()
+
+	// Answers that arrive after their request timed out have nobody waiting for them.
+	if !ok {
+		b.logger.Info("ignoring image for unknown or timed out request", slog.String("request_id", requestID))
+		return
+	}
+
+	select {
+	case images <- domain.Image{Side: side, Data: payload}:
+	default:
+		b.logger.Warn(
+			"ignoring duplicate image",
+			slog.String("request_id", requestID),
+			slog.String("side", side),
+		)
+	}
+}
+
+func (b *Bridge) isExpectedSide(side string) bool {
+	for _, s := range b.cfg.Sides {
+		if s == side {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCompleteTopic splits capture/complete/<side>/<request id> into its side and request id.
+func parseCompleteTopic(topic string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(topic, CompleteTopic+"/")
+	if !ok {
+		return "", "", false
+	}
+
+	side, requestID, ok := strings.Cut(rest, "/")
+	if !ok || side == "" || requestID == "" || strings.Contains(requestID, "/") {
+		return "", "", false
+	}
+
+	return side, requestID, true
+}
@@ -0,0 +1,260 @@
+package capture_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/capture"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+const (
+	testTimeout  = 2 * time.Second
+	shortTimeout = 200 * time.Millisecond
+	// reconnectTimeout leaves room for Paho's first reconnect attempt, which it makes after a second.
+	reconnectTimeout = 10 * time.Second
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// testBroker is an embedded MQTT broker with camera modules behind it: every capture request
+// is answered by the cameras it knows about, the way the firmware does.
+type testBroker struct {
+	server *mqtt.Server
+	addr   string
+	camera *capture.PahoClient
+
+	// images are what each camera sends back, by side.
+	images map[string][]byte
+	// repeat is how many times each camera sends its image.
+	repeat int
+
+	// answering tracks the cameras' answers, which may still be going out when a capture is done.
+	answering sync.WaitGroup
+}
+
+func startBroker(t *testing.T, images map[string][]byte) *testBroker {
+	t.Helper()
+
+	b := &testBroker{images: images, repeat: 1}
+	b.serve(t, "127.0.0.1:0")
+
+	t.Cleanup(func() {
+		b.server.Close()
+	})
+
+	b.camera = b.connect(t, "camera")
+
+	// Cleanups run last to first, so this waits for the answers before the camera disconnects.
+	t.Cleanup(b.answering.Wait)
+
+	if err := b.camera.Subscribe(capture.RequestTopic, func(_ string, payload []byte) {
+		requestID := string(payload)
+
+		// Paho delivers messages one at a time, so publishing has to happen outside the handler.
+		b.answering.Add(1)
+
+		go func() {
+			defer b.answering.Done()
+
+			for side, img := range b.images {
+				for i := 0; i < b.repeat; i++ {
+					topic := capture.CompleteTopic + "/" + side + "/" + requestID
+					if err := b.camera.Publish(context.Background(), topic, img); err != nil {
+						t.Errorf("camera %s failed to publish: %v", side, err)
+					}
+				}
+			}
+		}()
+	}); err != nil {
+		t.Fatalf("camera failed to subscribe: %v", err)
+	}
+
+	return b
+}
+
+// serve starts the broker on addr, remembering the address it ended up on.
+func (b *testBroker) serve(t *testing.T, addr string) {
+	t.Helper()
+
+	b.server = mqtt.New(&mqtt.Options{Logger: discardLogger})
+
+	if err := b.server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("failed to add auth hook: %v", err)
+	}
+
+	l := listeners.NewTCP("tcp", addr, nil)
+	if err := b.server.AddListener(l); err != nil {
+		t.Fatalf("failed to listen on %s: %v", addr, err)
+	}
+
+	if err := b.server.Serve(); err != nil {
+		t.Fatalf("failed to start broker: %v", err)
+	}
+
+	b.addr = l.Address()
+}
+
+// restart stops the broker and starts a new one on the same address, which has none of the old one's
+// sessions or subscriptions.
+func (b *testBroker) restart(t *testing.T) {
+	t.Helper()
+
+	if err := b.server.Close(); err != nil {
+		t.Fatalf("failed to stop broker: %v", err)
+	}
+
+	b.serve(t, b.addr)
+}
+
+func (b *testBroker) connect(t *testing.T, clientID string) *capture.PahoClient {
+	t.Helper()
+
+	c, err := capture.NewPahoClient(capture.PahoConfig{
+		BrokerURL: "tcp://" + b.addr,
+		ClientID:  clientID,
+	}, discardLogger)
+	if err != nil {
+		t.Fatalf("failed to connect %s: %v", clientID, err)
+	}
+
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+// newBridge connects a bridge to the broker.
+func newBridge(t *testing.T, broker *testBroker, sides []string, timeout time.Duration) *capture.Bridge {
+	t.Helper()
+
+	b, err := capture.NewBridge(
+		broker.connect(t, "bridge"),
+		capture.BridgeConfig{Sides: sides, Timeout: timeout},
+		discardLogger,
+	)
+	if err != nil {
+		t.Fatalf("failed to create bridge: %v", err)
+	}
+
+	return b
+}
+
+func TestBridgeCapture(t *testing.T) {
+	broker := startBroker(t, map[string][]byte{
+		"top":   []byte("top image"),
+		"front": []byte("front image"),
+		// Not one of the configured sides, so it is ignored.
+		"bottom": []byte("bottom image"),
+	})
+	broker.repeat = 2
+
+	b := newBridge(t, broker, []string{"top", "front"}, testTimeout)
+
+	c, err := b.Capture(context.Background())
+	if err != nil {
+		t.Fatalf("failed to capture: %v", err)
+	}
+
+	if c.RequestID == "" {
+		t.Error("got empty request id")
+	}
+
+	if len(c.Missing) != 0 {
+		t.Errorf("got missing sides %v, want none", c.Missing)
+	}
+
+	got := make(map[string]string, len(c.Images))
+	for _, img := range c.Images {
+		got[img.Side] = string(img.Data)
+	}
+
+	if len(c.Images) != 2 || got["top"] != "top image" || got["front"] != "front image" {
+		t.Errorf("got images %v, want one from each of top and front", got)
+	}
+}
+
+func TestBridgeCapturePartial(t *testing.T) {
+	broker := startBroker(t, map[string][]byte{"top": []byte("top image")})
+	b := newBridge(t, broker, []string{"top", "front"}, shortTimeout)
+
+	c, err := b.Capture(context.Background())
+	if err != nil {
+		t.Fatalf("failed to capture: %v", err)
+	}
+
+	if len(c.Images) != 1 || c.Images[0].Side != "top" {
+		t.Errorf("got %d images, want only the top one", len(c.Images))
+	}
+
+	if len(c.Missing) != 1 || c.Missing[0] != "front" {
+		t.Errorf("got missing sides %v, want [front]", c.Missing)
+	}
+}
+
+func TestBridgeCaptureTimeout(t *testing.T) {
+	broker := startBroker(t, nil)
+	b := newBridge(t, broker, []string{"top"}, shortTimeout)
+
+	if _, err := b.Capture(context.Background()); !errors.Is(err, capture.ErrCaptureTimedOut) {
+		t.Errorf("got error %v, want %v", err, capture.ErrCaptureTimedOut)
+	}
+}
+
+func TestBridgeIgnoresLateImages(t *testing.T) {
+	broker := startBroker(t, nil)
+	b := newBridge(t, broker, []string{"top"}, shortTimeout)
+
+	if _, err := b.Capture(context.Background()); !errors.Is(err, capture.ErrCaptureTimedOut) {
+		t.Fatalf("got error %v, want %v", err, capture.ErrCaptureTimedOut)
+	}
+
+	// Nobody is waiting for these any more; they must not block or panic.
+	for _, topic := range []string{
+		capture.CompleteTopic + "/top/00000000-0000-4000-8000-000000000000",
+		capture.CompleteTopic + "/top",
+	} {
+		if err := broker.camera.Publish(context.Background(), topic, []byte("late")); err != nil {
+			t.Fatalf("failed to publish to %s: %v", topic, err)
+		}
+	}
+
+	// The bridge is still serving captures after handling them. The cameras have answered
+	// the first request by now, so their images can change.
+	broker.answering.Wait()
+	broker.images = map[string][]byte{"top": []byte("top image")}
+
+	if _, err := b.Capture(context.Background()); err != nil {
+		t.Errorf("failed to capture after late images: %v", err)
+	}
+}
+
+func TestBridgeCaptureAfterReconnect(t *testing.T) {
+	broker := startBroker(t, map[string][]byte{"top": []byte("top image")})
+	b := newBridge(t, broker, []string{"top"}, shortTimeout)
+
+	broker.restart(t)
+
+	// Both the bridge and the camera have to reconnect and restore their subscriptions
+	// before a capture can go through.
+	deadline := time.Now().Add(reconnectTimeout)
+
+	for {
+		c, err := b.Capture(context.Background())
+		if err == nil && len(c.Images) == 1 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("no capture went through after the broker restarted, last error: %v", err)
+		}
+
+		time.Sleep(shortTimeout)
+	}
+}
@@ -0,0 +1,12 @@
+package capture
+
+import "context"
+
+// MessageHandler handles a message received on a subscribed topic.
+type MessageHandler func(topic string, payload []byte)
+
+// Client is the part of an MQTT client the bridge needs. Topic filters may use the + and # wildcards.
+type Client interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	Subscribe(topic string, handler MessageHandler) error
+}
@@ -0,0 +1,23 @@
+package domain
+
+// Image is what one of the machine's cameras took for a capture request.
+type Image struct {
+	// Side is where the camera sits on the machine, e.g. "top".
+	Side string
+	Data []byte
+}
+
+// Capture is everything the cameras sent back for one capture request.
+type Capture struct {
+	RequestID string
+	Images    []Image
+	// Missing lists the sides that didn't answer before the capture timed out.
+	Missing []string
+}
+
+// Classification is what the classifier made of the image from one side.
+type Classification struct {
+	Side       string
+	ItemID     int
+	Confidence float64
+}
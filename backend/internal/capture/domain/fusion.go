@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoConsensus = errors.New("the cameras did not reach a consensus")
+
+type FusionStrategy string
+
+const (
+	// FusionStrategyMajority picks the item more than half of the configured sides saw.
+	FusionStrategyMajority FusionStrategy = "majority"
+	// FusionStrategyMaxConfidence picks whatever the most confident side saw.
+	FusionStrategyMaxConfidence FusionStrategy = "max_confidence"
+	// FusionStrategyAllMustAgree requires every configured side to have seen the same item.
+	FusionStrategyAllMustAgree FusionStrategy = "all_must_agree"
+)
+
+func NewFusionStrategy(value string) (FusionStrategy, error) {
+	switch s := FusionStrategy(value); s {
+	case FusionStrategyMajority, FusionStrategyMaxConfidence, FusionStrategyAllMustAgree:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("unknown fusion strategy %q", value)
+}
+
+func (s FusionStrategy) String() string {
+	return string(s)
+}
+
+// FusedResult is the item the sides settled on.
+type FusedResult struct {
+	ItemID int
+	// Confidence is between 0 and 1: the mean confidence of the sides that voted for the item
+	// with majority, the winning side's with max confidence and the lowest with all must agree.
+	Confidence float64
+}
+
+// Fuse combines the classifications of a capture into a single result. sides is how many sides were
+// configured; those that didn't answer or couldn't be classified count as having abstained.
+// It returns ErrNoConsensus if the strategy can't settle on an item.
+func Fuse(strategy FusionStrategy, classifications []Classification, sides int) (FusedResult, error) {
+	if len(classifications) == 0 {
+		return FusedResult{}, ErrNoConsensus
+	}
+
+	switch strategy {
+	case FusionStrategyMajority:
+		return fuseMajority(classifications, sides)
+	case FusionStrategyMaxConfidence:
+		return fuseMaxConfidence(classifications), nil
+	case FusionStrategyAllMustAgree:
+		return fuseAllMustAgree(classifications, sides)
+	}
+
+	return FusedResult{}, fmt.Errorf("unknown fusion strategy %q", strategy)
+}
+
+func fuseMajority(classifications []Classification, sides int) (FusedResult, error) {
+	votes := make(map[int]int)
+	confidences := make(map[int]float64)
+
+	for _, c := range classifications {
+		votes[c.ItemID]++
+		confidences[c.ItemID] += c.Confidence
+	}
+
+	for itemID, n := range votes {
+		// At most one item can have more than half of the votes.
+		if 2*n > sides {
+			return FusedResult{ItemID: itemID, Confidence: confidences[itemID] / float64(n)}, nil
+		}
+	}
+
+	return FusedResult{}, ErrNoConsensus
+}
+
+func fuseMaxConfidence(classifications []Classification) FusedResult {
+	best := classifications[0]
+
+	for _, c := range classifications[1:] {
+		if c.Confidence > best.Confidence {
+			best = c
+		}
+	}
+
+	return FusedResult{ItemID: best.ItemID, Confidence: best.Confidence}
+}
+
+func fuseAllMustAgree(classifications []Classification, sides int) (FusedResult, error) {
+	if len(classifications) < sides {
+		return FusedResult{}, ErrNoConsensus
+	}
+
+	res := FusedResult{ItemID: classifications[0].ItemID, Confidence: classifications[0].Confidence}
+
+	for _, c := range classifications[1:] {
+		if c.ItemID != res.ItemID {
+			return FusedResult{}, ErrNoConsensus
+		}
+
+		res.Confidence = min(res.Confidence, c.Confidence)
+	}
+
+	return res, nil
+}
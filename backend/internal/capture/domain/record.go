@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// SideResult is what became of one configured side in a capture. ItemID and Confidence are nil
+// if the side didn't answer in time or its image couldn't be classified.
+type SideResult struct {
+	Side       string
+	ItemID     *int
+	Confidence *float64
+	// ImageKey is where the side's image is kept in the blob store, if it was kept.
+	ImageKey *string
+}
+
+// Record keeps the individual and fused results of a capture so that disagreements between
+// the cameras can be audited.
+type Record struct {
+	RequestID string
+	Strategy  FusionStrategy
+	// Fused is nil if the sides didn't reach a consensus.
+	Fused     *FusedResult
+	Sides     []SideResult
+	CreatedAt time.Time
+}
+
+// NewRecord lists every configured side, in order, with its classification and image key if it has them.
+func NewRecord(
+	requestID string,
+	strategy FusionStrategy,
+	fused *FusedResult,
+	sides []string,
+	classifications []Classification,
+	imageKeys map[string]string,
+	createdAt time.Time,
+) Record {
+	bySide := make(map[string]Classification, len(classifications))
+	for _, c := range classifications {
+		bySide[c.Side] = c
+	}
+
+	results := make([]SideResult, 0, len(sides))
+
+	for _, side := range sides {
+		sr := SideResult{Side: side}
+
+		if c, ok := bySide[side]; ok {
+			itemID, confidence := c.ItemID, c.Confidence
+			sr.ItemID = &itemID
+			sr.Confidence = &confidence
+		}
+
+		if key, ok := imageKeys[side]; ok {
+			sr.ImageKey = &key
+		}
+
+		results = append(results, sr)
+	}
+
+	return Record{
+		RequestID: requestID,
+		Strategy:  strategy,
+		Fused:     fused,
+		Sides:     results,
+		CreatedAt: createdAt,
+	}
+}
+
+// SidesAgree reports whether every side was classified as the same item.
+func (r Record) SidesAgree() bool {
+	for _, sr := range r.Sides {
+		if sr.ItemID == nil || *sr.ItemID != *r.Sides[0].ItemID {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,194 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeCapturingDisabled = "capturing_disabled"
+	codeCaptureTimedOut   = "capture_timed_out"
+	codeNoConsensus       = "no_consensus"
+)
+
+type sideResultResponse struct {
+	Side       string   `json:"side"`
+	ItemID     *int     `json:"item_id"`
+	Confidence *float64 `json:"confidence"`
+	ImageKey   *string  `json:"image_key"`
+}
+
+type recordResponse struct {
+	RequestID  string               `json:"request_id"`
+	Strategy   string               `json:"strategy"`
+	ItemID     *int                 `json:"item_id"`
+	Confidence *float64             `json:"confidence"`
+	SidesAgree bool                 `json:"sides_agree"`
+	Sides      []sideResultResponse `json:"sides"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new capture HTTP handler for the machine.
+//   - POST /captures - has the cameras take pictures, classifies them and fuses the results.
+//     The capture's request id is in the X-Capture-Request-ID header; pass it as capture_request_id
+//     when adding the item to the transaction so that the item can be reviewed later.
+//     Responds with the capture record as JSON, or a 422 no_consensus problem if the sides didn't reach
+//     a consensus. Responds with a 504 if no camera answered at all and a 503 if capturing is disabled.
+//     Clients that prefer text/plain get the fused "item_id=<id> confidence=<0-1>" line instead, followed
+//     by one "side=<side> item_id=<id> confidence=<0-1>" line per side, or "side=<side> none" for sides
+//     that didn't answer in time or couldn't be classified. Without a consensus, the first line is "none".
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Post("/", httputils.HandlerFunc(handler.capture))
+
+	handler.Router = r
+	return handler
+}
+
+// NewAuditHTTPHandler creates a new HTTP handler for auditing captures.
+//   - GET /captures/audit - returns the latest capture records as JSON, with every side's result next to
+//     the fused one. With disagreements_only=true, only captures whose sides disagreed are returned.
+func NewAuditHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getRecords))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) capture(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	record, err := h.s.CaptureAndClassify(r.Context())
+	if err != nil {
+		if errors.Is(err, ErrCapturingDisabled) {
+			oplog.Error("capturing is disabled")
+
+			w.TryWriteProblem(&oplog, http.StatusServiceUnavailable, codeCapturingDisabled, "capturing is disabled")
+
+			return
+		}
+
+		if errors.Is(err, ErrCaptureTimedOut) {
+			oplog.Error("capture timed out", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusGatewayTimeout, codeCaptureTimedOut, "no camera answered in time")
+
+			return
+		}
+
+		oplog.Error("failed to capture images", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.Header().Set("X-Capture-Request-ID", record.RequestID)
+
+	lines := make([]string, 0, len(record.Sides)+1)
+
+	if record.Fused != nil {
+		lines = append(lines, fmt.Sprintf("item_id=%d confidence=%.3f", record.Fused.ItemID, record.Fused.Confidence))
+	} else {
+		lines = append(lines, "none")
+	}
+
+	for _, sr := range record.Sides {
+		if sr.ItemID == nil || sr.Confidence == nil {
+			lines = append(lines, fmt.Sprintf("side=%s none", sr.Side))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("side=%s item_id=%d confidence=%.3f", sr.Side, *sr.ItemID, *sr.Confidence))
+	}
+
+	attrs := []any{slog.String("request_id", record.RequestID), slog.Bool("sides_agree", record.SidesAgree())}
+
+	status := http.StatusOK
+
+	if record.Fused == nil {
+		oplog.Info("cameras did not reach a consensus", attrs...)
+
+		// Plain text clients still get the per-side lines along with the 422.
+		if !httputils.PrefersPlainText(r) {
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusUnprocessableEntity,
+				codeNoConsensus,
+				"cameras did not reach a consensus",
+			)
+
+			return
+		}
+
+		status = http.StatusUnprocessableEntity
+	} else {
+		oplog.Info("captured and classified images", attrs...)
+	}
+
+	w.TryWriteNegotiated(&oplog, status, toRecordResponse(record), strings.Join(lines, "\n"))
+}
+
+func (h *HTTPHandler) getRecords(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	disagreementsOnly := r.FormValue("disagreements_only") == "true"
+
+	records, err := h.s.GetRecords(disagreementsOnly)
+	if err != nil {
+		oplog.Error("failed to get capture records", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]recordResponse, 0, len(records))
+	for _, record := range records {
+		res = append(res, toRecordResponse(record))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func toRecordResponse(record domain.Record) recordResponse {
+	res := recordResponse{
+		RequestID:  record.RequestID,
+		Strategy:   record.Strategy.String(),
+		SidesAgree: record.SidesAgree(),
+		Sides:      make([]sideResultResponse, 0, len(record.Sides)),
+		CreatedAt:  record.CreatedAt,
+	}
+
+	if record.Fused != nil {
+		res.ItemID = &record.Fused.ItemID
+		res.Confidence = &record.Fused.Confidence
+	}
+
+	for _, sr := range record.Sides {
+		res.Sides = append(res.Sides, sideResultResponse(sr))
+	}
+
+	return res
+}
@@ -0,0 +1,124 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	pahoConnectTimeout = 10 * time.Second
+	pahoQoS            = 1
+	// pahoDisconnectQuiesceMs gives in-flight messages a moment to go out when closing.
+	pahoDisconnectQuiesceMs = 250
+)
+
+type PahoConfig struct {
+	// BrokerURL is e.g. tcp://localhost:1883 or ssl://broker.example.com:8883.
+	BrokerURL string
+	ClientID  string
+	Username  string
+	Password  string
+}
+
+// PahoClient is a Client backed by the Eclipse Paho MQTT client. It reconnects on its own
+// and restores its subscriptions after every reconnect.
+type PahoClient struct {
+	c      mqtt.Client
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	subs map[string]MessageHandler
+}
+
+func NewPahoClient(cfg PahoConfig, logger *slog.Logger) (*PahoClient, error) {
+	pc := &PahoClient{logger: logger, subs: make(map[string]MessageHandler)}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(pc.resubscribe)
+
+	pc.c = mqtt.NewClient(opts)
+
+	token := pc.c.Connect()
+	if !token.WaitTimeout(pahoConnectTimeout) {
+		pc.c.Disconnect(0)
+		return nil, fmt.Errorf("NewPahoClient(): timed out connecting to %s", cfg.BrokerURL)
+	}
+
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("NewPahoClient(): failed to connect to %s: %w", cfg.BrokerURL, err)
+	}
+
+	return pc, nil
+}
+
+func (pc *PahoClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := wait(ctx, pc.c.Publish(topic, pahoQoS, false, payload)); err != nil {
+		return fmt.Errorf("Publish(): failed to publish to %s: %w", topic, err)
+	}
+
+	return nil
+}
+
+func (pc *PahoClient) Subscribe(topic string, handler MessageHandler) error {
+	pc.mu.Lock()
+	pc.subs[topic] = handler
+	pc.mu.Unlock()
+
+	token := pc.c.Subscribe(topic, pahoQoS, func(_ mqtt.Client, msg mqtt.Message) {
+		handler(msg.Topic(), msg.Payload())
+	})
+
+	if err := wait(context.Background(), token); err != nil {
+		return fmt.Errorf("Subscribe(): failed to subscribe to %s: %w", topic, err)
+	}
+
+	return nil
+}
+
+func (pc *PahoClient) Close() {
+	pc.c.Disconnect(pahoDisconnectQuiesceMs)
+}
+
+// resubscribe restores the subscriptions after a reconnect, as the broker may have dropped them.
+// Paho calls it in a goroutine of its own, so it can wait for the broker to acknowledge them.
+func (pc *PahoClient) resubscribe(c mqtt.Client) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for topic, handler := range pc.subs {
+		handler := handler
+
+		token := c.Subscribe(topic, pahoQoS, func(_ mqtt.Client, msg mqtt.Message) {
+			handler(msg.Topic(), msg.Payload())
+		})
+
+		if !token.WaitTimeout(pahoConnectTimeout) {
+			pc.logger.Error("timed out resubscribing after reconnect", slog.String("topic", topic))
+			continue
+		}
+
+		if err := token.Error(); err != nil {
+			pc.logger.Error("failed to resubscribe after reconnect", logging.ErrAttr(err), slog.String("topic", topic))
+		}
+	}
+}
+
+func wait(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,10 @@
+package capture
+
+import "github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+
+type Repository interface {
+	CreateRecord(record domain.Record) error
+	// GetRecords returns the latest capture records, newest first. With disagreementsOnly,
+	// only records whose sides didn't all see the same item are returned.
+	GetRecords(disagreementsOnly bool, limit int) ([]domain.Record, error)
+}
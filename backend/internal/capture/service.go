@@ -0,0 +1,133 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/classification"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+)
+
+// recordsLimit is how many of the latest capture records can be audited at once.
+const recordsLimit = 100
+
+var ErrCapturingDisabled = fmt.Errorf("capturing is disabled")
+
+type Service struct {
+	r        Repository
+	uow      db.UnitOfWork[Repository]
+	b        *Bridge
+	cs       *classification.Service
+	bs       blob.Store
+	strategy domain.FusionStrategy
+	logger   *slog.Logger
+}
+
+// NewService creates a capture service. b may be nil if no MQTT broker is configured, in which case
+// only the records of earlier captures are available.
+func NewService(
+	r Repository,
+	uow db.UnitOfWork[Repository],
+	b *Bridge,
+	cs *classification.Service,
+	bs blob.Store,
+	strategy domain.FusionStrategy,
+	logger *slog.Logger,
+) *Service {
+	return &Service{r: r, uow: uow, b: b, cs: cs, bs: bs, strategy: strategy, logger: logger}
+}
+
+// CaptureAndClassify has the cameras take pictures, classifies each of them and fuses the results
+// with the configured strategy. The individual and fused results are recorded either way; the record's
+// Fused is nil if the sides didn't reach a consensus.
+// An image that can't be classified, e.g. because it is corrupt, counts as that side abstaining.
+// Every image is kept in the blob store so that operators can see what the machine saw; an image
+// that can't be stored is only logged, as the capture is still usable without it.
+func (s *Service) CaptureAndClassify(ctx context.Context) (domain.Record, error) {
+	if s.b == nil {
+		return domain.Record{}, fmt.Errorf("CaptureAndClassify(): %w", ErrCapturingDisabled)
+	}
+
+	c, err := s.b.Capture(ctx)
+	if err != nil {
+		return domain.Record{}, fmt.Errorf("CaptureAndClassify(): %w", err)
+	}
+
+	classifications := make([]domain.Classification, 0, len(c.Images))
+	imageKeys := make(map[string]string, len(c.Images))
+
+	for _, img := range c.Images {
+		key := ImageKey(c.RequestID, img.Side)
+
+		if err = s.bs.Put(ctx, key, img.Data); err != nil {
+			s.logger.Error(
+				"failed to store captured image",
+				logging.ErrAttr(err),
+				slog.String("request_id", c.RequestID),
+				slog.String("side", img.Side),
+			)
+		} else {
+			imageKeys[img.Side] = key
+		}
+
+		res, err := s.cs.Classify(ctx, img.Data)
+		if err != nil {
+			s.logger.Warn(
+				"failed to classify captured image",
+				logging.ErrAttr(err),
+				slog.String("request_id", c.RequestID),
+				slog.String("side", img.Side),
+			)
+
+			continue
+		}
+
+		classifications = append(classifications, domain.Classification{
+			Side:       img.Side,
+			ItemID:     res.ItemID,
+			Confidence: res.Confidence,
+		})
+	}
+
+	sides := s.b.Sides()
+
+	var fused *domain.FusedResult
+
+	res, err := domain.Fuse(s.strategy, classifications, len(sides))
+	if err == nil {
+		fused = &res
+	} else if !errors.Is(err, domain.ErrNoConsensus) {
+		return domain.Record{}, fmt.Errorf("CaptureAndClassify(): failed to fuse results: %w", err)
+	}
+
+	record := domain.NewRecord(c.RequestID, s.strategy, fused, sides, classifications, imageKeys, time.Now())
+
+	if err = s.uow.Do(func(r Repository) error {
+		return r.CreateRecord(record)
+	}); err != nil {
+		return domain.Record{}, fmt.Errorf("CaptureAndClassify(): failed to record capture: %w", err)
+	}
+
+	return record, nil
+}
+
+// ImageKey is where the image a side took for a capture request is kept in the blob store.
+func ImageKey(requestID string, side string) string {
+	return "captures/" + requestID + "/" + side
+}
+
+// GetRecords returns the latest capture records, optionally only those whose sides disagreed.
+func (s *Service) GetRecords(disagreementsOnly bool) ([]domain.Record, error) {
+	records, err := s.r.GetRecords(disagreementsOnly, recordsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetRecords(): failed to get records: %w", err)
+	}
+
+	return records, nil
+}
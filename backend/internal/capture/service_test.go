@@ -0,0 +1,130 @@
+package capture_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/capture"
+	"github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/classification"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+)
+
+const classifiedItemID = 7
+
+// jpeg starts like a JPEG file, which is all it takes for the image to be classified.
+func jpeg(s string) []byte {
+	return append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, s...)
+}
+
+func newService(t *testing.T, b *capture.Bridge) (*capture.Service, blob.Store) {
+	t.Helper()
+
+	dbHandle := dbtest.Open(t)
+
+	classifier, err := classification.NewStubClassifier([]int{classifiedItemID})
+	if err != nil {
+		t.Fatalf("failed to create classifier: %v", err)
+	}
+
+	bs, err := blob.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	return capture.NewService(
+		capture.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) capture.Repository {
+			return capture.NewSQLRepository(q)
+		}),
+		b,
+		classification.NewService(classifier),
+		bs,
+		domain.FusionStrategyMajority,
+		discardLogger,
+	), bs
+}
+
+func TestCaptureAndClassify(t *testing.T) {
+	broker := startBroker(t, map[string][]byte{
+		"top":   jpeg("top"),
+		"front": jpeg("front"),
+	})
+
+	s, bs := newService(t, newBridge(t, broker, []string{"top", "front"}, testTimeout))
+
+	record, err := s.CaptureAndClassify(context.Background())
+	if err != nil {
+		t.Fatalf("failed to capture and classify: %v", err)
+	}
+
+	if record.Fused == nil || record.Fused.ItemID != classifiedItemID {
+		t.Fatalf("got fused result %+v, want item %d", record.Fused, classifiedItemID)
+	}
+
+	for _, sr := range record.Sides {
+		if sr.ItemID == nil || *sr.ItemID != classifiedItemID {
+			t.Errorf("side %s: got item %v, want %d", sr.Side, sr.ItemID, classifiedItemID)
+		}
+
+		if sr.ImageKey == nil {
+			t.Errorf("side %s: image wasn't kept", sr.Side)
+			continue
+		}
+
+		img, err := bs.Get(context.Background(), *sr.ImageKey)
+		if err != nil {
+			t.Errorf("side %s: failed to get image: %v", sr.Side, err)
+		} else if string(img) != string(broker.images[sr.Side]) {
+			t.Errorf("side %s: kept image doesn't match the one sent", sr.Side)
+		}
+	}
+
+	assertRecorded(t, s, record.RequestID)
+}
+
+func TestCaptureAndClassifyPartial(t *testing.T) {
+	broker := startBroker(t, map[string][]byte{"top": jpeg("top")})
+
+	s, _ := newService(t, newBridge(t, broker, []string{"top", "front"}, shortTimeout))
+
+	record, err := s.CaptureAndClassify(context.Background())
+	if err != nil {
+		t.Fatalf("failed to capture and classify: %v", err)
+	}
+
+	// One vote out of two sides is no majority.
+	if record.Fused != nil {
+		t.Errorf("got fused result %+v, want none", record.Fused)
+	}
+
+	sides := make(map[string]domain.SideResult, len(record.Sides))
+	for _, sr := range record.Sides {
+		sides[sr.Side] = sr
+	}
+
+	if sides["top"].ItemID == nil {
+		t.Error("top side wasn't classified")
+	}
+
+	if front := sides["front"]; front.ItemID != nil || front.ImageKey != nil {
+		t.Errorf("got %+v for the front side, which never answered", front)
+	}
+
+	assertRecorded(t, s, record.RequestID)
+}
+
+func assertRecorded(t *testing.T, s *capture.Service, requestID string) {
+	t.Helper()
+
+	records, err := s.GetRecords(false)
+	if err != nil {
+		t.Fatalf("failed to get records: %v", err)
+	}
+
+	if len(records) != 1 || records[0].RequestID != requestID {
+		t.Errorf("got %d records, want only the one for request %s", len(records), requestID)
+	}
+}
@@ -0,0 +1,145 @@
+package capture
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/capture/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+)
+
+type recordRow struct {
+	RequestID      string          `db:"request_id"`
+	Strategy       string          `db:"strategy"`
+	ItemID         sql.NullInt64   `db:"item_id"`
+	Confidence     sql.NullFloat64 `db:"confidence"`
+	CreatedAt      time.Time       `db:"created_at"`
+	Side           sql.NullString  `db:"side"`
+	SideItemID     sql.NullInt64   `db:"side_item_id"`
+	SideConfidence sql.NullFloat64 `db:"side_confidence"`
+	ImageKey       sql.NullString  `db:"image_key"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+// CreateRecord inserts the capture and its side results; run it in a unit of work so that
+// it's all or nothing.
+func (cr *SQLRepository) CreateRecord(record domain.Record) error {
+	var itemID *int
+	var confidence *float64
+
+	if record.Fused != nil {
+		itemID, confidence = &record.Fused.ItemID, &record.Fused.Confidence
+	}
+
+	if _, err := cr.db.Exec(`
+		INSERT INTO
+			captures (request_id, strategy, item_id, confidence, sides_agree, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+	`, record.RequestID, record.Strategy, itemID, confidence, record.SidesAgree(), record.CreatedAt); err != nil {
+		return fmt.Errorf("CreateRecord(): failed to insert capture: %w", err)
+	}
+
+	for _, sr := range record.Sides {
+		if _, err := cr.db.Exec(`
+			INSERT INTO
+				capture_results (request_id, side, item_id, confidence, image_key)
+			VALUES
+				(?, ?, ?, ?, ?)
+		`, record.RequestID, sr.Side, sr.ItemID, sr.Confidence, sr.ImageKey); err != nil {
+			return fmt.Errorf("CreateRecord(): failed to insert result of side %s: %w", sr.Side, err)
+		}
+	}
+
+	return nil
+}
+
+func (cr *SQLRepository) GetRecords(disagreementsOnly bool, limit int) ([]domain.Record, error) {
+	var rows []recordRow
+	if err := cr.db.Select(&rows, `
+		SELECT
+			captures.request_id,
+			captures.strategy,
+			captures.item_id,
+			captures.confidence,
+			captures.created_at,
+			capture_results.side,
+			capture_results.item_id AS side_item_id,
+			capture_results.confidence AS side_confidence,
+			capture_results.image_key
+		FROM
+			(
+				SELECT
+					request_id, strategy, item_id, confidence, created_at
+				FROM
+					captures
+				WHERE
+					? = FALSE OR sides_agree = FALSE
+				ORDER BY
+					created_at DESC, request_id
+				LIMIT ?
+			) AS captures
+		LEFT JOIN
+			capture_results ON capture_results.request_id = captures.request_id
+		ORDER BY
+			captures.created_at DESC, captures.request_id, capture_results.side
+	`, disagreementsOnly, limit); err != nil {
+		return nil, fmt.Errorf("GetRecords(): failed to execute query: %w", err)
+	}
+
+	records := make([]domain.Record, 0)
+
+	for _, row := range rows {
+		if len(records) == 0 || records[len(records)-1].RequestID != row.RequestID {
+			records = append(records, newRecordFromRow(row))
+		}
+
+		if !row.Side.Valid {
+			continue
+		}
+
+		sr := domain.SideResult{Side: row.Side.String}
+
+		if row.SideItemID.Valid {
+			itemID := int(row.SideItemID.Int64)
+			sr.ItemID = &itemID
+		}
+
+		if row.SideConfidence.Valid {
+			confidence := row.SideConfidence.Float64
+			sr.Confidence = &confidence
+		}
+
+		if row.ImageKey.Valid {
+			key := row.ImageKey.String
+			sr.ImageKey = &key
+		}
+
+		last := &records[len(records)-1]
+		last.Sides = append(last.Sides, sr)
+	}
+
+	return records, nil
+}
+
+func newRecordFromRow(row recordRow) domain.Record {
+	r := domain.Record{
+		RequestID: row.RequestID,
+		Strategy:  domain.FusionStrategy(row.Strategy),
+		CreatedAt: row.CreatedAt,
+	}
+
+	if row.ItemID.Valid && row.Confidence.Valid {
+		r.Fused = &domain.FusedResult{ItemID: int(row.ItemID.Int64), Confidence: row.Confidence.Float64}
+	}
+
+	return r
+}
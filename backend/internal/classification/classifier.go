@@ -0,0 +1,12 @@
+package classification
+
+import (
+	"context"
+
+	"github.com/JosephJoshua/rvm/backend/internal/classification/domain"
+)
+
+type Classifier interface {
+	// Classify returns the item the image most likely shows.
+	Classify(ctx context.Context, image []byte) (domain.Result, error)
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "fmt"
+
+// Result is what a classifier thinks an image shows.
+type Result struct {
+	ItemID int
+	// Confidence is between 0 and 1.
+	Confidence float64
+}
+
+func NewResult(itemID int, confidence float64) (Result, error) {
+	if confidence < 0 || confidence > 1 {
+		return Result{}, fmt.Errorf("confidence must be between 0 and 1, got %v", confidence)
+	}
+
+	return Result{ItemID: itemID, Confidence: confidence}, nil
+}
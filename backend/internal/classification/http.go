@@ -0,0 +1,176 @@
+package classification
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// multipartOverhead allows for the multipart boundaries and headers around the image.
+const multipartOverhead = 4 << 10
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeEmptyImage           = "empty_image"
+	codeImageTooLarge        = "image_too_large"
+	codeUnsupportedImageType = "unsupported_image_type"
+)
+
+type classificationResponse struct {
+	ItemID     int     `json:"item_id"`
+	Confidence float64 `json:"confidence"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new image classification HTTP handler for the camera module.
+//   - POST /image-classification - classifies the JPEG or PNG in the multipart field image and returns
+//     its item_id and confidence, or "item_id=<id> confidence=<0-1>" to clients that prefer text/plain
+//     like the camera module.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Post("/", httputils.HandlerFunc(handler.classify))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) classify(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImageSize+multipartOverhead)
+
+	image, status, err := readImage(r)
+	if err != nil {
+		oplog.Error("invalid image upload", logging.ErrAttr(err), slog.Int("status", status))
+
+		w.TryWriteProblem(&oplog, status, readImageErrorCode(status), err.Error())
+
+		return
+	}
+
+	res, err := h.s.Classify(r.Context(), image)
+	if err != nil {
+		if errors.Is(err, ErrEmptyImage) {
+			oplog.Error("image is empty")
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, codeEmptyImage, "image is empty")
+
+			return
+		}
+
+		if errors.Is(err, ErrImageTooLarge) {
+			oplog.Error("image is too large", slog.Int("size", len(image)))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusRequestEntityTooLarge,
+				codeImageTooLarge,
+				fmt.Sprintf("image must be at most %d bytes", MaxImageSize),
+			)
+
+			return
+		}
+
+		if errors.Is(err, ErrUnsupportedImageType) {
+			oplog.Error("unsupported image type", logging.ErrAttr(err))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusUnsupportedMediaType,
+				codeUnsupportedImageType,
+				"image must be a JPEG or PNG",
+			)
+
+			return
+		}
+
+		oplog.Error("failed to classify image", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	oplog.Info("classified image", slog.Int("item_id", res.ItemID), slog.Float64("confidence", res.Confidence))
+	w.TryWriteNegotiated(
+		&oplog,
+		http.StatusOK,
+		classificationResponse{ItemID: res.ItemID, Confidence: res.Confidence},
+		fmt.Sprintf("item_id=%d confidence=%.3f", res.ItemID, res.Confidence),
+	)
+}
+
+// readImage reads the image field of a multipart request. If it can't, it returns the status code
+// to respond with and an error fit to show the uploader.
+func readImage(r *http.Request) ([]byte, int, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("request must be multipart/form-data")
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("malformed multipart body")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, http.StatusBadRequest, fmt.Errorf("image is required")
+		}
+
+		if err != nil {
+			return readError(err)
+		}
+
+		if part.FormName() != "image" {
+			continue
+		}
+
+		if ct := part.Header.Get("Content-Type"); ct != "" && !IsSupportedImageType(ct) {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("image must be a JPEG or PNG")
+		}
+
+		image, err := io.ReadAll(part)
+		if err != nil {
+			return readError(err)
+		}
+
+		return image, http.StatusOK, nil
+	}
+}
+
+// readImageErrorCode returns the problem code for a status returned by readImage.
+func readImageErrorCode(status int) string {
+	switch status {
+	case http.StatusUnsupportedMediaType:
+		return codeUnsupportedImageType
+	case http.StatusRequestEntityTooLarge:
+		return codeImageTooLarge
+	default:
+		return httputils.CodeInvalidRequest
+	}
+}
+
+func readError(err error) ([]byte, int, error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("image must be at most %d bytes", MaxImageSize)
+	}
+
+	return nil, http.StatusBadRequest, fmt.Errorf("malformed multipart body")
+}
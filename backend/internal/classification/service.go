@@ -0,0 +1,54 @@
+package classification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/JosephJoshua/rvm/backend/internal/classification/domain"
+)
+
+// MaxImageSize is the largest image accepted for classification. The camera's largest JPEGs
+// are a few hundred kilobytes, so this leaves plenty of room.
+const MaxImageSize = 2 << 20
+
+var (
+	ErrEmptyImage           = fmt.Errorf("image is empty")
+	ErrImageTooLarge        = fmt.Errorf("image is too large")
+	ErrUnsupportedImageType = fmt.Errorf("unsupported image type")
+)
+
+type Service struct {
+	c Classifier
+}
+
+func NewService(c Classifier) *Service {
+	return &Service{c: c}
+}
+
+// Classify checks that the image is a JPEG or PNG of an acceptable size and returns what the classifier makes of it.
+// The image type is determined from its contents, not from what the uploader claims.
+func (s *Service) Classify(ctx context.Context, image []byte) (domain.Result, error) {
+	if len(image) == 0 {
+		return domain.Result{}, fmt.Errorf("Classify(): %w", ErrEmptyImage)
+	}
+
+	if len(image) > MaxImageSize {
+		return domain.Result{}, fmt.Errorf("Classify(): %w: %d bytes", ErrImageTooLarge, len(image))
+	}
+
+	if contentType := http.DetectContentType(image); !IsSupportedImageType(contentType) {
+		return domain.Result{}, fmt.Errorf("Classify(): %w %s", ErrUnsupportedImageType, contentType)
+	}
+
+	res, err := s.c.Classify(ctx, image)
+	if err != nil {
+		return domain.Result{}, fmt.Errorf("Classify(): failed to classify image: %w", err)
+	}
+
+	return res, nil
+}
+
+func IsSupportedImageType(contentType string) bool {
+	return contentType == "image/jpeg" || contentType == "image/png"
+}
@@ -0,0 +1,47 @@
+package classification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/JosephJoshua/rvm/backend/internal/classification/domain"
+)
+
+const (
+	stubMinConfidence   = 0.5
+	stubConfidenceRange = 0.5
+	stubConfidenceSteps = 1000
+)
+
+// StubClassifier stands in for a real model during local testing. It derives its answer from
+// a hash of the image, so the same image always gets the same item and confidence.
+type StubClassifier struct {
+	itemIDs []int
+}
+
+func NewStubClassifier(itemIDs []int) (*StubClassifier, error) {
+	if len(itemIDs) == 0 {
+		return nil, fmt.Errorf("NewStubClassifier(): at least one item id is required")
+	}
+
+	return &StubClassifier{itemIDs: itemIDs}, nil
+}
+
+func (c *StubClassifier) Classify(_ context.Context, image []byte) (domain.Result, error) {
+	sum := sha256.Sum256(image)
+
+	item := binary.BigEndian.Uint64(sum[:8]) % uint64(len(c.itemIDs))
+	step := binary.BigEndian.Uint64(sum[8:16]) % stubConfidenceSteps
+
+	// Confidence lands in [0.5, 1) so that the stub never looks hopelessly unsure.
+	confidence := stubMinConfidence + stubConfidenceRange*float64(step)/stubConfidenceSteps
+
+	res, err := domain.NewResult(c.itemIDs[item], confidence)
+	if err != nil {
+		return domain.Result{}, fmt.Errorf("Classify(): %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "fmt"
+
+type Format string
+
+const (
+	// FormatFolder puts every image in a folder named after its class.
+	FormatFolder Format = "folder"
+	// FormatCOCO puts every image in a single folder next to COCO-style annotations.
+	FormatCOCO Format = "coco"
+)
+
+func NewFormat(value string) (Format, error) {
+	switch f := Format(value); f {
+	case FormatFolder, FormatCOCO:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("unknown dataset format %q", value)
+}
+
+func (f Format) String() string {
+	return string(f)
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LabelSource is where a sample's label comes from.
+type LabelSource string
+
+const (
+	// LabelSourceApproved labels were confirmed by an operator.
+	LabelSourceApproved LabelSource = "approved"
+	// LabelSourceRelabeled labels were corrected by an operator.
+	LabelSourceRelabeled LabelSource = "relabeled"
+	// LabelSourceClassifier labels are whatever the machine added and nobody has reviewed.
+	LabelSourceClassifier LabelSource = "classifier"
+)
+
+func NewLabelSource(value string) (LabelSource, error) {
+	switch s := LabelSource(value); s {
+	case LabelSourceApproved, LabelSourceRelabeled, LabelSourceClassifier:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("unknown label source %q", value)
+}
+
+func (s LabelSource) String() string {
+	return string(s)
+}
+
+// Sample is one labeled image: what a camera side saw of a transaction item.
+type Sample struct {
+	TransactionItemID int64
+	CaptureRequestID  string
+	Side              string
+	ImageKey          string
+	ItemID            int
+	ItemName          string
+	// MachineID is the machine whose transaction the item was added to, if it is known.
+	MachineID  *int
+	Source     LabelSource
+	CapturedAt time.Time
+}
+
+// Filter narrows down which samples are exported.
+type Filter struct {
+	// From and To bound when the images were captured; From is inclusive and To exclusive.
+	// Either is nil if unbounded.
+	From *time.Time
+	To   *time.Time
+	// Sources are the label sources to include.
+	Sources []LabelSource
+	// MachineID only includes images from the given machine; nil includes every machine.
+	MachineID *int
+}
+
+// Includes reports whether the sample's label source is one of the filter's and, if the filter
+// is limited to a machine, whether the sample comes from it.
+func (f Filter) Includes(s Sample) bool {
+	if f.MachineID != nil && (s.MachineID == nil || *s.MachineID != *f.MachineID) {
+		return false
+	}
+
+	for _, source := range f.Sources {
+		if s.Source == source {
+			return true
+		}
+	}
+
+	return false
+}
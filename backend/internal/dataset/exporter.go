@@ -0,0 +1,266 @@
+package dataset
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	// Registered so that image.DecodeConfig can read the sizes COCO wants.
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/dataset/domain"
+)
+
+// Exporter turns the labeled images in the blob store into a training dataset.
+type Exporter struct {
+	r  Repository
+	bs blob.Store
+}
+
+func NewExporter(r Repository, bs blob.Store) *Exporter {
+	return &Exporter{r: r, bs: bs}
+}
+
+// Export writes the images matching the filter to w as a zip archive in the given format,
+// along with a manifest.json, and returns the manifest.
+// Images that are no longer in the blob store, or that aren't a JPEG or PNG, are left out
+// and counted in the manifest's Missing.
+func (e *Exporter) Export(
+	ctx context.Context,
+	w io.Writer,
+	format domain.Format,
+	filter domain.Filter,
+) (Manifest, error) {
+	from, to := time.Time{}, time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	if filter.From != nil {
+		from = *filter.From
+	}
+
+	if filter.To != nil {
+		to = *filter.To
+	}
+
+	samples, err := e.r.GetSamples(from, to)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("Export(): failed to get samples: %w", err)
+	}
+
+	a := newArchive(w, format, filter)
+
+	for _, s := range samples {
+		if !filter.Includes(s) {
+			continue
+		}
+
+		data, ext, ok, err := e.getImage(ctx, s.ImageKey)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("Export(): %w", err)
+		}
+
+		if !ok {
+			a.m.Missing++
+			continue
+		}
+
+		if err = a.add(s, data, ext); err != nil {
+			return Manifest{}, fmt.Errorf("Export(): %w", err)
+		}
+	}
+
+	if err = a.close(); err != nil {
+		return Manifest{}, fmt.Errorf("Export(): %w", err)
+	}
+
+	return a.m, nil
+}
+
+// getImage returns the image and the extension its type goes by. It reports false if the image
+// is gone or isn't a JPEG or PNG.
+func (e *Exporter) getImage(ctx context.Context, key string) ([]byte, string, bool, error) {
+	data, err := e.bs.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrBlobDoesNotExist) {
+			return nil, "", false, nil
+		}
+
+		return nil, "", false, fmt.Errorf("failed to get image %s: %w", key, err)
+	}
+
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return data, ".jpg", true, nil
+	case "image/png":
+		return data, ".png", true, nil
+	}
+
+	return nil, "", false, nil
+}
+
+// archive builds the zip archive of a dataset along with its manifest.
+type archive struct {
+	zw      *zip.Writer
+	format  domain.Format
+	m       Manifest
+	coco    cocoDataset
+	classes map[int]*ManifestClass
+}
+
+func newArchive(w io.Writer, format domain.Format, filter domain.Filter) *archive {
+	a := &archive{
+		zw:     zip.NewWriter(w),
+		format: format,
+		m: Manifest{
+			Format:     format.String(),
+			ExportedAt: time.Now(),
+			From:       filter.From,
+			To:         filter.To,
+			Sources:    make([]string, 0, len(filter.Sources)),
+			Files:      make([]ManifestFile, 0),
+		},
+		coco:    cocoDataset{Images: make([]cocoImage, 0), Annotations: make([]cocoAnnotation, 0)},
+		classes: make(map[int]*ManifestClass),
+	}
+
+	for _, source := range filter.Sources {
+		a.m.Sources = append(a.m.Sources, source.String())
+	}
+
+	return a
+}
+
+// add puts the sample's image in the archive. Images COCO can't get the size of are counted as missing.
+func (a *archive) add(s domain.Sample, data []byte, ext string) error {
+	name := strconv.FormatInt(s.TransactionItemID, 10) + "-" + s.Side + ext
+	path := classDir(s) + "/" + name
+
+	if a.format == domain.FormatCOCO {
+		path = "images/" + name
+
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			a.m.Missing++
+			return nil
+		}
+
+		id := len(a.coco.Images) + 1
+		a.coco.Images = append(a.coco.Images, cocoImage{ID: id, FileName: path, Width: cfg.Width, Height: cfg.Height})
+		a.coco.Annotations = append(a.coco.Annotations, cocoAnnotation{
+			ID:         id,
+			ImageID:    id,
+			CategoryID: s.ItemID,
+			BBox:       []float64{0, 0, float64(cfg.Width), float64(cfg.Height)},
+			Area:       float64(cfg.Width * cfg.Height),
+		})
+	}
+
+	// The images are already compressed, so deflating them again would only cost time.
+	if err := writeFile(a.zw, path, zip.Store, data); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	a.m.Files = append(a.m.Files, newManifestFile(path, hex.EncodeToString(sum[:]), s))
+
+	if c, ok := a.classes[s.ItemID]; ok {
+		c.Count++
+	} else {
+		a.classes[s.ItemID] = &ManifestClass{ItemID: s.ItemID, Name: s.ItemName, Count: 1}
+	}
+
+	return nil
+}
+
+// close writes the annotations and manifest and finishes the archive.
+func (a *archive) close() error {
+	a.m.Classes = make([]ManifestClass, 0, len(a.classes))
+	for _, c := range a.classes {
+		a.m.Classes = append(a.m.Classes, *c)
+	}
+
+	sort.Slice(a.m.Classes, func(i, j int) bool {
+		return a.m.Classes[i].ItemID < a.m.Classes[j].ItemID
+	})
+
+	if a.format == domain.FormatCOCO {
+		a.coco.Categories = make([]cocoCategory, 0, len(a.m.Classes))
+		for _, c := range a.m.Classes {
+			a.coco.Categories = append(a.coco.Categories, cocoCategory{ID: c.ItemID, Name: c.Name})
+		}
+
+		if err := writeJSON(a.zw, "annotations.json", a.coco); err != nil {
+			return err
+		}
+	}
+
+	if err := writeJSON(a.zw, "manifest.json", a.m); err != nil {
+		return err
+	}
+
+	if err := a.zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return nil
+}
+
+// classDir names the folder of the sample's class after its item, e.g. "2-aluminium-can-330ml".
+func classDir(s domain.Sample) string {
+	var b strings.Builder
+
+	b.WriteString(strconv.Itoa(s.ItemID))
+
+	dash := true
+	for _, r := range s.ItemName {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+
+			continue
+		}
+
+		dash = true
+	}
+
+	return b.String()
+}
+
+func writeJSON(zw *zip.Writer, path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	return writeFile(zw, path, zip.Deflate, data)
+}
+
+func writeFile(zw *zip.Writer, path string, method uint16, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: path, Method: method, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", path, err)
+	}
+
+	if _, err = f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", path, err)
+	}
+
+	return nil
+}
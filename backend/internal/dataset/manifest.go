@@ -0,0 +1,52 @@
+package dataset
+
+import (
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/dataset/domain"
+)
+
+// Manifest describes an exported dataset. It is written to manifest.json at the root of the archive.
+type Manifest struct {
+	Format     string    `json:"format"`
+	ExportedAt time.Time `json:"exported_at"`
+	// From is inclusive and To exclusive, as in the export's filter.
+	From    *time.Time      `json:"from"`
+	To      *time.Time      `json:"to"`
+	Sources []string        `json:"sources"`
+	Classes []ManifestClass `json:"classes"`
+	Files   []ManifestFile  `json:"files"`
+	// Missing counts the images that were left out because they are gone from the blob store
+	// or aren't a readable JPEG or PNG.
+	Missing int `json:"missing"`
+}
+
+type ManifestClass struct {
+	ItemID int    `json:"item_id"`
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+}
+
+type ManifestFile struct {
+	Path              string    `json:"path"`
+	SHA256            string    `json:"sha256"`
+	ItemID            int       `json:"item_id"`
+	Source            string    `json:"source"`
+	TransactionItemID int64     `json:"transaction_item_id"`
+	CaptureRequestID  string    `json:"capture_request_id"`
+	Side              string    `json:"side"`
+	CapturedAt        time.Time `json:"captured_at"`
+}
+
+func newManifestFile(path string, sha256 string, s domain.Sample) ManifestFile {
+	return ManifestFile{
+		Path:              path,
+		SHA256:            sha256,
+		ItemID:            s.ItemID,
+		Source:            s.Source.String(),
+		TransactionItemID: s.TransactionItemID,
+		CaptureRequestID:  s.CaptureRequestID,
+		Side:              s.Side,
+		CapturedAt:        s.CapturedAt,
+	}
+}
@@ -0,0 +1,13 @@
+package dataset
+
+import (
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/dataset/domain"
+)
+
+type Repository interface {
+	// GetSamples returns every kept image of a transaction item captured in [from, to), oldest first,
+	// labeled with the transaction item's current item.
+	GetSamples(from time.Time, to time.Time) ([]domain.Sample, error)
+}
@@ -0,0 +1,89 @@
+package dataset
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/dataset/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+)
+
+type sample struct {
+	TransactionItemID int64         `db:"transaction_item_id"`
+	CaptureRequestID  string        `db:"capture_request_id"`
+	Side              string        `db:"side"`
+	ImageKey          string        `db:"image_key"`
+	ItemID            int           `db:"item_id"`
+	ItemName          string        `db:"item_name"`
+	MachineID         sql.NullInt64 `db:"machine_id"`
+	Source            string        `db:"source"`
+	CapturedAt        time.Time     `db:"captured_at"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (dr *SQLRepository) GetSamples(from time.Time, to time.Time) ([]domain.Sample, error) {
+	var raw []sample
+	if err := dr.db.Select(&raw, `
+		SELECT
+			transaction_items.transaction_item_id,
+			transaction_items.capture_request_id,
+			capture_results.side,
+			capture_results.image_key,
+			transaction_items.item_id,
+			items.name AS item_name,
+			transactions.machine_id,
+			COALESCE(item_reviews.outcome, ?) AS source,
+			captures.created_at AS captured_at
+		FROM
+			transaction_items
+		INNER JOIN
+			captures ON captures.request_id = transaction_items.capture_request_id
+		INNER JOIN
+			capture_results ON capture_results.request_id = captures.request_id
+		INNER JOIN
+			items ON items.item_id = transaction_items.item_id
+		INNER JOIN
+			transactions ON transactions.transaction_id = transaction_items.transaction_id
+		LEFT JOIN
+			item_reviews ON item_reviews.transaction_item_id = transaction_items.transaction_item_id
+		WHERE
+			capture_results.image_key IS NOT NULL
+			AND captures.created_at >= ?
+			AND captures.created_at < ?
+		ORDER BY
+			captures.created_at, transaction_items.transaction_item_id, capture_results.side
+	`, domain.LabelSourceClassifier, from, to); err != nil {
+		return nil, fmt.Errorf("GetSamples(): failed to execute query: %w", err)
+	}
+
+	samples := make([]domain.Sample, 0, len(raw))
+	for _, s := range raw {
+		var machineID *int
+		if s.MachineID.Valid {
+			id := int(s.MachineID.Int64)
+			machineID = &id
+		}
+
+		samples = append(samples, domain.Sample{
+			TransactionItemID: s.TransactionItemID,
+			CaptureRequestID:  s.CaptureRequestID,
+			Side:              s.Side,
+			ImageKey:          s.ImageKey,
+			ItemID:            s.ItemID,
+			ItemName:          s.ItemName,
+			MachineID:         machineID,
+			Source:            domain.LabelSource(s.Source),
+			CapturedAt:        s.CapturedAt,
+		})
+	}
+
+	return samples, nil
+}
@@ -1,16 +1,72 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 
+	// postgres driver.
+	_ "github.com/jackc/pgx/v5/stdlib"
 	// sqlite3 driver.
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func NewDB(dbPath string) (*sqlx.DB, error) {
-	db, err := sqlx.Open("sqlite3", dbPath)
+var ErrUnknownDialect = errors.New("unknown database dialect")
+
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+func NewDialect(value string) (Dialect, error) {
+	switch d := Dialect(value); d {
+	case DialectSQLite, DialectPostgres:
+		return d, nil
+	}
+
+	return "", fmt.Errorf("%w %q", ErrUnknownDialect, value)
+}
+
+func (d Dialect) driverName() string {
+	if d == DialectPostgres {
+		return "pgx"
+	}
+
+	return "sqlite3"
+}
+
+// sqliteOptions are applied to every connection:
+//   - foreign keys are enforced so that the declared ON DELETE/ON UPDATE rules fire,
+//   - WAL lets readers and a writer work concurrently,
+//   - writers wait for a lock instead of failing immediately with SQLITE_BUSY,
+//   - transactions take the write lock up front, as upgrading a read lock can't wait.
+const sqliteOptions = "_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+
+// DB wraps *sqlx.DB so that queries written with ? placeholders work with every dialect.
+// Only Get, Select and Exec rebind their queries; use Rebind for anything else.
+type DB struct {
+	*sqlx.DB
+	dialect Dialect
+}
+
+// NewDB opens a database of the given dialect. dsn is a file path for SQLite
+// and a connection string for Postgres.
+func NewDB(dialect Dialect, dsn string) (*DB, error) {
+	if dialect == DialectSQLite {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+
+		dsn += separator + sqliteOptions
+	}
+
+	db, err := sqlx.Open(dialect.driverName(), dsn)
 	if err != nil {
 		return nil, fmt.Errorf("NewDB(): failed to open db: %w", err)
 	}
@@ -20,77 +76,69 @@ func NewDB(dbPath string) (*sqlx.DB, error) {
 		return nil, fmt.Errorf("NewDB(): failed to ping db: %w", err)
 	}
 
-	return db, nil
+	return &DB{DB: db, dialect: dialect}, nil
 }
 
-func MigrateDB(db *sqlx.DB) error {
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS api_tokens (
-			api_token_id VARCHAR(255) PRIMARY KEY NOT NULL,
-			expiring_at TIMESTAMP NULL,
-			created_at TIMESTAMP NOT NULL
-		) WITHOUT ROWID;
-	`); err != nil {
-		return fmt.Errorf("Migrate(): failed to migrate api_tokens: %w", err)
-	}
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			user_id VARCHAR(255) PRIMARY KEY NOT NULL,
-			full_name TEXT NOT NULL,
-			email TEXT NOT NULL,
-			birth_date DATE NULL
-		) WITHOUT ROWID;
-	`); err != nil {
-		return fmt.Errorf("Migrate(): failed to migrate users: %w", err)
-	}
+func (db *DB) Get(dest any, query string, args ...any) error {
+	return db.DB.Get(dest, db.Rebind(query), args...)
+}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS transactions (
-			transaction_id VARCHAR(255) PRIMARY KEY NOT NULL,
-			user_id INTEGER NULL,
-			created_at TIMESTAMP NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
-		) WITHOUT ROWID;
-	`); err != nil {
-		return fmt.Errorf("Migrate(): failed to migrate transactions: %w", err)
-	}
+func (db *DB) Select(dest any, query string, args ...any) error {
+	return db.DB.Select(dest, db.Rebind(query), args...)
+}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS items (
-			item_id INTEGER PRIMARY KEY NOT NULL,
-			name TEXT NOT NULL,
-			points int NOT NULL
-		);
-	`); err != nil {
-		return fmt.Errorf("Migrate(): failed to migrate items: %w", err)
-	}
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.Exec(db.Rebind(query), args...)
+}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS transaction_items (
-			transaction_item_id INTEGER PRIMARY KEY NOT NULL,
-			transaction_id INTEGER NOT NULL,
-			item_id INTEGER NOT NULL,
-			created_at TIMESTAMP NOT NULL,
-			FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE CASCADE ON UPDATE CASCADE,
-			FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE
-		);
-	`); err != nil {
-		return fmt.Errorf("Migrate(): failed to migrate transaction_items: %w", err)
+func (db *DB) Beginx() (*Tx, error) {
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("Beginx(): failed to begin transaction: %w", err)
 	}
 
-	return nil
+	return &Tx{Tx: tx}, nil
+}
+
+// Tx is the transaction counterpart of DB.
+type Tx struct {
+	*sqlx.Tx
+}
+
+func (tx *Tx) Get(dest any, query string, args ...any) error {
+	return tx.Tx.Get(dest, tx.Rebind(query), args...)
+}
+
+func (tx *Tx) Select(dest any, query string, args ...any) error {
+	return tx.Tx.Select(dest, tx.Rebind(query), args...)
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.Exec(tx.Rebind(query), args...)
 }
 
-func SeedDB(db *sqlx.DB) error {
+func SeedDB(db *DB) error {
 	if _, err := db.Exec(`
 		INSERT INTO
-			items (name, points)
+			items (name, material, volume_ml, points)
 		VALUES
-			('PET bottle', 10)
+			('PET bottle', 'pet', 600, 10)
 	`); err != nil {
 		return fmt.Errorf("SeedDB(): failed to seed items: %w", err)
 	}
 
+	if _, err := db.Exec(`
+		INSERT INTO
+			rewards (name, description, cost, stock)
+		VALUES
+			('Reusable bottle', 'A stainless steel water bottle', 500, 20)
+	`); err != nil {
+		return fmt.Errorf("SeedDB(): failed to seed rewards: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,177 @@
+// Package dbtest opens databases for the repository tests.
+//
+// Tests run against SQLite unless DATABASE_URL is set, in which case they run against that
+// Postgres database instead, each in a schema of its own that is dropped when the test ends.
+package dbtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+)
+
+const schemaSuffixBytes = 8
+
+// Open returns a freshly migrated database that is closed when the test ends.
+func Open(t testing.TB) *db.DB {
+	t.Helper()
+
+	var dbHandle *db.DB
+
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		dbHandle = openPostgres(t, databaseURL)
+	} else {
+		dbHandle = openSQLite(t)
+	}
+
+	migrator, err := db.NewMigrator(dbHandle)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	if _, err = migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate db: %v", err)
+	}
+
+	return dbHandle
+}
+
+// CreateUser adds a user with the given id.
+func CreateUser(t testing.TB, dbHandle *db.DB, userID string) {
+	t.Helper()
+
+	if _, err := dbHandle.Exec(`
+		INSERT INTO
+			users (user_id, full_name, email)
+		VALUES
+			(?, ?, ?)
+	`, userID, "Test user", userID+"@example.com"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+}
+
+// CreateMachine adds an active machine and returns its id.
+func CreateMachine(t testing.TB, dbHandle *db.DB) int {
+	t.Helper()
+
+	var machineID int
+	if err := dbHandle.Get(&machineID, `
+		INSERT INTO
+			machines (name, created_at)
+		VALUES
+			(?, ?)
+		RETURNING
+			machine_id
+	`, "Test machine", time.Now()); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	return machineID
+}
+
+// CreateItem adds an active item worth the given points and returns its id.
+func CreateItem(t testing.TB, dbHandle *db.DB, points int) int {
+	t.Helper()
+
+	var itemID int
+	if err := dbHandle.Get(&itemID, `
+		INSERT INTO
+			items (name, material, points)
+		VALUES
+			(?, ?, ?)
+		RETURNING
+			item_id
+	`, "Test item", "pet", points); err != nil {
+		t.Fatalf("failed to create item: %v", err)
+	}
+
+	return itemID
+}
+
+func openSQLite(t testing.TB) *db.DB {
+	t.Helper()
+
+	dbHandle, err := db.NewDB(db.DialectSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+
+	t.Cleanup(func() {
+		dbHandle.Close()
+	})
+
+	return dbHandle
+}
+
+// openPostgres creates a schema for the test and returns a database whose connections all use it,
+// so that tests can run in parallel and don't see each other's rows.
+func openPostgres(t testing.TB, databaseURL string) *db.DB {
+	t.Helper()
+
+	admin, err := db.NewDB(db.DialectPostgres, databaseURL)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+
+	suffix := make([]byte, schemaSuffixBytes)
+	if _, err = rand.Read(suffix); err != nil {
+		t.Fatalf("failed to generate schema name: %v", err)
+	}
+
+	schema := "test_" + hex.EncodeToString(suffix)
+
+	if _, err = admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+
+		admin.Close()
+	})
+
+	dsn, err := withSearchPath(databaseURL, schema)
+	if err != nil {
+		t.Fatalf("failed to set search path: %v", err)
+	}
+
+	dbHandle, err := db.NewDB(db.DialectPostgres, dsn)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+
+	// Registered after dropping the schema, so it runs first.
+	t.Cleanup(func() {
+		dbHandle.Close()
+	})
+
+	return dbHandle
+}
+
+// withSearchPath adds the search_path runtime parameter to a URL or keyword/value connection string.
+func withSearchPath(dsn string, schema string) (string, error) {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
@@ -0,0 +1,584 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrChecksumMismatch     = errors.New("migration checksum mismatch")
+	ErrUnknownMigration     = errors.New("applied migration is unknown to this binary")
+	ErrNoAppliedMigrations  = errors.New("no applied migrations")
+	ErrInvalidMigrationFile = errors.New("invalid migration file")
+	ErrForeignKeyViolation  = errors.New("migration leaves foreign key violations")
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName matches e.g. 0002_add_point_ledger.up.sql, or 0002_add_point_ledger.postgres.up.sql
+// for a script that replaces the shared one on that dialect only.
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)(?:\.(sqlite|postgres))?\.(up|down)\.sql$`)
+
+// migrationTemplateData is available to every migration script through text/template,
+// so that most migrations can be shared between dialects.
+type migrationTemplateData struct {
+	// WithoutRowID is " WITHOUT ROWID" on SQLite and empty elsewhere, e.g. ){{.WithoutRowID}};
+	WithoutRowID string
+	// AutoIncrementPrimaryKey is the column definition of an auto-incrementing integer primary key.
+	AutoIncrementPrimaryKey string
+}
+
+func newMigrationTemplateData(dialect Dialect) migrationTemplateData {
+	if dialect == DialectPostgres {
+		return migrationTemplateData{
+			WithoutRowID:            "",
+			AutoIncrementPrimaryKey: "INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY",
+		}
+	}
+
+	return migrationTemplateData{
+		WithoutRowID:            " WITHOUT ROWID",
+		AutoIncrementPrimaryKey: "INTEGER PRIMARY KEY NOT NULL",
+	}
+}
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is nil if the migration hasn't been applied yet.
+	AppliedAt *time.Time
+	// ChecksumMatches is false if the migration file has changed since it was applied.
+	ChecksumMatches bool
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Migrator applies and reverts the numbered migrations in the migrations directory,
+// recording them in the schema_migrations table.
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+func NewMigrator(db *DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations", db.Dialect())
+	if err != nil {
+		return nil, fmt.Errorf("NewMigrator(): failed to load migrations: %w", err)
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.prepare()
+	if err != nil {
+		return nil, fmt.Errorf("Up(): %w", err)
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		if err = m.apply(mig); err != nil {
+			return done, fmt.Errorf("Up(): failed to apply migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
+
+// Down reverts the most recently applied migration and returns it.
+func (m *Migrator) Down() (Migration, error) {
+	applied, err := m.prepare()
+	if err != nil {
+		return Migration{}, fmt.Errorf("Down(): %w", err)
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+
+		if err = m.revert(mig); err != nil {
+			return Migration{}, fmt.Errorf("Down(): failed to revert migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+
+		return mig, nil
+	}
+
+	return Migration{}, fmt.Errorf("Down(): %w", ErrNoAppliedMigrations)
+}
+
+// Status lists every known migration along with whether and when it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.createMigrationsTable(); err != nil {
+		return nil, fmt.Errorf("Status(): %w", err)
+	}
+
+	applied, err := m.getApplied()
+	if err != nil {
+		return nil, fmt.Errorf("Status(): %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Migration: mig, AppliedAt: nil, ChecksumMatches: true}
+
+		if a, ok := applied[mig.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.ChecksumMatches = a.Checksum == mig.Checksum
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// prepare makes sure the migrations table exists and that every applied migration
+// still matches what this binary knows about.
+func (m *Migrator) prepare() (map[int]appliedMigration, error) {
+	exists, err := m.tableExists("schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists && m.db.Dialect() == DialectSQLite {
+		if err = m.adoptLegacySchema(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = m.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.getApplied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	for version, a := range applied {
+		mig, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrUnknownMigration, a.Version, a.Name)
+		}
+
+		if a.Checksum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) apply(mig Migration) error {
+	return m.run(mig.Up, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(m.db.Rebind(`
+			INSERT INTO
+				schema_migrations (version, name, checksum, applied_at)
+			VALUES
+				(?, ?, ?, ?)
+		`), mig.Version, mig.Name, mig.Checksum, time.Now()); err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (m *Migrator) revert(mig Migration) error {
+	return m.run(mig.Down, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(m.db.Rebind(`
+			DELETE FROM
+				schema_migrations
+			WHERE
+				version = ?
+		`), mig.Version); err != nil {
+			return fmt.Errorf("failed to remove migration record: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// run executes the script and then record in a single transaction.
+//
+// On SQLite, foreign keys are switched off while the script runs, since rebuilding a table means
+// dropping it, which would otherwise cascade into every table referencing it. The foreign keys
+// are checked by hand before committing instead.
+func (m *Migrator) run(script string, record func(tx *sqlx.Tx) error) error {
+	ctx := context.Background()
+
+	// The pragma is per connection, so the whole migration has to stay on one.
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	defer conn.Close()
+
+	sqlite := m.db.Dialect() == DialectSQLite
+
+	if sqlite {
+		if _, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to disable foreign keys: %w", err)
+		}
+
+		defer func() { _, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON") }()
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(script); err != nil {
+		return fmt.Errorf("failed to execute script: %w", err)
+	}
+
+	if sqlite {
+		if err = checkForeignKeys(tx); err != nil {
+			return err
+		}
+	}
+
+	if err = record(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func checkForeignKeys(tx *sqlx.Tx) error {
+	var violations []struct {
+		Table  string `db:"table"`
+		RowID  *int64 `db:"rowid"`
+		Parent string `db:"parent"`
+		FKID   int    `db:"fkid"`
+	}
+
+	if err := tx.Select(&violations, "PRAGMA foreign_key_check"); err != nil {
+		return fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf(
+			"%w: %d rows in %s reference missing rows in %s",
+			ErrForeignKeyViolation,
+			len(violations),
+			violations[0].Table,
+			violations[0].Parent,
+		)
+	}
+
+	return nil
+}
+
+func (m *Migrator) createMigrationsTable() error {
+	if _, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		);
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) getApplied() (map[int]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := m.db.Select(&rows, `
+		SELECT
+			version, name, checksum, applied_at
+		FROM
+			schema_migrations
+	`); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) tableExists(table string) (bool, error) {
+	query := `
+		SELECT
+			COUNT(*)
+		FROM
+			sqlite_master
+		WHERE
+			type = 'table' AND name = ?
+	`
+
+	if m.db.Dialect() == DialectPostgres {
+		query = `
+			SELECT
+				COUNT(*)
+			FROM
+				information_schema.tables
+			WHERE
+				table_schema = current_schema() AND table_name = ?
+		`
+	}
+
+	var count int
+	if err := m.db.Get(&count, query, table); err != nil {
+		return false, fmt.Errorf("failed to check if %s exists: %w", table, err)
+	}
+
+	return count > 0, nil
+}
+
+// adoptLegacySchema brings databases created before versioned migrations existed up to the
+// schema of the first migration, so that it can be recorded as applied without losing data.
+func (m *Migrator) adoptLegacySchema() error {
+	exists, err := m.tableExists("transactions")
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	added, err := m.addColumnIfNotExists("transactions", "status", "VARCHAR(16) NOT NULL DEFAULT 'open'")
+	if err != nil {
+		return fmt.Errorf("failed to add transactions.status: %w", err)
+	}
+
+	if added {
+		// Before statuses existed, every unassigned transaction could be claimed,
+		// so treat those as closed rather than leaving them open forever.
+		if _, err = m.db.Exec(`
+			UPDATE
+				transactions
+			SET
+				status = CASE WHEN user_id IS NULL THEN 'closed' ELSE 'claimed' END
+		`); err != nil {
+			return fmt.Errorf("failed to backfill transactions.status: %w", err)
+		}
+	}
+
+	if _, err = m.addColumnIfNotExists("transactions", "closed_at", "TIMESTAMP NULL"); err != nil {
+		return fmt.Errorf("failed to add transactions.closed_at: %w", err)
+	}
+
+	if _, err = m.addColumnIfNotExists("transactions", "claimed_at", "TIMESTAMP NULL"); err != nil {
+		return fmt.Errorf("failed to add transactions.claimed_at: %w", err)
+	}
+
+	return nil
+}
+
+// addColumnIfNotExists adds the column to an existing table, reporting whether it had to be added.
+func (m *Migrator) addColumnIfNotExists(table string, column string, definition string) (bool, error) {
+	var count int
+	if err := m.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			pragma_table_info(?)
+		WHERE
+			name = ?
+	`, table, column); err != nil {
+		return false, fmt.Errorf("failed to inspect table: %w", err)
+	}
+
+	if count > 0 {
+		return false, nil
+	}
+
+	if _, err := m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return false, fmt.Errorf("failed to add column: %w", err)
+	}
+
+	return true, nil
+}
+
+// migrationScripts holds the scripts found for one version, keyed by dialect ("" for shared scripts).
+type migrationScripts struct {
+	name string
+	up   map[string]string
+	down map[string]string
+}
+
+// loadMigrations reads every migration in dir for the given dialect, preferring dialect-specific
+// scripts over shared ones. It makes sure each version has both an up and a down script and that
+// versions start at 1 without gaps.
+func loadMigrations(fsys fs.FS, dir string, dialect Dialect) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("loadMigrations(): failed to read directory: %w", err)
+	}
+
+	byVersion := make(map[int]*migrationScripts)
+
+	for _, entry := range entries {
+		matches := migrationFileName.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("loadMigrations(): %w: %s", ErrInvalidMigrationFile, entry.Name())
+		}
+
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations(): failed to parse version of %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations(): failed to read %s: %w", entry.Name(), err)
+		}
+
+		scripts, ok := byVersion[version]
+		if !ok {
+			scripts = &migrationScripts{name: matches[2], up: map[string]string{}, down: map[string]string{}}
+			byVersion[version] = scripts
+		}
+
+		if scripts.name != matches[2] {
+			return nil, fmt.Errorf("loadMigrations(): %w: version %d has two names", ErrInvalidMigrationFile, version)
+		}
+
+		if matches[4] == "up" {
+			scripts.up[matches[3]] = string(content)
+		} else {
+			scripts.down[matches[3]] = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, scripts := range byVersion {
+		mig, err := newMigration(version, scripts, dialect)
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations(): %w", err)
+		}
+
+		migrations = append(migrations, mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("loadMigrations(): %w: expected version %d, got %d", ErrInvalidMigrationFile, i+1, mig.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func newMigration(version int, scripts *migrationScripts, dialect Dialect) (Migration, error) {
+	pick := func(byDialect map[string]string) (string, bool) {
+		if script, ok := byDialect[string(dialect)]; ok {
+			return script, true
+		}
+
+		script, ok := byDialect[""]
+		return script, ok
+	}
+
+	up, hasUp := pick(scripts.up)
+	down, hasDown := pick(scripts.down)
+
+	if !hasUp || !hasDown {
+		return Migration{}, fmt.Errorf(
+			"%w: %04d_%s needs both an up and a down script for %s",
+			ErrInvalidMigrationFile,
+			version,
+			scripts.name,
+			dialect,
+		)
+	}
+
+	data := newMigrationTemplateData(dialect)
+
+	up, err := renderMigration(up, data)
+	if err != nil {
+		return Migration{}, fmt.Errorf("failed to render up script of %04d_%s: %w", version, scripts.name, err)
+	}
+
+	down, err = renderMigration(down, data)
+	if err != nil {
+		return Migration{}, fmt.Errorf("failed to render down script of %04d_%s: %w", version, scripts.name, err)
+	}
+
+	sum := sha256.Sum256([]byte(up + "\x00" + down))
+
+	return Migration{
+		Version:  version,
+		Name:     scripts.name,
+		Up:       up,
+		Down:     down,
+		Checksum: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func renderMigration(script string, data migrationTemplateData) (string, error) {
+	tmpl, err := template.New("migration").Option("missingkey=error").Parse(script)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var b strings.Builder
+	if err = tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return b.String(), nil
+}
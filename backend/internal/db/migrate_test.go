@@ -0,0 +1,58 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+)
+
+func TestMigratorRoundTrip(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+
+	migrator, err := db.NewMigrator(dbHandle)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %04d_%s is still pending", s.Version, s.Name)
+		}
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("failed to migrate up again: %v", err)
+	}
+
+	if len(applied) != 0 {
+		t.Errorf("got %d migrations applied to an up to date db, want 0", len(applied))
+	}
+
+	// Every down migration has to work on a real schema, not just the up ones.
+	for range statuses {
+		if _, err = migrator.Down(); err != nil {
+			t.Fatalf("failed to migrate down: %v", err)
+		}
+	}
+
+	if _, err = migrator.Down(); !errors.Is(err, db.ErrNoAppliedMigrations) {
+		t.Errorf("got error %v, want %v", err, db.ErrNoAppliedMigrations)
+	}
+
+	applied, err = migrator.Up()
+	if err != nil {
+		t.Fatalf("failed to migrate up from scratch: %v", err)
+	}
+
+	if len(applied) != len(statuses) {
+		t.Errorf("got %d migrations applied, want %d", len(applied), len(statuses))
+	}
+}
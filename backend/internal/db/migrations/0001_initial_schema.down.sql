@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS transaction_items;
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS api_tokens;
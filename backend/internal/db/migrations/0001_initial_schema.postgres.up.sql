@@ -0,0 +1,40 @@
+-- Unlike SQLite, Postgres refuses foreign keys between columns of different types,
+-- so the column types fixed by 0002 on SQLite are already correct here.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+	api_token_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	expiring_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	full_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	birth_date DATE NULL
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	transaction_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	user_id VARCHAR(255) NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	created_at TIMESTAMP NOT NULL,
+	closed_at TIMESTAMP NULL,
+	claimed_at TIMESTAMP NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS items (
+	item_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL,
+	points INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS transaction_items (
+	transaction_item_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	transaction_id VARCHAR(255) NOT NULL,
+	item_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+	api_token_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	expiring_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	full_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	birth_date DATE NULL
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS transactions (
+	transaction_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	user_id INTEGER NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	created_at TIMESTAMP NOT NULL,
+	closed_at TIMESTAMP NULL,
+	claimed_at TIMESTAMP NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS items (
+	item_id INTEGER PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	points int NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS transaction_items (
+	transaction_item_id INTEGER PRIMARY KEY NOT NULL,
+	transaction_id INTEGER NOT NULL,
+	item_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,38 @@
+CREATE TABLE transactions_old (
+	transaction_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	user_id INTEGER NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	created_at TIMESTAMP NOT NULL,
+	closed_at TIMESTAMP NULL,
+	claimed_at TIMESTAMP NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+) WITHOUT ROWID;
+
+INSERT INTO
+	transactions_old (transaction_id, user_id, status, created_at, closed_at, claimed_at)
+SELECT
+	transaction_id, user_id, status, created_at, closed_at, claimed_at
+FROM
+	transactions;
+
+CREATE TABLE transaction_items_old (
+	transaction_item_id INTEGER PRIMARY KEY NOT NULL,
+	transaction_id INTEGER NOT NULL,
+	item_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+INSERT INTO
+	transaction_items_old (transaction_item_id, transaction_id, item_id, created_at)
+SELECT
+	transaction_item_id, transaction_id, item_id, created_at
+FROM
+	transaction_items;
+
+DROP TABLE transaction_items;
+DROP TABLE transactions;
+
+ALTER TABLE transactions_old RENAME TO transactions;
+ALTER TABLE transaction_items_old RENAME TO transaction_items;
@@ -0,0 +1,4 @@
+DROP INDEX idx_transaction_items_item_id;
+DROP INDEX idx_transaction_items_transaction_id;
+DROP INDEX idx_transactions_status;
+DROP INDEX idx_transactions_user_id;
@@ -0,0 +1,6 @@
+-- The column types were already created correctly by 0001 on Postgres; only the indexes are missing.
+
+CREATE INDEX idx_transactions_user_id ON transactions (user_id);
+CREATE INDEX idx_transactions_status ON transactions (status);
+CREATE INDEX idx_transaction_items_transaction_id ON transaction_items (transaction_id);
+CREATE INDEX idx_transaction_items_item_id ON transaction_items (item_id);
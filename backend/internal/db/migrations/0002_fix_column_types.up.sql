@@ -0,0 +1,46 @@
+-- transactions.user_id and transaction_items.transaction_id were declared as INTEGER even though
+-- they hold string ids. SQLite can't change a column's type in place, so both tables are rebuilt.
+
+CREATE TABLE transactions_new (
+	transaction_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	user_id VARCHAR(255) NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	created_at TIMESTAMP NOT NULL,
+	closed_at TIMESTAMP NULL,
+	claimed_at TIMESTAMP NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+) WITHOUT ROWID;
+
+INSERT INTO
+	transactions_new (transaction_id, user_id, status, created_at, closed_at, claimed_at)
+SELECT
+	transaction_id, CAST(user_id AS TEXT), status, created_at, closed_at, claimed_at
+FROM
+	transactions;
+
+CREATE TABLE transaction_items_new (
+	transaction_item_id INTEGER PRIMARY KEY NOT NULL,
+	transaction_id VARCHAR(255) NOT NULL,
+	item_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+INSERT INTO
+	transaction_items_new (transaction_item_id, transaction_id, item_id, created_at)
+SELECT
+	transaction_item_id, CAST(transaction_id AS TEXT), item_id, created_at
+FROM
+	transaction_items;
+
+DROP TABLE transaction_items;
+DROP TABLE transactions;
+
+ALTER TABLE transactions_new RENAME TO transactions;
+ALTER TABLE transaction_items_new RENAME TO transaction_items;
+
+CREATE INDEX idx_transactions_user_id ON transactions (user_id);
+CREATE INDEX idx_transactions_status ON transactions (status);
+CREATE INDEX idx_transaction_items_transaction_id ON transaction_items (transaction_id);
+CREATE INDEX idx_transaction_items_item_id ON transaction_items (item_id);
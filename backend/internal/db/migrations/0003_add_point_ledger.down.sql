@@ -0,0 +1,5 @@
+DROP TABLE point_balances;
+
+DROP INDEX idx_point_ledger_earning;
+DROP INDEX idx_point_ledger_user_id;
+DROP TABLE point_ledger;
@@ -0,0 +1,57 @@
+CREATE TABLE point_ledger (
+	entry_id {{.AutoIncrementPrimaryKey}},
+	user_id VARCHAR(255) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	amount INTEGER NOT NULL,
+	transaction_id VARCHAR(255) NULL,
+	reference VARCHAR(255) NULL,
+	note TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_point_ledger_user_id ON point_ledger (user_id);
+
+-- A transaction's points can only be earned once.
+CREATE UNIQUE INDEX idx_point_ledger_earning ON point_ledger (transaction_id) WHERE kind = 'earning';
+
+CREATE TABLE point_balances (
+	user_id VARCHAR(255) PRIMARY KEY NOT NULL,
+	balance INTEGER NOT NULL CHECK (balance >= 0),
+	updated_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+){{.WithoutRowID}};
+
+-- Balances used to be derived from the claimed transactions' items, so that is what the existing claims earned.
+INSERT INTO
+	point_ledger (user_id, kind, amount, transaction_id, created_at)
+SELECT
+	transactions.user_id,
+	'earning',
+	COALESCE(SUM(items.points), 0),
+	transactions.transaction_id,
+	COALESCE(transactions.claimed_at, transactions.closed_at, transactions.created_at)
+FROM
+	transactions
+LEFT JOIN
+	transaction_items ON transaction_items.transaction_id = transactions.transaction_id
+LEFT JOIN
+	items ON items.item_id = transaction_items.item_id
+WHERE
+	transactions.user_id IS NOT NULL
+GROUP BY
+	transactions.transaction_id,
+	transactions.user_id,
+	transactions.claimed_at,
+	transactions.closed_at,
+	transactions.created_at;
+
+INSERT INTO
+	point_balances (user_id, balance, updated_at)
+SELECT
+	user_id, SUM(amount), CURRENT_TIMESTAMP
+FROM
+	point_ledger
+GROUP BY
+	user_id;
@@ -0,0 +1,5 @@
+DROP INDEX idx_redemptions_user_id;
+DROP INDEX idx_redemptions_voucher_code;
+DROP TABLE redemptions;
+
+DROP TABLE rewards;
@@ -0,0 +1,24 @@
+CREATE TABLE rewards (
+	reward_id {{.AutoIncrementPrimaryKey}},
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	cost INTEGER NOT NULL CHECK (cost > 0),
+	-- NULL means the reward never runs out.
+	stock INTEGER NULL CHECK (stock >= 0),
+	valid_from TIMESTAMP NULL,
+	valid_until TIMESTAMP NULL
+);
+
+CREATE TABLE redemptions (
+	redemption_id {{.AutoIncrementPrimaryKey}},
+	user_id VARCHAR(255) NOT NULL,
+	reward_id INTEGER NOT NULL,
+	cost INTEGER NOT NULL,
+	voucher_code VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (reward_id) REFERENCES rewards (reward_id) ON DELETE RESTRICT ON UPDATE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_redemptions_voucher_code ON redemptions (voucher_code);
+CREATE INDEX idx_redemptions_user_id ON redemptions (user_id);
@@ -0,0 +1,10 @@
+DROP INDEX idx_voucher_audit_log_merchant_id;
+DROP TABLE voucher_audit_log;
+
+ALTER TABLE redemptions DROP COLUMN voucher_used_by;
+ALTER TABLE redemptions DROP COLUMN voucher_used_at;
+
+ALTER TABLE api_tokens DROP COLUMN merchant_id;
+ALTER TABLE api_tokens DROP COLUMN kind;
+
+DROP TABLE merchants;
@@ -0,0 +1,27 @@
+CREATE TABLE merchants (
+	merchant_id {{.AutoIncrementPrimaryKey}},
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+-- Existing tokens all belong to machines.
+ALTER TABLE api_tokens ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'machine';
+ALTER TABLE api_tokens ADD COLUMN merchant_id INTEGER NULL
+	REFERENCES merchants (merchant_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE redemptions ADD COLUMN voucher_used_at TIMESTAMP NULL;
+ALTER TABLE redemptions ADD COLUMN voucher_used_by INTEGER NULL
+	REFERENCES merchants (merchant_id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+CREATE TABLE voucher_audit_log (
+	voucher_audit_log_id {{.AutoIncrementPrimaryKey}},
+	merchant_id INTEGER NOT NULL,
+	-- Not a foreign key: lookups of codes that don't exist are recorded too.
+	voucher_code VARCHAR(32) NOT NULL,
+	action VARCHAR(16) NOT NULL,
+	outcome VARCHAR(16) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (merchant_id) REFERENCES merchants (merchant_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_voucher_audit_log_merchant_id ON voucher_audit_log (merchant_id, created_at);
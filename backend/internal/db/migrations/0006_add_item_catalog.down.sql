@@ -0,0 +1,5 @@
+ALTER TABLE items DROP COLUMN is_active;
+ALTER TABLE items DROP COLUMN volume_ml;
+ALTER TABLE items DROP COLUMN material;
+
+ALTER TABLE users DROP COLUMN is_admin;
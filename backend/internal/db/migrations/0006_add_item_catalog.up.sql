@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE items ADD COLUMN material VARCHAR(16) NOT NULL DEFAULT 'other';
+ALTER TABLE items ADD COLUMN volume_ml INTEGER NULL;
+-- Archived items are kept so that past transactions can still resolve them.
+ALTER TABLE items ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE;
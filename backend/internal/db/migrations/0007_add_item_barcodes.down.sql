@@ -0,0 +1,2 @@
+DROP INDEX idx_item_barcodes_item_id;
+DROP TABLE item_barcodes;
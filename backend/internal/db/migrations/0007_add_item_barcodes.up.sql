@@ -0,0 +1,9 @@
+CREATE TABLE item_barcodes (
+	-- Always the 14 digit form of the GTIN.
+	barcode VARCHAR(14) PRIMARY KEY NOT NULL,
+	item_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE
+){{.WithoutRowID}};
+
+CREATE INDEX idx_item_barcodes_item_id ON item_barcodes (item_id);
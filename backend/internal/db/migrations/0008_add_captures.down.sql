@@ -0,0 +1,3 @@
+DROP TABLE capture_results;
+DROP INDEX idx_captures_created_at;
+DROP TABLE captures;
@@ -0,0 +1,24 @@
+CREATE TABLE captures (
+	-- The correlation id the capture request was published with.
+	request_id VARCHAR(36) PRIMARY KEY NOT NULL,
+	strategy VARCHAR(16) NOT NULL,
+	-- NULL when the sides didn't reach a consensus.
+	item_id INTEGER NULL,
+	confidence DOUBLE PRECISION NULL,
+	sides_agree BOOLEAN NOT NULL,
+	created_at TIMESTAMP NOT NULL
+){{.WithoutRowID}};
+
+CREATE INDEX idx_captures_created_at ON captures (created_at);
+
+-- item_id isn't a foreign key: the audit trail keeps whatever the classifier answered,
+-- even for items that have since been removed.
+CREATE TABLE capture_results (
+	request_id VARCHAR(36) NOT NULL,
+	side VARCHAR(32) NOT NULL,
+	-- NULL when the side didn't answer in time or its image couldn't be classified.
+	item_id INTEGER NULL,
+	confidence DOUBLE PRECISION NULL,
+	PRIMARY KEY (request_id, side),
+	FOREIGN KEY (request_id) REFERENCES captures (request_id) ON DELETE CASCADE ON UPDATE CASCADE
+){{.WithoutRowID}};
@@ -0,0 +1,4 @@
+DROP INDEX idx_transaction_items_capture_request_id;
+DROP TABLE item_reviews;
+ALTER TABLE transaction_items DROP COLUMN capture_request_id;
+ALTER TABLE capture_results DROP COLUMN image_key;
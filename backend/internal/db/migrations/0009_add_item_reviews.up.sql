@@ -0,0 +1,23 @@
+ALTER TABLE capture_results ADD COLUMN image_key VARCHAR(255) NULL;
+
+ALTER TABLE transaction_items ADD COLUMN capture_request_id VARCHAR(36) NULL
+	REFERENCES captures (request_id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- An item without a review that was classified from an uncertain capture is in the review queue.
+CREATE TABLE item_reviews (
+	transaction_item_id INTEGER PRIMARY KEY NOT NULL,
+	outcome VARCHAR(16) NOT NULL,
+	original_item_id INTEGER NOT NULL,
+	item_id INTEGER NOT NULL,
+	-- The points credited or debited because of a relabel after the transaction was claimed.
+	points_adjustment INTEGER NOT NULL DEFAULT 0,
+	reviewed_by VARCHAR(255) NOT NULL,
+	reviewed_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (transaction_item_id) REFERENCES transaction_items (transaction_item_id)
+		ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (original_item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (item_id) REFERENCES items (item_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (reviewed_by) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_transaction_items_capture_request_id ON transaction_items (capture_request_id);
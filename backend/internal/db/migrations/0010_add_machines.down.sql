@@ -0,0 +1,4 @@
+DROP INDEX idx_transactions_machine_id;
+ALTER TABLE transactions DROP COLUMN machine_id;
+ALTER TABLE api_tokens DROP COLUMN machine_id;
+DROP TABLE machines;
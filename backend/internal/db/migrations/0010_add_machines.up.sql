@@ -0,0 +1,24 @@
+CREATE TABLE machines (
+	machine_id {{.AutoIncrementPrimaryKey}},
+	name TEXT NOT NULL,
+	location TEXT NOT NULL DEFAULT '',
+	status VARCHAR(16) NOT NULL DEFAULT 'active',
+	created_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE api_tokens ADD COLUMN machine_id INTEGER NULL
+	REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- Transactions started before machines were registered stay without one.
+ALTER TABLE transactions ADD COLUMN machine_id INTEGER NULL
+	REFERENCES machines (machine_id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- Existing machine tokens can't be told apart, so they are all bound to a single machine
+-- that operators can rename, or retire once its machines have been issued their own tokens.
+INSERT INTO machines (name, location, status, created_at)
+SELECT 'Unregistered machine', '', 'active', CURRENT_TIMESTAMP
+WHERE EXISTS (SELECT 1 FROM api_tokens WHERE kind = 'machine');
+
+UPDATE api_tokens SET machine_id = (SELECT MIN(machine_id) FROM machines) WHERE kind = 'machine';
+
+CREATE INDEX idx_transactions_machine_id ON transactions (machine_id, created_at);
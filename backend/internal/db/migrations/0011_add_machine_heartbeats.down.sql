@@ -0,0 +1,3 @@
+DROP INDEX idx_machine_connectivity_events_machine_id;
+DROP TABLE machine_connectivity_events;
+DROP TABLE machine_heartbeats;
@@ -0,0 +1,21 @@
+-- Only the latest heartbeat of each machine is kept.
+CREATE TABLE machine_heartbeats (
+	machine_id INTEGER PRIMARY KEY NOT NULL,
+	firmware_version VARCHAR(64) NOT NULL,
+	uptime_seconds BIGINT NOT NULL,
+	free_heap_bytes BIGINT NOT NULL,
+	wifi_rssi INTEGER NOT NULL,
+	received_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- Every time a machine went online or offline.
+CREATE TABLE machine_connectivity_events (
+	machine_connectivity_event_id {{.AutoIncrementPrimaryKey}},
+	machine_id INTEGER NOT NULL,
+	connectivity VARCHAR(16) NOT NULL,
+	occurred_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_machine_connectivity_events_machine_id ON machine_connectivity_events (machine_id, occurred_at);
@@ -0,0 +1,8 @@
+DROP INDEX idx_bin_alerts_machine_id;
+DROP INDEX idx_bin_collections_machine_id;
+DROP TABLE bin_alerts;
+DROP TABLE bin_collections;
+ALTER TABLE machines DROP COLUMN bin_sensor_reported_at;
+ALTER TABLE machines DROP COLUMN bin_sensor_fill_percent;
+ALTER TABLE machines DROP COLUMN bin_emptied_at;
+ALTER TABLE machines DROP COLUMN bin_capacity;
@@ -0,0 +1,29 @@
+-- Capacity is in containers, since that's what transaction_items counts.
+ALTER TABLE machines ADD COLUMN bin_capacity INTEGER NOT NULL DEFAULT 200;
+ALTER TABLE machines ADD COLUMN bin_emptied_at TIMESTAMP NULL;
+-- The latest reading of the bin's fill-level sensor, for machines that have one.
+ALTER TABLE machines ADD COLUMN bin_sensor_fill_percent INTEGER NULL;
+ALTER TABLE machines ADD COLUMN bin_sensor_reported_at TIMESTAMP NULL;
+
+CREATE TABLE bin_collections (
+	bin_collection_id {{.AutoIncrementPrimaryKey}},
+	machine_id INTEGER NOT NULL,
+	-- How full the bin was estimated to be when it was emptied.
+	estimated_items INTEGER NOT NULL,
+	emptied_by VARCHAR(255) NOT NULL,
+	emptied_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (emptied_by) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE bin_alerts (
+	bin_alert_id {{.AutoIncrementPrimaryKey}},
+	machine_id INTEGER NOT NULL,
+	level VARCHAR(16) NOT NULL,
+	fill_percent INTEGER NOT NULL,
+	raised_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_bin_collections_machine_id ON bin_collections (machine_id, emptied_at);
+CREATE INDEX idx_bin_alerts_machine_id ON bin_alerts (machine_id, raised_at);
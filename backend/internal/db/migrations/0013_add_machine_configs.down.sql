@@ -0,0 +1 @@
+DROP TABLE machine_configs;
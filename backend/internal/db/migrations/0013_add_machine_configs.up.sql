@@ -0,0 +1,13 @@
+-- Every change to a machine's configuration is kept as a new version. The latest one is current.
+CREATE TABLE machine_configs (
+	machine_id INTEGER NOT NULL,
+	version INTEGER NOT NULL,
+	document TEXT NOT NULL,
+	-- The version this one restored, if it was created by a rollback.
+	rolled_back_from INTEGER NULL,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (machine_id, version),
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (created_by) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
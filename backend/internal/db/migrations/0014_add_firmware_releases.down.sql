@@ -0,0 +1,5 @@
+DROP INDEX idx_firmware_update_reports_machine_id;
+DROP INDEX idx_firmware_update_reports_release_id;
+DROP TABLE firmware_update_reports;
+DROP TABLE firmware_rollout_machines;
+DROP TABLE firmware_releases;
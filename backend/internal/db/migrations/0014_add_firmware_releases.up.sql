@@ -0,0 +1,37 @@
+-- The images themselves are kept in the blob store under firmware/<version>.bin.
+CREATE TABLE firmware_releases (
+	firmware_release_id {{.AutoIncrementPrimaryKey}},
+	version VARCHAR(64) NOT NULL UNIQUE,
+	sha256 CHAR(64) NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	notes TEXT NOT NULL DEFAULT '',
+	-- The share of machines, besides the ones in firmware_rollout_machines, that are offered the release.
+	rollout_percent INTEGER NOT NULL DEFAULT 0,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (created_by) REFERENCES users (user_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE firmware_rollout_machines (
+	firmware_release_id INTEGER NOT NULL,
+	machine_id INTEGER NOT NULL,
+	PRIMARY KEY (firmware_release_id, machine_id),
+	FOREIGN KEY (firmware_release_id) REFERENCES firmware_releases (firmware_release_id)
+		ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE firmware_update_reports (
+	firmware_update_report_id {{.AutoIncrementPrimaryKey}},
+	firmware_release_id INTEGER NOT NULL,
+	machine_id INTEGER NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	detail TEXT NOT NULL DEFAULT '',
+	reported_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (firmware_release_id) REFERENCES firmware_releases (firmware_release_id)
+		ON DELETE CASCADE ON UPDATE CASCADE,
+	FOREIGN KEY (machine_id) REFERENCES machines (machine_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_firmware_update_reports_release_id ON firmware_update_reports (firmware_release_id, reported_at);
+CREATE INDEX idx_firmware_update_reports_machine_id ON firmware_update_reports (machine_id, status);
@@ -0,0 +1 @@
+ALTER TABLE transaction_items DROP COLUMN points;
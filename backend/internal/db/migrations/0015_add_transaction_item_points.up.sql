@@ -0,0 +1,11 @@
+-- The points the item was credited with when its transaction was claimed, NULL until then.
+-- Relabels adjust against this rather than the catalog, whose points may have changed since.
+ALTER TABLE transaction_items ADD COLUMN points INTEGER NULL;
+
+-- Claims so far only recorded the transaction's total, so the items' current points are the best guess.
+UPDATE
+	transaction_items
+SET
+	points = (SELECT items.points FROM items WHERE items.item_id = transaction_items.item_id)
+WHERE
+	transaction_id IN (SELECT transaction_id FROM transactions WHERE user_id IS NOT NULL);
@@ -0,0 +1,6 @@
+-- Tokens are stored as their SHA-256 hash. Machine tokens issued before this are stored as is,
+-- and are only looked up by their raw value while this is FALSE.
+ALTER TABLE api_tokens ADD COLUMN is_hashed BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Merchant tokens have been hashed since they could be issued.
+UPDATE api_tokens SET is_hashed = TRUE WHERE kind = 'merchant';
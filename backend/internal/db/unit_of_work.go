@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Queryer is implemented by both *DB and *Tx, which rebind ? placeholders for their dialect,
+// so repositories built on top of it can be used on their own or as part of a UnitOfWork.
+type Queryer interface {
+	Get(dest any, query string, args ...any) error
+	Select(dest any, query string, args ...any) error
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// UnitOfWork runs a function against repositories that all share a single database transaction.
+// The transaction is committed if the function returns nil and rolled back otherwise.
+type UnitOfWork[R any] interface {
+	Do(fn func(r R) error) error
+}
+
+type SQLUnitOfWork[R any] struct {
+	db      *DB
+	newRepo func(q Queryer) R
+}
+
+// NewSQLUnitOfWork creates a unit of work that builds its repositories with newRepo,
+// passing in the transaction each time Do is called.
+func NewSQLUnitOfWork[R any](db *DB, newRepo func(q Queryer) R) *SQLUnitOfWork[R] {
+	return &SQLUnitOfWork[R]{db: db, newRepo: newRepo}
+}
+
+// Do returns the error from fn as is, so callers can still match it with errors.Is.
+func (u *SQLUnitOfWork[R]) Do(fn func(r R) error) error {
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("Do(): failed to begin transaction: %w", err)
+	}
+
+	finished := false
+	defer func() {
+		// fn panicked; don't leave the transaction open.
+		if !finished {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = fn(u.newRepo(tx))
+	finished = true
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("Do(): failed to roll back transaction: %w", rbErr))
+		}
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Do(): failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
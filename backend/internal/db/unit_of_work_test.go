@@ -0,0 +1,55 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+)
+
+var errAbort = errors.New("abort")
+
+func TestSQLUnitOfWork(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	uow := db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) db.Queryer {
+		return q
+	})
+
+	insert := func(q db.Queryer, name string) error {
+		_, err := q.Exec(`
+			INSERT INTO
+				machines (name, created_at)
+			VALUES
+				(?, CURRENT_TIMESTAMP)
+		`, name)
+
+		return err
+	}
+
+	err := uow.Do(func(q db.Queryer) error {
+		if err := insert(q, "rolled back"); err != nil {
+			return err
+		}
+
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("got error %v, want %v", err, errAbort)
+	}
+
+	if err = uow.Do(func(q db.Queryer) error {
+		return insert(q, "committed")
+	}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	var names []string
+	if err = dbHandle.Select(&names, "SELECT name FROM machines ORDER BY machine_id"); err != nil {
+		t.Fatalf("failed to select machines: %v", err)
+	}
+
+	if len(names) != 1 || names[0] != "committed" {
+		t.Errorf("got machines %v, want only the committed one", names)
+	}
+}
@@ -4,6 +4,21 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTransactionClaimWindow    = 15 * time.Minute
+	defaultTransactionSessionTimeout = 30 * time.Minute
+	defaultTransactionSweepInterval  = time.Minute
+	defaultCaptureTimeout            = 5 * time.Second
+	defaultReviewThreshold           = 0.8
+	// Machines send a heartbeat every minute, so this tolerates a couple of lost ones.
+	defaultMachineHeartbeatGracePeriod = 3 * time.Minute
+	defaultMachineMonitorInterval      = 30 * time.Second
+	defaultBinAlertThreshold           = 0.8
 )
 
 type AppEnv string
@@ -22,6 +37,26 @@ func GetAppEnv() AppEnv {
 	return AppEnvDevelopment
 }
 
+// GetDBDriver returns which database to use: "sqlite" (the default) or "postgres".
+func GetDBDriver() string {
+	env := os.Getenv("DATABASE_DRIVER")
+	if env == "" {
+		return "sqlite"
+	}
+
+	return env
+}
+
+// GetDBURL returns the Postgres connection string.
+func GetDBURL() (string, error) {
+	env := os.Getenv("DATABASE_URL")
+	if env == "" {
+		return "", fmt.Errorf("GetDBURL(): DATABASE_URL not set")
+	}
+
+	return env, nil
+}
+
 func GetDBPath() string {
 	env := os.Getenv("DATABASE_FILE_PATH")
 	if env == "" {
@@ -44,3 +79,185 @@ func GetFirebaseCredentialsJSON() ([]byte, error) {
 
 	return decoded, nil
 }
+
+// GetTransactionClaimWindow returns how long a closed transaction can be claimed for.
+func GetTransactionClaimWindow() (time.Duration, error) {
+	return getDuration("TRANSACTION_CLAIM_WINDOW", defaultTransactionClaimWindow)
+}
+
+// GetTransactionSessionTimeout returns how long a transaction can stay open before it expires.
+func GetTransactionSessionTimeout() (time.Duration, error) {
+	return getDuration("TRANSACTION_SESSION_TIMEOUT", defaultTransactionSessionTimeout)
+}
+
+// GetTransactionSweepInterval returns how often stale transactions are expired.
+func GetTransactionSweepInterval() (time.Duration, error) {
+	return getDuration("TRANSACTION_SWEEP_INTERVAL", defaultTransactionSweepInterval)
+}
+
+// GetClassifier returns which image classifier to use. Only "stub" is available for now.
+func GetClassifier() string {
+	env := os.Getenv("CLASSIFIER")
+	if env == "" {
+		return "stub"
+	}
+
+	return env
+}
+
+// GetStubClassifierItemIDs returns the items the stub classifier picks from, e.g. "1,2,3".
+func GetStubClassifierItemIDs() ([]int, error) {
+	env := os.Getenv("STUB_CLASSIFIER_ITEM_IDS")
+	if env == "" {
+		return []int{1}, nil
+	}
+
+	parts := strings.Split(env, ",")
+	ids := make([]int, 0, len(parts))
+
+	for _, p := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("GetStubClassifierItemIDs(): failed to parse STUB_CLASSIFIER_ITEM_IDS: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetMQTTBrokerURL returns the broker the camera modules talk to, e.g. ssl://broker.example.com:8883.
+// Capturing is disabled when it is empty.
+func GetMQTTBrokerURL() string {
+	return os.Getenv("MQTT_BROKER_URL")
+}
+
+func GetMQTTUsername() string {
+	return os.Getenv("MQTT_USERNAME")
+}
+
+func GetMQTTPassword() string {
+	return os.Getenv("MQTT_PASSWORD")
+}
+
+// GetMQTTClientID returns the client id the backend connects to the broker with.
+func GetMQTTClientID() string {
+	env := os.Getenv("MQTT_CLIENT_ID")
+	if env == "" {
+		return "rvm-backend"
+	}
+
+	return env
+}
+
+// GetCaptureSides returns the camera sides that answer capture requests, e.g. "top,left".
+func GetCaptureSides() []string {
+	env := os.Getenv("CAPTURE_SIDES")
+	if env == "" {
+		return []string{"top"}
+	}
+
+	parts := strings.Split(env, ",")
+	sides := make([]string, 0, len(parts))
+
+	for _, p := range parts {
+		if side := strings.TrimSpace(p); side != "" {
+			sides = append(sides, side)
+		}
+	}
+
+	return sides
+}
+
+// GetCaptureTimeout returns how long to wait for the cameras to answer a capture request.
+func GetCaptureTimeout() (time.Duration, error) {
+	return getDuration("CAPTURE_TIMEOUT", defaultCaptureTimeout)
+}
+
+// GetCaptureFusionStrategy returns how the results of several camera sides are combined:
+// "majority" (the default), "max_confidence" or "all_must_agree".
+func GetCaptureFusionStrategy() string {
+	env := os.Getenv("CAPTURE_FUSION_STRATEGY")
+	if env == "" {
+		return "majority"
+	}
+
+	return env
+}
+
+// GetBlobStoreDir returns the directory captured images are kept in.
+func GetBlobStoreDir() string {
+	env := os.Getenv("BLOB_STORE_DIR")
+	if env == "" {
+		return "./blobs"
+	}
+
+	return env
+}
+
+// GetReviewConfidenceThreshold returns the fused confidence below which an item needs to be reviewed.
+func GetReviewConfidenceThreshold() (float64, error) {
+	env := os.Getenv("REVIEW_CONFIDENCE_THRESHOLD")
+	if env == "" {
+		return defaultReviewThreshold, nil
+	}
+
+	threshold, err := strconv.ParseFloat(env, 64)
+	if err != nil {
+		return 0, fmt.Errorf("GetReviewConfidenceThreshold(): failed to parse REVIEW_CONFIDENCE_THRESHOLD: %w", err)
+	}
+
+	if threshold < 0 || threshold > 1 {
+		return 0, fmt.Errorf("GetReviewConfidenceThreshold(): REVIEW_CONFIDENCE_THRESHOLD must be between 0 and 1")
+	}
+
+	return threshold, nil
+}
+
+// GetMachineHeartbeatGracePeriod returns how long a machine can go without a heartbeat before it counts as offline.
+func GetMachineHeartbeatGracePeriod() (time.Duration, error) {
+	return getDuration("MACHINE_HEARTBEAT_GRACE_PERIOD", defaultMachineHeartbeatGracePeriod)
+}
+
+// GetMachineMonitorInterval returns how often machines that stopped sending heartbeats are marked offline.
+func GetMachineMonitorInterval() (time.Duration, error) {
+	return getDuration("MACHINE_MONITOR_INTERVAL", defaultMachineMonitorInterval)
+}
+
+// GetBinAlertThreshold returns the fraction of a bin's capacity at which collectors are alerted.
+func GetBinAlertThreshold() (float64, error) {
+	env := os.Getenv("BIN_ALERT_THRESHOLD")
+	if env == "" {
+		return defaultBinAlertThreshold, nil
+	}
+
+	threshold, err := strconv.ParseFloat(env, 64)
+	if err != nil {
+		return 0, fmt.Errorf("GetBinAlertThreshold(): failed to parse BIN_ALERT_THRESHOLD: %w", err)
+	}
+
+	if threshold <= 0 || threshold > 1 {
+		return 0, fmt.Errorf("GetBinAlertThreshold(): BIN_ALERT_THRESHOLD must be above 0 and at most 1")
+	}
+
+	return threshold, nil
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	env := os.Getenv(key)
+	if env == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(env)
+	if err != nil {
+		return 0, fmt.Errorf("getDuration(): failed to parse %s: %w", key, err)
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("getDuration(): %s must be positive", key)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	maxVersionLength = 64
+	maxNotesLength   = 4096
+	// MaxImageSize is the size of the camera module's app partition in the min_spiffs layout.
+	MaxImageSize = 1920 << 10
+)
+
+// InvalidReleaseError is returned when a firmware release or its rollout can't be accepted.
+type InvalidReleaseError struct {
+	Reason string
+}
+
+func (e *InvalidReleaseError) Error() string {
+	return "invalid firmware release: " + e.Reason
+}
+
+// Release is a firmware image for the camera module that can be rolled out to machines.
+type Release struct {
+	ID      int
+	Version string
+	// SHA256 is the hex-encoded checksum of the image, for machines to verify what they downloaded.
+	SHA256    string
+	SizeBytes int
+	Notes     string
+	Rollout   Rollout
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// NewRelease creates a release of the image that isn't rolled out to any machine yet,
+// returning an *InvalidReleaseError if the version, notes or image are unacceptable.
+func NewRelease(version string, notes string, image []byte, createdBy string, createdAt time.Time) (Release, error) {
+	if err := validateVersion(version); err != nil {
+		return Release{}, err
+	}
+
+	if len(notes) > maxNotesLength {
+		return Release{}, &InvalidReleaseError{
+			Reason: fmt.Sprintf("notes can't be longer than %d characters", maxNotesLength),
+		}
+	}
+
+	if len(image) == 0 {
+		return Release{}, &InvalidReleaseError{Reason: "image is empty"}
+	}
+
+	if len(image) > MaxImageSize {
+		return Release{}, &InvalidReleaseError{Reason: fmt.Sprintf("image must be at most %d bytes", MaxImageSize)}
+	}
+
+	sum := sha256.Sum256(image)
+
+	return Release{
+		Version:   version,
+		SHA256:    hex.EncodeToString(sum[:]),
+		SizeBytes: len(image),
+		Notes:     notes,
+		CreatedBy: createdBy,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// ImageKey is where the release's image is kept in the blob store.
+func (r Release) ImageKey() string {
+	return "firmware/" + r.Version + ".bin"
+}
+
+// validateVersion only allows characters that are safe in a blob key, such as those of "0.3.0" or "0.4.0-rc.1".
+func validateVersion(version string) error {
+	if version == "" || len(version) > maxVersionLength {
+		return &InvalidReleaseError{
+			Reason: fmt.Sprintf("version must be between 1 and %d characters long", maxVersionLength),
+		}
+	}
+
+	for _, c := range version {
+		isAlphanumeric := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isAlphanumeric && c != '.' && c != '-' && c != '_' && c != '+' {
+			return &InvalidReleaseError{
+				Reason: "version can only contain letters, digits, '.', '-', '_' and '+'",
+			}
+		}
+	}
+
+	return nil
+}
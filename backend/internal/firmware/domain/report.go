@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const maxDetailLength = 1024
+
+type UpdateStatus string
+
+const (
+	// UpdateStatusSucceeded means the machine installed the release and booted into it.
+	UpdateStatusSucceeded UpdateStatus = "succeeded"
+	// UpdateStatusFailed means the machine couldn't download, verify or install the release.
+	// The machine isn't offered the release again.
+	UpdateStatusFailed UpdateStatus = "failed"
+)
+
+func NewUpdateStatus(value string) (UpdateStatus, error) {
+	switch s := UpdateStatus(value); s {
+	case UpdateStatusSucceeded, UpdateStatusFailed:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("unknown update status %q", value)
+}
+
+func (s UpdateStatus) String() string {
+	return string(s)
+}
+
+// UpdateReport is a machine's account of how installing a release went.
+type UpdateReport struct {
+	ReleaseID int
+	MachineID int
+	Status    UpdateStatus
+	// Detail is whatever the machine had to say, such as why the update failed.
+	Detail     string
+	ReportedAt time.Time
+}
+
+// NewUpdateReport returns an *InvalidReleaseError if the detail is too long.
+func NewUpdateReport(
+	releaseID int,
+	machineID int,
+	status UpdateStatus,
+	detail string,
+	reportedAt time.Time,
+) (UpdateReport, error) {
+	if len(detail) > maxDetailLength {
+		return UpdateReport{}, &InvalidReleaseError{
+			Reason: fmt.Sprintf("detail can't be longer than %d characters", maxDetailLength),
+		}
+	}
+
+	return UpdateReport{
+		ReleaseID:  releaseID,
+		MachineID:  machineID,
+		Status:     status,
+		Detail:     detail,
+		ReportedAt: reportedAt,
+	}, nil
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+const maxRolloutPercent = 100
+
+// Rollout decides which machines are offered a release.
+type Rollout struct {
+	// Percent is the share of all machines that are offered the release, from 0 to 100.
+	Percent int
+	// MachineIDs are offered the release regardless of Percent, e.g. to try it on a few machines first.
+	MachineIDs []int
+}
+
+// NewRollout returns an *InvalidReleaseError if the percentage is out of range.
+func NewRollout(percent int, machineIDs []int) (Rollout, error) {
+	if percent < 0 || percent > maxRolloutPercent {
+		return Rollout{}, &InvalidReleaseError{
+			Reason: fmt.Sprintf("percent must be between 0 and %d", maxRolloutPercent),
+		}
+	}
+
+	return Rollout{Percent: percent, MachineIDs: machineIDs}, nil
+}
+
+// Includes reports whether the machine is offered the release. Each machine falls in a fixed bucket per release,
+// so raising the percentage only ever adds machines, and different releases start with different machines.
+func (r Rollout) Includes(releaseID int, machineID int) bool {
+	for _, id := range r.MachineIDs {
+		if id == machineID {
+			return true
+		}
+	}
+
+	return rolloutBucket(releaseID, machineID) < r.Percent
+}
+
+func rolloutBucket(releaseID int, machineID int) int {
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%d:%d", releaseID, machineID)
+
+	return int(h.Sum32() % maxRolloutPercent)
+}
@@ -0,0 +1,111 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/firmware/domain"
+)
+
+// machineCount is how many machines the percentage tests spread a release over.
+const machineCount = 1000
+
+func TestNewRollout(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		percent int
+		wantErr bool
+	}{
+		{name: "none", percent: 0},
+		{name: "some", percent: 25},
+		{name: "all", percent: 100},
+		{name: "negative", percent: -1, wantErr: true},
+		{name: "over 100", percent: 101, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := domain.NewRollout(tc.percent, nil)
+
+			var invalid *domain.InvalidReleaseError
+			if got := errors.As(err, &invalid); got != tc.wantErr {
+				t.Errorf("got error %v, want an *InvalidReleaseError: %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRolloutIncludes(t *testing.T) {
+	const releaseID = 7
+
+	for _, tc := range []struct {
+		name       string
+		percent    int
+		machineIDs []int
+		machineID  int
+		want       bool
+	}{
+		{name: "no machines", percent: 0, machineID: 1, want: false},
+		{name: "all machines", percent: 100, machineID: 1, want: true},
+		{name: "listed machine", percent: 0, machineIDs: []int{3, 1}, machineID: 1, want: true},
+		{name: "unlisted machine", percent: 0, machineIDs: []int{3, 4}, machineID: 1, want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := domain.NewRollout(tc.percent, tc.machineIDs)
+			if err != nil {
+				t.Fatalf("failed to create rollout: %v", err)
+			}
+
+			if got := r.Includes(releaseID, tc.machineID); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRolloutPercentage(t *testing.T) {
+	const releaseID = 7
+
+	included := make(map[int]bool)
+
+	for _, percent := range []int{10, 25, 50, 90} {
+		r, err := domain.NewRollout(percent, nil)
+		if err != nil {
+			t.Fatalf("failed to create rollout: %v", err)
+		}
+
+		count := 0
+
+		for machineID := 1; machineID <= machineCount; machineID++ {
+			if !r.Includes(releaseID, machineID) {
+				if included[machineID] {
+					t.Errorf("machine %d dropped when raising the rollout to %d%%", machineID, percent)
+				}
+
+				continue
+			}
+
+			included[machineID] = true
+			count++
+		}
+
+		// The buckets come from a hash, so the share is only roughly the percentage.
+		want := machineCount * percent / 100
+		if count < want-machineCount/20 || count > want+machineCount/20 {
+			t.Errorf("%d%%: got %d of %d machines, want about %d", percent, count, machineCount, want)
+		}
+	}
+}
+
+func TestRolloutBucketsDifferPerRelease(t *testing.T) {
+	r, err := domain.NewRollout(50, nil)
+	if err != nil {
+		t.Fatalf("failed to create rollout: %v", err)
+	}
+
+	for machineID := 1; machineID <= machineCount; machineID++ {
+		if r.Includes(1, machineID) != r.Includes(2, machineID) {
+			return
+		}
+	}
+
+	t.Error("got the same machines for two releases, want different ones")
+}
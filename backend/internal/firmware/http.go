@@ -0,0 +1,472 @@
+package firmware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/auth"
+	"github.com/JosephJoshua/rvm/backend/internal/firmware/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// multipartOverhead allows for the version, the notes and the multipart boundaries and headers around the image.
+const multipartOverhead = 16 << 10
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeReleaseDoesNotExist = "release_does_not_exist"
+	codeVersionExists       = "version_exists"
+	codeMachineDoesNotExist = "machine_does_not_exist"
+	codeImageTooLarge       = "image_too_large"
+)
+
+type releaseResponse struct {
+	ID                int       `json:"id"`
+	Version           string    `json:"version"`
+	SHA256            string    `json:"sha256"`
+	SizeBytes         int       `json:"size_bytes"`
+	Notes             string    `json:"notes"`
+	RolloutPercent    int       `json:"rollout_percent"`
+	RolloutMachineIDs []int     `json:"rollout_machine_ids"`
+	CreatedBy         string    `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type updateResponse struct {
+	ReleaseID int    `json:"release_id"`
+	Version   string `json:"version"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int    `json:"size_bytes"`
+	ImagePath string `json:"image_path"`
+}
+
+type updateReportResponse struct {
+	MachineID  int       `json:"machine_id"`
+	Status     string    `json:"status"`
+	Detail     string    `json:"detail"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new firmware HTTP handler for operators.
+// It expects auth.LoggedInMiddleware and auth.AdminOnlyMiddleware to run before it.
+//   - GET /firmware - returns every release and its rollout as JSON, newest first.
+//   - POST /firmware - uploads the firmware image in the multipart field image as a new release
+//     and returns it as JSON. version is a required form value; notes is optional.
+//     New releases aren't offered to any machine until they are rolled out.
+//   - PUT /firmware/{releaseID}/rollout - sets which machines are offered the release and returns it as JSON.
+//     percent (0-100) is a required form value; machine_ids is an optional comma-separated list of machines
+//     that are offered the release regardless of the percentage.
+//   - GET /firmware/{releaseID}/reports - returns the latest reports of machines installing the release as JSON,
+//     newest first.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getReleases))
+	r.Post("/", httputils.HandlerFunc(handler.createRelease))
+	r.Put("/{releaseID}/rollout", httputils.HandlerFunc(handler.setRollout))
+	r.Get("/{releaseID}/reports", httputils.HandlerFunc(handler.getUpdateReports))
+
+	handler.Router = r
+	return handler
+}
+
+// NewUpdateHTTPHandler creates a new HTTP handler for machines to update their firmware.
+// It expects apitoken.ValidTokenMiddleware to run before it.
+//   - GET /firmware/updates - returns the release the token's machine should install as JSON,
+//     or 204 No Content if it is up to date. current_version is a required query parameter.
+//   - GET /firmware/updates/{releaseID}/image - returns the release's image. Its SHA-256 checksum
+//     is sent in the X-Checksum-SHA256 header.
+//   - POST /firmware/updates/{releaseID}/reports - records whether the machine installed the release.
+//     status (succeeded or failed) is a required form value; detail is optional.
+//     Machines that failed to install a release aren't offered it again.
+func NewUpdateHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.checkForUpdate))
+	r.Get("/{releaseID}/image", httputils.HandlerFunc(handler.getImage))
+	r.Post("/{releaseID}/reports", httputils.HandlerFunc(handler.reportUpdate))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getReleases(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	releases, err := h.s.GetReleases()
+	if err != nil {
+		oplog.Error("failed to get firmware releases", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]releaseResponse, 0, len(releases))
+	for _, rel := range releases {
+		res = append(res, toReleaseResponse(rel))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) createRelease(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxImageSize+multipartOverhead)
+
+	image, status, err := readImage(r)
+	if err != nil {
+		oplog.Error("invalid firmware upload", logging.ErrAttr(err), slog.Int("status", status))
+
+		code := httputils.CodeInvalidRequest
+		if status == http.StatusRequestEntityTooLarge {
+			code = codeImageTooLarge
+		}
+
+		w.TryWriteProblem(&oplog, status, code, err.Error())
+
+		return
+	}
+
+	rel, err := h.s.CreateRelease(
+		r.Context(),
+		r.FormValue("version"),
+		r.FormValue("notes"),
+		image,
+		auth.UIDFromCtx(r.Context()),
+	)
+	if err != nil {
+		var invalidErr *domain.InvalidReleaseError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid firmware release", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		if errors.Is(err, ErrVersionExists) {
+			oplog.Error("firmware version already exists", slog.String("version", r.FormValue("version")))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeVersionExists, "version already exists")
+
+			return
+		}
+
+		oplog.Error("failed to create firmware release", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	oplog.Info("created firmware release", slog.Int("release_id", rel.ID), slog.String("version", rel.Version))
+	w.TryWriteJSON(&oplog, http.StatusCreated, toReleaseResponse(rel))
+}
+
+func (h *HTTPHandler) setRollout(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	releaseID, err := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	if err != nil {
+		oplog.Error("failed to convert release id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+		return
+	}
+
+	badRequest := func(msg string) {
+		oplog.Error("invalid rollout", slog.String("reason", msg), slog.Int("release_id", releaseID))
+
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, msg)
+	}
+
+	percent, err := strconv.Atoi(r.FormValue("percent"))
+	if err != nil {
+		badRequest("percent has to be an integer")
+		return
+	}
+
+	var machineIDs []int
+
+	if v := r.FormValue("machine_ids"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				badRequest("machine_ids has to be a comma-separated list of integers")
+				return
+			}
+
+			machineIDs = append(machineIDs, id)
+		}
+	}
+
+	rel, err := h.s.SetRollout(releaseID, percent, machineIDs)
+	if err != nil {
+		if errors.Is(err, ErrReleaseDoesNotExist) {
+			oplog.Error("firmware release not found", slog.Int("release_id", releaseID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("invalid rollout", logging.ErrAttr(err), slog.Int("release_id", releaseID))
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		var invalidErr *domain.InvalidReleaseError
+		if errors.As(err, &invalidErr) {
+			badRequest(invalidErr.Reason)
+			return
+		}
+
+		oplog.Error("failed to set rollout", logging.ErrAttr(err), slog.Int("release_id", releaseID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	oplog.Info(
+		"set firmware rollout",
+		slog.Int("release_id", releaseID),
+		slog.Int("percent", percent),
+		slog.Int("machines", len(machineIDs)),
+	)
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toReleaseResponse(rel))
+}
+
+func (h *HTTPHandler) getUpdateReports(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	releaseID, err := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	if err != nil {
+		oplog.Error("failed to convert release id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+		return
+	}
+
+	reports, err := h.s.GetUpdateReports(releaseID)
+	if err != nil {
+		if errors.Is(err, ErrReleaseDoesNotExist) {
+			oplog.Error("firmware release not found", slog.Int("release_id", releaseID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+			return
+		}
+
+		oplog.Error("failed to get update reports", logging.ErrAttr(err), slog.Int("release_id", releaseID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]updateReportResponse, 0, len(reports))
+	for _, rep := range reports {
+		res = append(res, updateReportResponse{
+			MachineID:  rep.MachineID,
+			Status:     rep.Status.String(),
+			Detail:     rep.Detail,
+			ReportedAt: rep.ReportedAt,
+		})
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) checkForUpdate(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID := apitoken.MachineIDFromCtx(r.Context())
+
+	currentVersion := r.URL.Query().Get("current_version")
+	if currentVersion == "" {
+		oplog.Error("missing current firmware version", slog.Int("machine_id", machineID))
+
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, "current_version is required")
+
+		return
+	}
+
+	rel, ok, err := h.s.CheckForUpdate(machineID, currentVersion)
+	if err != nil {
+		oplog.Error("failed to check for firmware update", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, updateResponse{
+		ReleaseID: rel.ID,
+		Version:   rel.Version,
+		SHA256:    rel.SHA256,
+		SizeBytes: rel.SizeBytes,
+		ImagePath: fmt.Sprintf("/firmware/updates/%d/image", rel.ID),
+	})
+}
+
+func (h *HTTPHandler) getImage(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	releaseID, err := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	if err != nil {
+		oplog.Error("failed to convert release id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+		return
+	}
+
+	rel, image, err := h.s.GetImage(r.Context(), releaseID)
+	if err != nil {
+		if errors.Is(err, ErrReleaseDoesNotExist) {
+			oplog.Error("firmware release not found", slog.Int("release_id", releaseID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+			return
+		}
+
+		oplog.Error("failed to get firmware image", logging.ErrAttr(err), slog.Int("release_id", releaseID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+	w.Header().Set("X-Checksum-SHA256", rel.SHA256)
+	w.TryWrite(&oplog, image)
+}
+
+func (h *HTTPHandler) reportUpdate(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID := apitoken.MachineIDFromCtx(r.Context())
+
+	releaseID, err := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	if err != nil {
+		oplog.Error("failed to convert release id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+		return
+	}
+
+	status, err := domain.NewUpdateStatus(r.FormValue("status"))
+	if err != nil {
+		oplog.Error("invalid update status", logging.ErrAttr(err))
+
+		w.TryWriteProblem(
+			&oplog,
+			http.StatusBadRequest,
+			httputils.CodeInvalidRequest,
+			"status has to be one of succeeded or failed",
+		)
+
+		return
+	}
+
+	_, err = h.s.ReportUpdate(machineID, releaseID, status, r.FormValue("detail"))
+	if err != nil {
+		if errors.Is(err, ErrReleaseDoesNotExist) {
+			oplog.Error("firmware release not found", slog.Int("release_id", releaseID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeReleaseDoesNotExist, "release not found")
+
+			return
+		}
+
+		var invalidErr *domain.InvalidReleaseError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid update report", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to report update", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	oplog.Info(
+		"machine reported firmware update",
+		slog.Int("machine_id", machineID),
+		slog.Int("release_id", releaseID),
+		slog.String("status", status.String()),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readImage reads the image field of a multipart request, leaving the other fields in r.Form.
+// If it can't, it returns the status code to respond with and an error fit to show the uploader.
+func readImage(r *http.Request) ([]byte, int, error) {
+	if err := r.ParseMultipartForm(domain.MaxImageSize + multipartOverhead); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("image must be at most %d bytes", domain.MaxImageSize)
+		}
+
+		return nil, http.StatusBadRequest, fmt.Errorf("request must be a multipart/form-data upload")
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("image is required")
+	}
+
+	defer file.Close()
+
+	image, err := io.ReadAll(file)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to read image")
+	}
+
+	return image, http.StatusOK, nil
+}
+
+func toReleaseResponse(rel domain.Release) releaseResponse {
+	machineIDs := rel.Rollout.MachineIDs
+	if machineIDs == nil {
+		machineIDs = []int{}
+	}
+
+	return releaseResponse{
+		ID:                rel.ID,
+		Version:           rel.Version,
+		SHA256:            rel.SHA256,
+		SizeBytes:         rel.SizeBytes,
+		Notes:             rel.Notes,
+		RolloutPercent:    rel.Rollout.Percent,
+		RolloutMachineIDs: machineIDs,
+		CreatedBy:         rel.CreatedBy,
+		CreatedAt:         rel.CreatedAt,
+	}
+}
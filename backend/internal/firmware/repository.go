@@ -0,0 +1,21 @@
+package firmware
+
+import "github.com/JosephJoshua/rvm/backend/internal/firmware/domain"
+
+type Repository interface {
+	// GetReleases returns every release, newest first.
+	GetReleases() ([]domain.Release, error)
+	// GetRelease reports false if there is no release with the given id.
+	GetRelease(id int) (domain.Release, bool, error)
+	// GetReleaseByVersion reports false if there is no release with the given version.
+	GetReleaseByVersion(version string) (domain.Release, bool, error)
+	CreateRelease(r domain.Release) (int, error)
+	// SetRollout replaces the release's rollout percentage and machines.
+	SetRollout(releaseID int, rollout domain.Rollout) error
+	MachineExists(machineID int) (bool, error)
+	// GetFailedReleaseIDs returns the releases the machine reported failing to install.
+	GetFailedReleaseIDs(machineID int) ([]int, error)
+	AddUpdateReport(r domain.UpdateReport) error
+	// GetUpdateReports returns the release's latest update reports, newest first.
+	GetUpdateReports(releaseID int, limit int) ([]domain.UpdateReport, error)
+}
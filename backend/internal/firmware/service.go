@@ -0,0 +1,234 @@
+package firmware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/firmware/domain"
+)
+
+// updateReportsLimit is how many of a release's latest update reports can be looked at once.
+const updateReportsLimit = 100
+
+var (
+	ErrReleaseDoesNotExist = fmt.Errorf("firmware release does not exist")
+	ErrVersionExists       = fmt.Errorf("firmware version already exists")
+	ErrMachineDoesNotExist = fmt.Errorf("machine does not exist")
+	ErrImageDoesNotExist   = fmt.Errorf("firmware image does not exist")
+)
+
+type Service struct {
+	r   Repository
+	uow db.UnitOfWork[Repository]
+	bs  blob.Store
+}
+
+func NewService(r Repository, uow db.UnitOfWork[Repository], bs blob.Store) *Service {
+	return &Service{r: r, uow: uow, bs: bs}
+}
+
+// GetReleases returns every release along with its rollout, newest first.
+func (s *Service) GetReleases() ([]domain.Release, error) {
+	releases, err := s.r.GetReleases()
+	if err != nil {
+		return nil, fmt.Errorf("GetReleases(): failed to get releases: %w", err)
+	}
+
+	return releases, nil
+}
+
+// CreateRelease stores the image and records it as a release that isn't rolled out to any machine yet.
+// Invalid values return a *domain.InvalidReleaseError.
+func (s *Service) CreateRelease(
+	ctx context.Context,
+	version string,
+	notes string,
+	image []byte,
+	userID string,
+) (domain.Release, error) {
+	rel, err := domain.NewRelease(version, notes, image, userID, time.Now())
+	if err != nil {
+		return domain.Release{}, fmt.Errorf("CreateRelease(): %w", err)
+	}
+
+	_, exists, err := s.r.GetReleaseByVersion(version)
+	if err != nil {
+		return domain.Release{}, fmt.Errorf("CreateRelease(): failed to get release by version: %w", err)
+	}
+
+	if exists {
+		return domain.Release{}, fmt.Errorf("CreateRelease(): %w: %s", ErrVersionExists, version)
+	}
+
+	// The image goes in first so that a release is never offered without one.
+	if err = s.bs.Put(ctx, rel.ImageKey(), image); err != nil {
+		return domain.Release{}, fmt.Errorf("CreateRelease(): failed to store image: %w", err)
+	}
+
+	rel.ID, err = s.r.CreateRelease(rel)
+	if err != nil {
+		return domain.Release{}, fmt.Errorf("CreateRelease(): failed to create release: %w", err)
+	}
+
+	return rel, nil
+}
+
+// SetRollout replaces which machines are offered the release. Setting a 0% rollout without any machines
+// stops the release from being offered, e.g. when it turns out to be faulty.
+// An invalid rollout returns a *domain.InvalidReleaseError.
+func (s *Service) SetRollout(releaseID int, percent int, machineIDs []int) (domain.Release, error) {
+	rollout, err := domain.NewRollout(percent, machineIDs)
+	if err != nil {
+		return domain.Release{}, fmt.Errorf("SetRollout(): %w", err)
+	}
+
+	var rel domain.Release
+
+	err = s.uow.Do(func(r Repository) error {
+		var ok bool
+
+		rel, ok, err = r.GetRelease(releaseID)
+		if err != nil {
+			return fmt.Errorf("failed to get release with id %d: %w", releaseID, err)
+		}
+
+		if !ok {
+			return ErrReleaseDoesNotExist
+		}
+
+		for _, machineID := range machineIDs {
+			exists, err := r.MachineExists(machineID)
+			if err != nil {
+				return fmt.Errorf("failed to check if machine with id %d exists: %w", machineID, err)
+			}
+
+			if !exists {
+				return fmt.Errorf("%w: %d", ErrMachineDoesNotExist, machineID)
+			}
+		}
+
+		if err = r.SetRollout(releaseID, rollout); err != nil {
+			return fmt.Errorf("failed to set rollout: %w", err)
+		}
+
+		rel.Rollout = rollout
+		return nil
+	})
+
+	if err != nil {
+		return domain.Release{}, fmt.Errorf("SetRollout(): %w", err)
+	}
+
+	return rel, nil
+}
+
+// CheckForUpdate returns the release the machine should install, reporting false if it is up to date.
+// That is the newest release rolled out to the machine, unless the machine already runs it or a newer release,
+// or reported failing to install it.
+func (s *Service) CheckForUpdate(machineID int, currentVersion string) (domain.Release, bool, error) {
+	releases, err := s.r.GetReleases()
+	if err != nil {
+		return domain.Release{}, false, fmt.Errorf("CheckForUpdate(): failed to get releases: %w", err)
+	}
+
+	failedIDs, err := s.r.GetFailedReleaseIDs(machineID)
+	if err != nil {
+		return domain.Release{}, false, fmt.Errorf("CheckForUpdate(): failed to get failed releases: %w", err)
+	}
+
+	failed := make(map[int]bool, len(failedIDs))
+	for _, id := range failedIDs {
+		failed[id] = true
+	}
+
+	// Releases are newest first, so reaching the current version means there's nothing newer to install.
+	// Versions that were never uploaded, like those of hand-flashed builds, are always updated.
+	for _, rel := range releases {
+		if rel.Version == currentVersion {
+			return domain.Release{}, false, nil
+		}
+
+		if failed[rel.ID] || !rel.Rollout.Includes(rel.ID, machineID) {
+			continue
+		}
+
+		return rel, true, nil
+	}
+
+	return domain.Release{}, false, nil
+}
+
+// GetImage returns the release along with its image.
+func (s *Service) GetImage(ctx context.Context, releaseID int) (domain.Release, []byte, error) {
+	rel, ok, err := s.r.GetRelease(releaseID)
+	if err != nil {
+		return domain.Release{}, nil, fmt.Errorf("GetImage(): failed to get release with id %d: %w", releaseID, err)
+	}
+
+	if !ok {
+		return domain.Release{}, nil, fmt.Errorf("GetImage(): %w", ErrReleaseDoesNotExist)
+	}
+
+	image, err := s.bs.Get(ctx, rel.ImageKey())
+	if err != nil {
+		if errors.Is(err, blob.ErrBlobDoesNotExist) {
+			return domain.Release{}, nil, fmt.Errorf("GetImage(): %w: %s", ErrImageDoesNotExist, rel.ImageKey())
+		}
+
+		return domain.Release{}, nil, fmt.Errorf("GetImage(): failed to get image: %w", err)
+	}
+
+	return rel, image, nil
+}
+
+// ReportUpdate records how installing the release went on the machine. A too long detail returns
+// a *domain.InvalidReleaseError.
+func (s *Service) ReportUpdate(
+	machineID int,
+	releaseID int,
+	status domain.UpdateStatus,
+	detail string,
+) (domain.UpdateReport, error) {
+	report, err := domain.NewUpdateReport(releaseID, machineID, status, detail, time.Now())
+	if err != nil {
+		return domain.UpdateReport{}, fmt.Errorf("ReportUpdate(): %w", err)
+	}
+
+	_, ok, err := s.r.GetRelease(releaseID)
+	if err != nil {
+		return domain.UpdateReport{}, fmt.Errorf("ReportUpdate(): failed to get release with id %d: %w", releaseID, err)
+	}
+
+	if !ok {
+		return domain.UpdateReport{}, fmt.Errorf("ReportUpdate(): %w", ErrReleaseDoesNotExist)
+	}
+
+	if err = s.r.AddUpdateReport(report); err != nil {
+		return domain.UpdateReport{}, fmt.Errorf("ReportUpdate(): failed to add update report: %w", err)
+	}
+
+	return report, nil
+}
+
+// GetUpdateReports returns the latest reports of machines installing the release, newest first.
+func (s *Service) GetUpdateReports(releaseID int) ([]domain.UpdateReport, error) {
+	_, ok, err := s.r.GetRelease(releaseID)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpdateReports(): failed to get release with id %d: %w", releaseID, err)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("GetUpdateReports(): %w", ErrReleaseDoesNotExist)
+	}
+
+	reports, err := s.r.GetUpdateReports(releaseID, updateReportsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpdateReports(): failed to get update reports: %w", err)
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,294 @@
+package firmware_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/firmware"
+	"github.com/JosephJoshua/rvm/backend/internal/firmware/domain"
+)
+
+const (
+	userID       = "admin-1"
+	unknownID    = 9999
+	oldVersion   = "0.1.0"
+	newVersion   = "0.2.0"
+	devVersion   = "0.2.0-dev+local"
+	failedDetail = "checksum mismatch"
+)
+
+var image = []byte("firmware image")
+
+func newService(t *testing.T, dbHandle *db.DB) *firmware.Service {
+	t.Helper()
+
+	bs, err := blob.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	return firmware.NewService(
+		firmware.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) firmware.Repository {
+			return firmware.NewSQLRepository(q)
+		}),
+		bs,
+	)
+}
+
+func createRelease(t *testing.T, s *firmware.Service, version string) domain.Release {
+	t.Helper()
+
+	rel, err := s.CreateRelease(context.Background(), version, "", image, userID)
+	if err != nil {
+		t.Fatalf("failed to create release %s: %v", version, err)
+	}
+
+	return rel
+}
+
+func TestCreateRelease(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		version string
+		image   []byte
+		// wantInvalid expects a *domain.InvalidReleaseError.
+		wantInvalid bool
+		want        error
+	}{
+		{name: "new version", version: newVersion, image: image},
+		{name: "existing version", version: oldVersion, image: image, want: firmware.ErrVersionExists},
+		{name: "version unsafe in a blob key", version: "../0.2.0", image: image, wantInvalid: true},
+		{name: "empty image", version: newVersion, wantInvalid: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			dbtest.CreateUser(t, dbHandle, userID)
+			s := newService(t, dbHandle)
+
+			createRelease(t, s, oldVersion)
+
+			rel, err := s.CreateRelease(context.Background(), tc.version, "", tc.image, userID)
+
+			var invalid *domain.InvalidReleaseError
+			if tc.wantInvalid {
+				if !errors.As(err, &invalid) {
+					t.Errorf("got error %v, want an *InvalidReleaseError", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			if tc.want != nil {
+				return
+			}
+
+			if rel.Rollout.Percent != 0 || len(rel.Rollout.MachineIDs) != 0 {
+				t.Errorf("got rollout %+v, want none", rel.Rollout)
+			}
+
+			_, got, err := s.GetImage(context.Background(), rel.ID)
+			if err != nil {
+				t.Fatalf("failed to get image: %v", err)
+			}
+
+			if !bytes.Equal(got, tc.image) {
+				t.Errorf("got image %q, want %q", got, tc.image)
+			}
+		})
+	}
+}
+
+func TestSetRollout(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		percent int
+		// listMachine offers the release to the machine explicitly.
+		listMachine    bool
+		unknownRelease bool
+		unknownMachine bool
+		wantInvalid    bool
+		want           error
+	}{
+		{name: "percentage", percent: 25},
+		{name: "listed machine", listMachine: true},
+		{name: "stopped", percent: 0},
+		{name: "percentage out of range", percent: 101, wantInvalid: true},
+		{name: "unknown release", percent: 25, unknownRelease: true, want: firmware.ErrReleaseDoesNotExist},
+		{name: "unknown machine", unknownMachine: true, want: firmware.ErrMachineDoesNotExist},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			dbtest.CreateUser(t, dbHandle, userID)
+			s := newService(t, dbHandle)
+
+			machineID := dbtest.CreateMachine(t, dbHandle)
+			releaseID := createRelease(t, s, newVersion).ID
+
+			var machineIDs []int
+
+			switch {
+			case tc.listMachine:
+				machineIDs = []int{machineID}
+			case tc.unknownMachine:
+				machineIDs = []int{unknownID}
+			}
+
+			if tc.unknownRelease {
+				releaseID = unknownID
+			}
+
+			_, err := s.SetRollout(releaseID, tc.percent, machineIDs)
+
+			var invalid *domain.InvalidReleaseError
+			if tc.wantInvalid {
+				if !errors.As(err, &invalid) {
+					t.Errorf("got error %v, want an *InvalidReleaseError", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			releases, err := s.GetReleases()
+			if err != nil {
+				t.Fatalf("failed to get releases: %v", err)
+			}
+
+			// A rejected rollout leaves the release as it was created.
+			wantPercent, wantMachines := 0, 0
+			if tc.want == nil {
+				wantPercent, wantMachines = tc.percent, len(machineIDs)
+			}
+
+			if len(releases) != 1 {
+				t.Fatalf("got %d releases, want 1", len(releases))
+			}
+
+			got := releases[0].Rollout
+			if got.Percent != wantPercent || len(got.MachineIDs) != wantMachines {
+				t.Errorf("got rollout %+v, want %d%% and %d machines", got, wantPercent, wantMachines)
+			}
+		})
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	// rollout is how a release is offered; listed offers it to the machine under test explicitly.
+	type rollout struct {
+		percent int
+		listed  bool
+	}
+
+	everyone := rollout{percent: 100}
+	nobody := rollout{}
+	onlyMachine := rollout{listed: true}
+
+	for _, tc := range []struct {
+		name       string
+		oldRollout rollout
+		newRollout rollout
+		current    string
+		// newFailed reports that the machine failed to install the new release.
+		newFailed bool
+		// want is the version the machine should install, or empty if it is up to date.
+		want string
+	}{
+		{name: "newer release", oldRollout: everyone, newRollout: everyone, current: oldVersion, want: newVersion},
+		{name: "already on the newest release", oldRollout: everyone, newRollout: everyone, current: newVersion},
+		{name: "newer release not rolled out", oldRollout: everyone, newRollout: nobody, current: oldVersion},
+		{name: "newer release offered to the machine", newRollout: onlyMachine, current: oldVersion, want: newVersion},
+		{name: "older release not reinstalled", oldRollout: everyone, newRollout: nobody, current: newVersion},
+		{name: "unknown build", oldRollout: everyone, newRollout: everyone, current: devVersion, want: newVersion},
+		{
+			name:       "unknown build skips releases not rolled out",
+			oldRollout: everyone,
+			newRollout: nobody,
+			current:    devVersion,
+			want:       oldVersion,
+		},
+		{
+			name:       "failed release not offered again",
+			oldRollout: everyone,
+			newRollout: everyone,
+			current:    oldVersion,
+			newFailed:  true,
+		},
+		{
+			name:       "older release offered after a failed one",
+			oldRollout: everyone,
+			newRollout: everyone,
+			current:    devVersion,
+			newFailed:  true,
+			want:       oldVersion,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			dbtest.CreateUser(t, dbHandle, userID)
+			s := newService(t, dbHandle)
+
+			machineID := dbtest.CreateMachine(t, dbHandle)
+			otherMachineID := dbtest.CreateMachine(t, dbHandle)
+
+			oldRelease := createRelease(t, s, oldVersion)
+			newRelease := createRelease(t, s, newVersion)
+
+			for _, r := range []struct {
+				id      int
+				rollout rollout
+			}{
+				{id: oldRelease.ID, rollout: tc.oldRollout},
+				{id: newRelease.ID, rollout: tc.newRollout},
+			} {
+				var machineIDs []int
+				if r.rollout.listed {
+					machineIDs = []int{machineID}
+				}
+
+				if _, err := s.SetRollout(r.id, r.rollout.percent, machineIDs); err != nil {
+					t.Fatalf("failed to set rollout: %v", err)
+				}
+			}
+
+			if tc.newFailed {
+				if _, err := s.ReportUpdate(machineID, newRelease.ID, domain.UpdateStatusFailed, failedDetail); err != nil {
+					t.Fatalf("failed to report update: %v", err)
+				}
+			}
+
+			rel, ok, err := s.CheckForUpdate(machineID, tc.current)
+			if err != nil {
+				t.Fatalf("failed to check for update: %v", err)
+			}
+
+			got := ""
+			if ok {
+				got = rel.Version
+			}
+
+			if got != tc.want {
+				t.Errorf("got update %q, want %q", got, tc.want)
+			}
+
+			// Explicitly listed releases aren't offered to the other machines.
+			if tc.newRollout.listed {
+				if rel, ok, err := s.CheckForUpdate(otherMachineID, tc.current); err != nil || ok {
+					t.Errorf("got update %q for another machine (error %v), want none", rel.Version, err)
+				}
+			}
+		})
+	}
+}
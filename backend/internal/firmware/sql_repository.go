@@ -0,0 +1,296 @@
+package firmware
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/firmware/domain"
+)
+
+type release struct {
+	ReleaseID      int       `db:"firmware_release_id"`
+	Version        string    `db:"version"`
+	SHA256         string    `db:"sha256"`
+	SizeBytes      int       `db:"size_bytes"`
+	Notes          string    `db:"notes"`
+	RolloutPercent int       `db:"rollout_percent"`
+	CreatedBy      string    `db:"created_by"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (r release) toDomain(machineIDs []int) domain.Release {
+	return domain.Release{
+		ID:        r.ReleaseID,
+		Version:   r.Version,
+		SHA256:    r.SHA256,
+		SizeBytes: r.SizeBytes,
+		Notes:     r.Notes,
+		Rollout: domain.Rollout{
+			Percent:    r.RolloutPercent,
+			MachineIDs: machineIDs,
+		},
+		CreatedBy: r.CreatedBy,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+type rolloutMachine struct {
+	ReleaseID int `db:"firmware_release_id"`
+	MachineID int `db:"machine_id"`
+}
+
+type updateReport struct {
+	ReleaseID  int       `db:"firmware_release_id"`
+	MachineID  int       `db:"machine_id"`
+	Status     string    `db:"status"`
+	Detail     string    `db:"detail"`
+	ReportedAt time.Time `db:"reported_at"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (fr *SQLRepository) GetReleases() ([]domain.Release, error) {
+	var raw []release
+	if err := fr.db.Select(&raw, `
+		SELECT
+			firmware_release_id, version, sha256, size_bytes, notes, rollout_percent, created_by, created_at
+		FROM
+			firmware_releases
+		ORDER BY
+			firmware_release_id DESC
+	`); err != nil {
+		return nil, fmt.Errorf("GetReleases(): failed to execute query: %w", err)
+	}
+
+	var machines []rolloutMachine
+	if err := fr.db.Select(&machines, `
+		SELECT
+			firmware_release_id, machine_id
+		FROM
+			firmware_rollout_machines
+		ORDER BY
+			machine_id
+	`); err != nil {
+		return nil, fmt.Errorf("GetReleases(): failed to execute rollout machines query: %w", err)
+	}
+
+	machineIDs := make(map[int][]int)
+	for _, m := range machines {
+		machineIDs[m.ReleaseID] = append(machineIDs[m.ReleaseID], m.MachineID)
+	}
+
+	releases := make([]domain.Release, 0, len(raw))
+	for _, r := range raw {
+		releases = append(releases, r.toDomain(machineIDs[r.ReleaseID]))
+	}
+
+	return releases, nil
+}
+
+func (fr *SQLRepository) GetRelease(id int) (domain.Release, bool, error) {
+	var raw release
+	if err := fr.db.Get(&raw, `
+		SELECT
+			firmware_release_id, version, sha256, size_bytes, notes, rollout_percent, created_by, created_at
+		FROM
+			firmware_releases
+		WHERE
+			firmware_release_id = ?
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Release{}, false, nil
+		}
+
+		return domain.Release{}, false, fmt.Errorf("GetRelease(): failed to execute query: %w", err)
+	}
+
+	machineIDs, err := fr.getRolloutMachineIDs(raw.ReleaseID)
+	if err != nil {
+		return domain.Release{}, false, fmt.Errorf("GetRelease(): %w", err)
+	}
+
+	return raw.toDomain(machineIDs), true, nil
+}
+
+func (fr *SQLRepository) GetReleaseByVersion(version string) (domain.Release, bool, error) {
+	var raw release
+	if err := fr.db.Get(&raw, `
+		SELECT
+			firmware_release_id, version, sha256, size_bytes, notes, rollout_percent, created_by, created_at
+		FROM
+			firmware_releases
+		WHERE
+			version = ?
+	`, version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Release{}, false, nil
+		}
+
+		return domain.Release{}, false, fmt.Errorf("GetReleaseByVersion(): failed to execute query: %w", err)
+	}
+
+	machineIDs, err := fr.getRolloutMachineIDs(raw.ReleaseID)
+	if err != nil {
+		return domain.Release{}, false, fmt.Errorf("GetReleaseByVersion(): %w", err)
+	}
+
+	return raw.toDomain(machineIDs), true, nil
+}
+
+func (fr *SQLRepository) getRolloutMachineIDs(releaseID int) ([]int, error) {
+	var machineIDs []int
+	if err := fr.db.Select(&machineIDs, `
+		SELECT
+			machine_id
+		FROM
+			firmware_rollout_machines
+		WHERE
+			firmware_release_id = ?
+		ORDER BY
+			machine_id
+	`, releaseID); err != nil {
+		return nil, fmt.Errorf("failed to execute rollout machines query: %w", err)
+	}
+
+	return machineIDs, nil
+}
+
+func (fr *SQLRepository) CreateRelease(r domain.Release) (int, error) {
+	var id int
+	if err := fr.db.Get(&id, `
+		INSERT INTO
+			firmware_releases (version, sha256, size_bytes, notes, rollout_percent, created_by, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?)
+		RETURNING
+			firmware_release_id
+	`, r.Version, r.SHA256, r.SizeBytes, r.Notes, r.Rollout.Percent, r.CreatedBy, r.CreatedAt); err != nil {
+		return 0, fmt.Errorf("CreateRelease(): failed to execute query: %w", err)
+	}
+
+	return id, nil
+}
+
+func (fr *SQLRepository) SetRollout(releaseID int, rollout domain.Rollout) error {
+	if _, err := fr.db.Exec(`
+		UPDATE
+			firmware_releases
+		SET
+			rollout_percent = ?
+		WHERE
+			firmware_release_id = ?
+	`, rollout.Percent, releaseID); err != nil {
+		return fmt.Errorf("SetRollout(): failed to execute update query: %w", err)
+	}
+
+	if _, err := fr.db.Exec(`
+		DELETE FROM
+			firmware_rollout_machines
+		WHERE
+			firmware_release_id = ?
+	`, releaseID); err != nil {
+		return fmt.Errorf("SetRollout(): failed to execute delete query: %w", err)
+	}
+
+	for _, machineID := range rollout.MachineIDs {
+		if _, err := fr.db.Exec(`
+			INSERT INTO
+				firmware_rollout_machines (firmware_release_id, machine_id)
+			VALUES
+				(?, ?)
+		`, releaseID, machineID); err != nil {
+			return fmt.Errorf("SetRollout(): failed to execute insert query: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (fr *SQLRepository) MachineExists(machineID int) (bool, error) {
+	var count int
+	if err := fr.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			machines
+		WHERE
+			machine_id = ?
+	`, machineID); err != nil {
+		return false, fmt.Errorf("MachineExists(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (fr *SQLRepository) GetFailedReleaseIDs(machineID int) ([]int, error) {
+	var ids []int
+	if err := fr.db.Select(&ids, `
+		SELECT DISTINCT
+			firmware_release_id
+		FROM
+			firmware_update_reports
+		WHERE
+			machine_id = ? AND status = ?
+	`, machineID, domain.UpdateStatusFailed); err != nil {
+		return nil, fmt.Errorf("GetFailedReleaseIDs(): failed to execute query: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (fr *SQLRepository) AddUpdateReport(r domain.UpdateReport) error {
+	if _, err := fr.db.Exec(`
+		INSERT INTO
+			firmware_update_reports (firmware_release_id, machine_id, status, detail, reported_at)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`, r.ReleaseID, r.MachineID, r.Status, r.Detail, r.ReportedAt); err != nil {
+		return fmt.Errorf("AddUpdateReport(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (fr *SQLRepository) GetUpdateReports(releaseID int, limit int) ([]domain.UpdateReport, error) {
+	var raw []updateReport
+	if err := fr.db.Select(&raw, `
+		SELECT
+			firmware_release_id, machine_id, status, detail, reported_at
+		FROM
+			firmware_update_reports
+		WHERE
+			firmware_release_id = ?
+		ORDER BY
+			reported_at DESC, firmware_update_report_id DESC
+		LIMIT ?
+	`, releaseID, limit); err != nil {
+		return nil, fmt.Errorf("GetUpdateReports(): failed to execute query: %w", err)
+	}
+
+	reports := make([]domain.UpdateReport, 0, len(raw))
+	for _, r := range raw {
+		status, err := domain.NewUpdateStatus(r.Status)
+		if err != nil {
+			return nil, fmt.Errorf("GetUpdateReports(): %w", err)
+		}
+
+		reports = append(reports, domain.UpdateReport{
+			ReleaseID:  r.ReleaseID,
+			MachineID:  r.MachineID,
+			Status:     status,
+			Detail:     r.Detail,
+			ReportedAt: r.ReportedAt,
+		})
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,123 @@
+package httputils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/httplog/v2"
+)
+
+// maxJSONBodySize is the largest JSON request body accepted.
+const maxJSONBodySize = 1 << 20
+
+// JSONFormMiddleware lets handlers read the fields of a JSON object body through r.FormValue, alongside
+// the query parameters, so that every endpoint taking form values takes JSON as well.
+// Arrays become comma-separated values and nested objects are left out; the body itself is left intact
+// for handlers that decode it whole.
+func JSONFormMiddleware(next http.Handler) http.Handler {
+	return HandlerFunc(func(w ResponseWriter, r *http.Request) {
+		oplog := httplog.LogEntry(r.Context())
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" || r.Body == nil {
+			next.ServeHTTP(w.ResponseWriter, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w.ResponseWriter, r.Body, maxJSONBodySize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				oplog.Error("request body is too large")
+				w.TryWriteProblem(&oplog, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "request body is too large")
+
+				return
+			}
+
+			oplog.Error("failed to read request body", slog.String("error", err.Error()))
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, CodeInvalidRequest, "failed to read request body")
+
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			next.ServeHTTP(w.ResponseWriter, r)
+			return
+		}
+
+		fields, err := jsonFormValues(body)
+		if err != nil {
+			oplog.Error("invalid json body", slog.String("error", err.Error()))
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, CodeInvalidRequest, "request body must be a JSON object")
+
+			return
+		}
+
+		form := r.URL.Query()
+		for k, vs := range fields {
+			form[k] = append(form[k], vs...)
+		}
+
+		r.Form = form
+		r.PostForm = fields
+
+		next.ServeHTTP(w.ResponseWriter, r)
+	})
+}
+
+func jsonFormValues(body []byte) (url.Values, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("jsonFormValues(): failed to decode body: %w", err)
+	}
+
+	values := make(url.Values, len(obj))
+
+	for k, v := range obj {
+		if s, ok := jsonFormValue(v); ok {
+			values.Set(k, s)
+		}
+	}
+
+	return values, nil
+}
+
+func jsonFormValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case []any:
+		parts := make([]string, 0, len(v))
+
+		for _, e := range v {
+			s, ok := jsonFormValue(e)
+			if !ok {
+				return "", false
+			}
+
+			parts = append(parts, s)
+		}
+
+		return strings.Join(parts, ","), true
+	default:
+		return "", false
+	}
+}
@@ -1,23 +1,26 @@
 package httputils
 
 import (
+	"encoding/json"
 	"log/slog"
 	"net/http"
 )
 
 func HandlerFunc(fn func(ResponseWriter, *http.Request)) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		nw := newResponseWriter(w)
+		nw := newResponseWriter(w, r)
 		fn(nw, r)
 	})
 }
 
 type ResponseWriter struct {
 	http.ResponseWriter
+	// plainText is set for clients that prefer text/plain to JSON, like older camera module firmware.
+	plainText bool
 }
 
-func newResponseWriter(w http.ResponseWriter) ResponseWriter {
-	return ResponseWriter{w}
+func newResponseWriter(w http.ResponseWriter, r *http.Request) ResponseWriter {
+	return ResponseWriter{ResponseWriter: w, plainText: PrefersPlainText(r)}
 }
 
 func (w *ResponseWriter) TryWrite(oplog *slog.Logger, toWrite []byte) (bool, int) {
@@ -29,3 +32,34 @@ func (w *ResponseWriter) TryWrite(oplog *slog.Logger, toWrite []byte) (bool, int
 
 	return true, 0
 }
+
+// TryWriteJSON writes v as JSON with the given status.
+func (w *ResponseWriter) TryWriteJSON(oplog *slog.Logger, status int, v any) bool {
+	body, err := json.Marshal(v)
+	if err != nil {
+		oplog.Error("failed to marshal response", slog.String("error", err.Error()))
+		w.TryWriteInternalError(oplog)
+
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	ok, _ := w.TryWrite(oplog, body)
+	return ok
+}
+
+// TryWriteNegotiated writes v as JSON, or text as text/plain to clients that prefer it.
+// It is meant for responses that older clients read as a bare value, like a transaction code.
+func (w *ResponseWriter) TryWriteNegotiated(oplog *slog.Logger, status int, v any, text string) bool {
+	if !w.plainText {
+		return w.TryWriteJSON(oplog, status, v)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+
+	ok, _ := w.TryWrite(oplog, []byte(text))
+	return ok
+}
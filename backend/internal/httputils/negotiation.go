@@ -0,0 +1,45 @@
+package httputils
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrefersPlainText reports whether the request's Accept header ranks text/plain above JSON.
+// Requests without one, or that accept both equally like browsers do, get JSON.
+func PrefersPlainText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	var jsonQ, textQ float64
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "application/json", "application/problem+json", "application/*":
+			jsonQ = max(jsonQ, q)
+		case "text/plain", "text/*":
+			textQ = max(textQ, q)
+		case "*/*":
+			jsonQ = max(jsonQ, q)
+			textQ = max(textQ, q)
+		}
+	}
+
+	return textQ > jsonQ
+}
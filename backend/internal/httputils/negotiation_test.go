@@ -0,0 +1,45 @@
+package httputils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+)
+
+func TestPrefersPlainText(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{name: "no Accept header", accept: "", want: false},
+		{name: "plain text", accept: "text/plain", want: true},
+		{name: "json", accept: "application/json", want: false},
+		{name: "problem json", accept: "application/problem+json", want: false},
+		{name: "anything", accept: "*/*", want: false},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: false},
+		{name: "plain text over anything", accept: "text/plain, */*;q=0.1", want: true},
+		{name: "json ranked higher", accept: "text/plain;q=0.5, application/json", want: false},
+		{name: "plain text ranked higher", accept: "application/json;q=0.5, text/plain", want: true},
+		{name: "equal ranks", accept: "text/plain, application/json", want: false},
+		{name: "any text", accept: "text/*", want: true},
+		{name: "any application", accept: "application/*, text/plain;q=0.9", want: false},
+		{name: "parameters", accept: "text/plain; charset=utf-8", want: true},
+		{name: "json refused", accept: "application/json;q=0, text/plain;q=0.1", want: true},
+		{name: "invalid quality skipped", accept: "text/plain;q=abc, application/json;q=0.1", want: false},
+		{name: "unknown media type ignored", accept: "image/png, text/plain", want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			if got := httputils.PrefersPlainText(r); got != tc.want {
+				t.Errorf("Accept %q: got %t, want %t", tc.accept, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package httputils
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes shared by every endpoint. Packages define codes for their own errors next to their handlers.
+// Codes are part of the API: clients match on them, so they must never change once released.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeRequestTooLarge  = "request_too_large"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object. Code is an extension member identifying the problem,
+// since the type is always about:blank.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+func NewProblem(status int, code string, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// TryWriteProblem writes the problem as application/problem+json, or only its detail as text/plain
+// to clients that prefer it.
+func (w *ResponseWriter) TryWriteProblem(oplog *slog.Logger, status int, code string, detail string) bool {
+	if w.plainText {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+
+		ok, _ := w.TryWrite(oplog, []byte(detail))
+		return ok
+	}
+
+	body, err := json.Marshal(NewProblem(status, code, detail))
+	if err != nil {
+		oplog.Error("failed to marshal problem", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+
+	ok, _ := w.TryWrite(oplog, body)
+	return ok
+}
+
+// TryWriteInternalError writes a problem that doesn't reveal what went wrong; the cause is only logged.
+func (w *ResponseWriter) TryWriteInternalError(oplog *slog.Logger) bool {
+	return w.TryWriteProblem(oplog, http.StatusInternalServerError, CodeInternal, "something went wrong")
+}
+
+// NotFound is the router's handler for routes that don't exist.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+	nw := newResponseWriter(w, r)
+	nw.TryWriteProblem(&oplog, http.StatusNotFound, CodeNotFound, "route not found")
+}
+
+// MethodNotAllowed is the router's handler for routes that exist but not with the request's method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+	nw := newResponseWriter(w, r)
+	nw.TryWriteProblem(&oplog, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
+
+// NewRouter returns a router that responds with problems to routes and methods it doesn't have.
+// Handlers mounted on another router need their own, since chi can only pass these down to bare routers.
+func NewRouter() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.NotFound(NotFound)
+	r.MethodNotAllowed(MethodNotAllowed)
+
+	return r
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const gtinLength = 14
+
+var ErrInvalidGTIN = errors.New("invalid GTIN")
+
+// GTIN is a barcode number (EAN-8, UPC-A, EAN-13 or GTIN-14), always stored as 14 digits
+// so that the same product scanned as UPC-A or EAN-13 resolves to the same value.
+type GTIN string
+
+// NewGTIN parses a GTIN of 8, 12, 13 or 14 digits and validates its check digit.
+func NewGTIN(value string) (GTIN, error) {
+	value = strings.TrimSpace(value)
+
+	switch len(value) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("%w %q: must be 8, 12, 13 or 14 digits long", ErrInvalidGTIN, value)
+	}
+
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("%w %q: must only contain digits", ErrInvalidGTIN, value)
+		}
+	}
+
+	if checkDigit(value[:len(value)-1]) != value[len(value)-1] {
+		return "", fmt.Errorf("%w %q: wrong check digit", ErrInvalidGTIN, value)
+	}
+
+	return GTIN(strings.Repeat("0", gtinLength-len(value)) + value), nil
+}
+
+func (g GTIN) String() string {
+	return string(g)
+}
+
+// checkDigit computes the GS1 check digit: digits are weighted 3 and 1 alternately,
+// starting with 3 at the rightmost one.
+func checkDigit(digits string) byte {
+	sum := 0
+	weight := 3
+
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight = 4 - weight
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,51 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/item/domain"
+)
+
+func TestNewGTIN(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		value   string
+		want    domain.GTIN
+		wantErr bool
+	}{
+		{name: "EAN-8", value: "96385074", want: "00000096385074"},
+		{name: "UPC-A", value: "036000291452", want: "00036000291452"},
+		{name: "EAN-13", value: "4006381333931", want: "04006381333931"},
+		{name: "UPC-A written as EAN-13", value: "0036000291452", want: "00036000291452"},
+		{name: "GTIN-14", value: "10012345678902", want: "10012345678902"},
+		{name: "surrounding whitespace", value: " 4006381333931\n", want: "04006381333931"},
+		{name: "wrong check digit", value: "4006381333932", wantErr: true},
+		{name: "UPC-A with wrong check digit", value: "036000291450", wantErr: true},
+		{name: "GTIN-14 with wrong check digit", value: "10012345678903", wantErr: true},
+		{name: "letters", value: "40063813339A1", wantErr: true},
+		{name: "too short", value: "1234567", wantErr: true},
+		{name: "unsupported length", value: "12345678901", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := domain.NewGTIN(tc.value)
+
+			if tc.wantErr {
+				if !errors.Is(err, domain.ErrInvalidGTIN) {
+					t.Errorf("got %q and error %v, want %v", got, err, domain.ErrInvalidGTIN)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tc.value, err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
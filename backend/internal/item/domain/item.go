@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const maxNameLength = 255
+
+// InvalidItemError is returned when an item's fields are out of range. Reason is meant for whoever entered them.
+type InvalidItemError struct {
+	Reason string
+}
+
+func (e *InvalidItemError) Error() string {
+	return "invalid item: " + e.Reason
+}
+
+type Item struct {
+	ID       int
+	Name     string
+	Material Material
+	// VolumeML is the container's volume in millilitres, if it has one.
+	VolumeML *int
+	Points   int
+	// Active is false for archived items, which can no longer be added to transactions.
+	Active bool
+	// Barcodes are the GTINs that resolve to this item when scanned.
+	Barcodes []GTIN
+}
+
+// NewItem creates a new item, returning an *InvalidItemError if any field is out of range.
+func NewItem(id int, name string, material Material, volumeML *int, points int, active bool) (Item, error) {
+	if name == "" || len(name) > maxNameLength {
+		return Item{}, &InvalidItemError{
+			Reason: fmt.Sprintf("name must be between 1 and %d characters long", maxNameLength),
+		}
+	}
+
+	if volumeML != nil && *volumeML <= 0 {
+		return Item{}, &InvalidItemError{Reason: "volume_ml must be positive"}
+	}
+
+	if points < 0 {
+		return Item{}, &InvalidItemError{Reason: "points can't be negative"}
+	}
+
+	return Item{
+		ID:       id,
+		Name:     name,
+		Material: material,
+		VolumeML: volumeML,
+		Points:   points,
+		Active:   active,
+	}, nil
+}
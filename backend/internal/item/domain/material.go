@@ -0,0 +1,26 @@
+package domain
+
+import "fmt"
+
+type Material string
+
+const (
+	MaterialPET       Material = "pet"
+	MaterialHDPE      Material = "hdpe"
+	MaterialAluminium Material = "aluminium"
+	MaterialGlass     Material = "glass"
+	MaterialOther     Material = "other"
+)
+
+func NewMaterial(value string) (Material, error) {
+	switch m := Material(value); m {
+	case MaterialPET, MaterialHDPE, MaterialAluminium, MaterialGlass, MaterialOther:
+		return m, nil
+	}
+
+	return "", fmt.Errorf("unknown material %q", value)
+}
+
+func (m Material) String() string {
+	return string(m)
+}
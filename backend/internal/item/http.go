@@ -0,0 +1,334 @@
+package item
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/item/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeItemDoesNotExist    = "item_does_not_exist"
+	codeBarcodeTaken        = "barcode_taken"
+	codeBarcodeDoesNotExist = "barcode_does_not_exist"
+	codeInvalidBarcode      = "invalid_barcode"
+)
+
+type itemResponse struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Material string   `json:"material"`
+	VolumeML *int     `json:"volume_ml"`
+	Points   int      `json:"points"`
+	Active   bool     `json:"active"`
+	Barcodes []string `json:"barcodes"`
+}
+
+// itemForm holds the fields shared by creating and updating an item.
+type itemForm struct {
+	name     string
+	material domain.Material
+	volumeML *int
+	points   int
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new item HTTP handler for operators.
+// It expects auth.LoggedInMiddleware and auth.AdminOnlyMiddleware to run before it.
+//   - GET /items - returns the active items as JSON; include_archived=true returns archived ones too.
+//   - POST /items - creates an item and returns it as JSON.
+//     name, material and points are required form values; volume_ml is optional.
+//   - PUT /items/{itemID} - updates the item with the same form values as POST /items and returns it as JSON.
+//   - POST /items/{itemID}/archive - archives the item so that it can no longer be added to transactions.
+//   - POST /items/{itemID}/barcodes - adds a GTIN barcode to the item and returns the item as JSON.
+//     barcode is a form value.
+//   - DELETE /items/{itemID}/barcodes/{barcode} - removes a barcode from the item.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getItems))
+	r.Post("/", httputils.HandlerFunc(handler.createItem))
+	r.Put("/{itemID}", httputils.HandlerFunc(handler.updateItem))
+	r.Post("/{itemID}/archive", httputils.HandlerFunc(handler.archiveItem))
+	r.Post("/{itemID}/barcodes", httputils.HandlerFunc(handler.addBarcode))
+	r.Delete("/{itemID}/barcodes/{barcode}", httputils.HandlerFunc(handler.removeBarcode))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getItems(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	includeArchived := r.FormValue("include_archived") == "true"
+
+	items, err := h.s.GetItems(includeArchived)
+	if err != nil {
+		oplog.Error("failed to get items", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]itemResponse, 0, len(items))
+	for _, it := range items {
+		res = append(res, toItemResponse(it))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) createItem(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	form, ok := parseItemForm(w, r, &oplog)
+	if !ok {
+		return
+	}
+
+	it, err := h.s.CreateItem(form.name, form.material, form.volumeML, form.points)
+	if err != nil {
+		var invalidErr *domain.InvalidItemError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid item", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to create item", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, toItemResponse(it))
+}
+
+func (h *HTTPHandler) updateItem(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		oplog.Error("failed to convert item id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+		return
+	}
+
+	form, ok := parseItemForm(w, r, &oplog)
+	if !ok {
+		return
+	}
+
+	it, err := h.s.UpdateItem(itemID, form.name, form.material, form.volumeML, form.points)
+	if err != nil {
+		if errors.Is(err, ErrItemDoesNotExist) {
+			oplog.Error("item not found", slog.Int("item_id", itemID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+			return
+		}
+
+		var invalidErr *domain.InvalidItemError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid item", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to update item", logging.ErrAttr(err), slog.Int("item_id", itemID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toItemResponse(it))
+}
+
+func (h *HTTPHandler) archiveItem(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		oplog.Error("failed to convert item id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+		return
+	}
+
+	if err = h.s.ArchiveItem(itemID); err != nil {
+		if errors.Is(err, ErrItemDoesNotExist) {
+			oplog.Error("item not found", slog.Int("item_id", itemID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+			return
+		}
+
+		oplog.Error("failed to archive item", logging.ErrAttr(err), slog.Int("item_id", itemID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) addBarcode(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		oplog.Error("failed to convert item id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+		return
+	}
+
+	barcode, err := domain.NewGTIN(r.FormValue("barcode"))
+	if err != nil {
+		oplog.Error("invalid barcode", logging.ErrAttr(err))
+
+		w.TryWriteProblem(
+			&oplog,
+			http.StatusBadRequest,
+			codeInvalidBarcode,
+			"barcode has to be a valid GTIN-8, GTIN-12, GTIN-13 or GTIN-14",
+		)
+
+		return
+	}
+
+	it, err := h.s.AddBarcode(itemID, barcode)
+	if err != nil {
+		if errors.Is(err, ErrItemDoesNotExist) {
+			oplog.Error("item not found", slog.Int("item_id", itemID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrBarcodeTaken) {
+			oplog.Error("barcode belongs to another item", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeBarcodeTaken, "barcode belongs to another item")
+
+			return
+		}
+
+		oplog.Error("failed to add barcode", logging.ErrAttr(err), slog.Int("item_id", itemID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toItemResponse(it))
+}
+
+func (h *HTTPHandler) removeBarcode(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		oplog.Error("failed to convert item id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeItemDoesNotExist, "item not found")
+
+		return
+	}
+
+	barcode, err := domain.NewGTIN(chi.URLParam(r, "barcode"))
+	if err != nil {
+		oplog.Error("invalid barcode", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeBarcodeDoesNotExist, "barcode not found")
+
+		return
+	}
+
+	if err = h.s.RemoveBarcode(itemID, barcode); err != nil {
+		if errors.Is(err, ErrBarcodeDoesNotExist) {
+			oplog.Error("item does not have barcode", slog.Int("item_id", itemID), slog.String("barcode", barcode.String()))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeBarcodeDoesNotExist, "item does not have barcode")
+
+			return
+		}
+
+		oplog.Error("failed to remove barcode", logging.ErrAttr(err), slog.Int("item_id", itemID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseItemForm reads the item's form values, writing a 400 response and returning false if any is malformed.
+func parseItemForm(w httputils.ResponseWriter, r *http.Request, oplog *slog.Logger) (itemForm, bool) {
+	badRequest := func(msg string) (itemForm, bool) {
+		oplog.Error("invalid item form", slog.String("reason", msg))
+
+		w.TryWriteProblem(oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, msg)
+
+		return itemForm{}, false
+	}
+
+	material, err := domain.NewMaterial(r.FormValue("material"))
+	if err != nil {
+		return badRequest("material has to be one of pet, hdpe, aluminium, glass or other")
+	}
+
+	points, err := strconv.Atoi(r.FormValue("points"))
+	if err != nil {
+		return badRequest("points has to be an integer")
+	}
+
+	var volumeML *int
+
+	if v := r.FormValue("volume_ml"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return badRequest("volume_ml has to be an integer")
+		}
+
+		volumeML = &parsed
+	}
+
+	return itemForm{
+		name:     r.FormValue("name"),
+		material: material,
+		volumeML: volumeML,
+		points:   points,
+	}, true
+}
+
+func toItemResponse(it domain.Item) itemResponse {
+	barcodes := make([]string, 0, len(it.Barcodes))
+	for _, b := range it.Barcodes {
+		barcodes = append(barcodes, b.String())
+	}
+
+	return itemResponse{
+		ID:       it.ID,
+		Name:     it.Name,
+		Material: it.Material.String(),
+		VolumeML: it.VolumeML,
+		Points:   it.Points,
+		Active:   it.Active,
+		Barcodes: barcodes,
+	}
+}
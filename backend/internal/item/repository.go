@@ -0,0 +1,26 @@
+package item
+
+import (
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/item/domain"
+)
+
+type Repository interface {
+	GetItems(includeArchived bool) ([]domain.Item, error)
+	// GetItem returns ErrItemDoesNotExist if there is no item with the given id, archived or not.
+	GetItem(id int) (*domain.Item, error)
+	CreateItem(item domain.Item) (int, error)
+	// UpdateItem overwrites every field of the item but its active flag, reporting whether it exists.
+	UpdateItem(item domain.Item) (bool, error)
+	// SetItemActive reports whether the item exists.
+	SetItemActive(id int, active bool) (bool, error)
+	GetBarcodes(itemID int) ([]domain.GTIN, error)
+	// GetAllBarcodes returns every item's barcodes by item id.
+	GetAllBarcodes() (map[int][]domain.GTIN, error)
+	// AddBarcode returns ErrBarcodeTaken if the barcode already belongs to another item.
+	// Adding a barcode the item already has does nothing.
+	AddBarcode(itemID int, barcode domain.GTIN, createdAt time.Time) error
+	// RemoveBarcode reports whether the item had the barcode.
+	RemoveBarcode(itemID int, barcode domain.GTIN) (bool, error)
+}
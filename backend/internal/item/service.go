@@ -0,0 +1,152 @@
+package item
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/item/domain"
+)
+
+var (
+	ErrItemDoesNotExist    = fmt.Errorf("item does not exist")
+	ErrBarcodeTaken        = fmt.Errorf("barcode belongs to another item")
+	ErrBarcodeDoesNotExist = fmt.Errorf("item does not have barcode")
+)
+
+type Service struct {
+	r Repository
+}
+
+func NewService(r Repository) *Service {
+	return &Service{r: r}
+}
+
+func (s *Service) GetItems(includeArchived bool) ([]domain.Item, error) {
+	items, err := s.r.GetItems(includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("GetItems(): failed to get items: %w", err)
+	}
+
+	barcodes, err := s.r.GetAllBarcodes()
+	if err != nil {
+		return nil, fmt.Errorf("GetItems(): failed to get barcodes: %w", err)
+	}
+
+	for i := range items {
+		items[i].Barcodes = barcodes[items[i].ID]
+	}
+
+	return items, nil
+}
+
+// CreateItem adds an active item to the catalog. Invalid fields return a *domain.InvalidItemError.
+func (s *Service) CreateItem(
+	name string,
+	material domain.Material,
+	volumeML *int,
+	points int,
+) (domain.Item, error) {
+	item, err := domain.NewItem(0, name, material, volumeML, points, true)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("CreateItem(): %w", err)
+	}
+
+	item.ID, err = s.r.CreateItem(item)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("CreateItem(): failed to create item: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateItem changes the item's details. New points only apply to transactions that haven't been claimed yet;
+// claimed ones keep the points they earned.
+func (s *Service) UpdateItem(
+	id int,
+	name string,
+	material domain.Material,
+	volumeML *int,
+	points int,
+) (domain.Item, error) {
+	current, err := s.getItem(id)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("UpdateItem(): %w", err)
+	}
+
+	item, err := domain.NewItem(id, name, material, volumeML, points, current.Active)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("UpdateItem(): %w", err)
+	}
+
+	ok, err := s.r.UpdateItem(item)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("UpdateItem(): failed to update item: %w", err)
+	}
+
+	if !ok {
+		return domain.Item{}, fmt.Errorf("UpdateItem(): %w with id %d", ErrItemDoesNotExist, id)
+	}
+
+	item.Barcodes = current.Barcodes
+	return item, nil
+}
+
+// ArchiveItem stops the item from being added to new transactions. Past transactions keep referring to it.
+func (s *Service) ArchiveItem(id int) error {
+	ok, err := s.r.SetItemActive(id, false)
+	if err != nil {
+		return fmt.Errorf("ArchiveItem(): failed to archive item: %w", err)
+	}
+
+	if !ok {
+		return fmt.Errorf("ArchiveItem(): %w with id %d", ErrItemDoesNotExist, id)
+	}
+
+	return nil
+}
+
+// AddBarcode lets the item be added to transactions by scanning the barcode and returns the updated item.
+func (s *Service) AddBarcode(itemID int, barcode domain.GTIN) (domain.Item, error) {
+	if _, err := s.r.GetItem(itemID); err != nil {
+		return domain.Item{}, fmt.Errorf("AddBarcode(): failed to get item with id %d: %w", itemID, err)
+	}
+
+	if err := s.r.AddBarcode(itemID, barcode, time.Now()); err != nil {
+		return domain.Item{}, fmt.Errorf("AddBarcode(): failed to add barcode %s: %w", barcode, err)
+	}
+
+	item, err := s.getItem(itemID)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("AddBarcode(): %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *Service) RemoveBarcode(itemID int, barcode domain.GTIN) error {
+	ok, err := s.r.RemoveBarcode(itemID, barcode)
+	if err != nil {
+		return fmt.Errorf("RemoveBarcode(): failed to remove barcode %s: %w", barcode, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("RemoveBarcode(): %w %s", ErrBarcodeDoesNotExist, barcode)
+	}
+
+	return nil
+}
+
+// getItem returns the item along with its barcodes.
+func (s *Service) getItem(id int) (domain.Item, error) {
+	item, err := s.r.GetItem(id)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("failed to get item with id %d: %w", id, err)
+	}
+
+	item.Barcodes, err = s.r.GetBarcodes(id)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("failed to get barcodes of item with id %d: %w", id, err)
+	}
+
+	return *item, nil
+}
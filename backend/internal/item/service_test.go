@@ -0,0 +1,102 @@
+package item_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/item"
+	"github.com/JosephJoshua/rvm/backend/internal/item/domain"
+)
+
+func mustGTIN(t *testing.T, value string) domain.GTIN {
+	t.Helper()
+
+	g, err := domain.NewGTIN(value)
+	if err != nil {
+		t.Fatalf("failed to parse GTIN %q: %v", value, err)
+	}
+
+	return g
+}
+
+func TestAddBarcode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// toCan adds the barcode to the can rather than the bottle, which already has 4006381333931.
+		toCan   bool
+		unknown bool
+		barcode string
+		want    error
+	}{
+		{name: "new barcode", toCan: true, barcode: "036000291452"},
+		{name: "barcode the item already has", barcode: "4006381333931"},
+		{name: "barcode of another item", toCan: true, barcode: "4006381333931", want: item.ErrBarcodeTaken},
+		{
+			name:    "another item's barcode in another format",
+			toCan:   true,
+			barcode: "04006381333931",
+			want:    item.ErrBarcodeTaken,
+		},
+		{name: "unknown item", unknown: true, barcode: "036000291452", want: item.ErrItemDoesNotExist},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			s := item.NewService(item.NewSQLRepository(dbHandle))
+
+			bottleID := dbtest.CreateItem(t, dbHandle, 10)
+			canID := dbtest.CreateItem(t, dbHandle, 5)
+
+			if _, err := s.AddBarcode(bottleID, mustGTIN(t, "4006381333931")); err != nil {
+				t.Fatalf("failed to add the bottle's barcode: %v", err)
+			}
+
+			itemID := bottleID
+			if tc.toCan {
+				itemID = canID
+			}
+
+			if tc.unknown {
+				itemID = canID + 1
+			}
+
+			barcode := mustGTIN(t, tc.barcode)
+
+			got, err := s.AddBarcode(itemID, barcode)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			if tc.want == nil && !slices.Contains(got.Barcodes, barcode) {
+				t.Errorf("got barcodes %v, want them to include %s", got.Barcodes, barcode)
+			}
+		})
+	}
+}
+
+func TestRemoveBarcode(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := item.NewService(item.NewSQLRepository(dbHandle))
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 5)
+	barcode := mustGTIN(t, "4006381333931")
+
+	if _, err := s.AddBarcode(bottleID, barcode); err != nil {
+		t.Fatalf("failed to add barcode: %v", err)
+	}
+
+	if err := s.RemoveBarcode(canID, barcode); !errors.Is(err, item.ErrBarcodeDoesNotExist) {
+		t.Errorf("removing from another item: got error %v, want %v", err, item.ErrBarcodeDoesNotExist)
+	}
+
+	if err := s.RemoveBarcode(bottleID, barcode); err != nil {
+		t.Fatalf("failed to remove barcode: %v", err)
+	}
+
+	// Once removed, the barcode is free for another item.
+	if _, err := s.AddBarcode(canID, barcode); err != nil {
+		t.Errorf("failed to add removed barcode to another item: %v", err)
+	}
+}
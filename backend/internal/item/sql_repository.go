@@ -0,0 +1,258 @@
+package item
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/item/domain"
+)
+
+type item struct {
+	ItemID   int           `db:"item_id"`
+	Name     string        `db:"name"`
+	Material string        `db:"material"`
+	VolumeML sql.NullInt64 `db:"volume_ml"`
+	Points   int           `db:"points"`
+	IsActive bool          `db:"is_active"`
+}
+
+func (i item) toDomain() (domain.Item, error) {
+	material, err := domain.NewMaterial(i.Material)
+	if err != nil {
+		return domain.Item{}, fmt.Errorf("failed to parse material: %w", err)
+	}
+
+	var volumeML *int
+	if i.VolumeML.Valid {
+		v := int(i.VolumeML.Int64)
+		volumeML = &v
+	}
+
+	return domain.Item{
+		ID:       i.ItemID,
+		Name:     i.Name,
+		Material: material,
+		VolumeML: volumeML,
+		Points:   i.Points,
+		Active:   i.IsActive,
+	}, nil
+}
+
+type itemBarcode struct {
+	ItemID  int    `db:"item_id"`
+	Barcode string `db:"barcode"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (ir *SQLRepository) GetItems(includeArchived bool) ([]domain.Item, error) {
+	var raw []item
+	if err := ir.db.Select(&raw, `
+		SELECT
+			item_id, name, material, volume_ml, points, is_active
+		FROM
+			items
+		WHERE
+			is_active = ? OR ?
+		ORDER BY
+			item_id
+	`, true, includeArchived); err != nil {
+		return nil, fmt.Errorf("GetItems(): failed to execute query: %w", err)
+	}
+
+	items := make([]domain.Item, 0, len(raw))
+	for _, i := range raw {
+		it, err := i.toDomain()
+		if err != nil {
+			return nil, fmt.Errorf("GetItems(): %w", err)
+		}
+
+		items = append(items, it)
+	}
+
+	return items, nil
+}
+
+func (ir *SQLRepository) GetItem(id int) (*domain.Item, error) {
+	var raw item
+	if err := ir.db.Get(&raw, `
+		SELECT
+			item_id, name, material, volume_ml, points, is_active
+		FROM
+			items
+		WHERE
+			item_id = ?
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrItemDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetItem(): failed to execute query: %w", err)
+	}
+
+	it, err := raw.toDomain()
+	if err != nil {
+		return nil, fmt.Errorf("GetItem(): %w", err)
+	}
+
+	return &it, nil
+}
+
+func (ir *SQLRepository) CreateItem(it domain.Item) (int, error) {
+	var id int
+	if err := ir.db.Get(&id, `
+		INSERT INTO
+			items (name, material, volume_ml, points, is_active)
+		VALUES
+			(?, ?, ?, ?, ?)
+		RETURNING
+			item_id
+	`, it.Name, it.Material, it.VolumeML, it.Points, it.Active); err != nil {
+		return 0, fmt.Errorf("CreateItem(): failed to execute query: %w", err)
+	}
+
+	return id, nil
+}
+
+func (ir *SQLRepository) UpdateItem(it domain.Item) (bool, error) {
+	res, err := ir.db.Exec(`
+		UPDATE
+			items
+		SET
+			name = ?,
+			material = ?,
+			volume_ml = ?,
+			points = ?
+		WHERE
+			item_id = ?
+	`, it.Name, it.Material, it.VolumeML, it.Points, it.ID)
+	if err != nil {
+		return false, fmt.Errorf("UpdateItem(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("UpdateItem(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (ir *SQLRepository) SetItemActive(id int, active bool) (bool, error) {
+	res, err := ir.db.Exec(`
+		UPDATE
+			items
+		SET
+			is_active = ?
+		WHERE
+			item_id = ?
+	`, active, id)
+	if err != nil {
+		return false, fmt.Errorf("SetItemActive(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("SetItemActive(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (ir *SQLRepository) GetBarcodes(itemID int) ([]domain.GTIN, error) {
+	var barcodes []domain.GTIN
+	if err := ir.db.Select(&barcodes, `
+		SELECT
+			barcode
+		FROM
+			item_barcodes
+		WHERE
+			item_id = ?
+		ORDER BY
+			barcode
+	`, itemID); err != nil {
+		return nil, fmt.Errorf("GetBarcodes(): failed to execute query: %w", err)
+	}
+
+	return barcodes, nil
+}
+
+func (ir *SQLRepository) GetAllBarcodes() (map[int][]domain.GTIN, error) {
+	var raw []itemBarcode
+	if err := ir.db.Select(&raw, `
+		SELECT
+			item_id, barcode
+		FROM
+			item_barcodes
+		ORDER BY
+			item_id, barcode
+	`); err != nil {
+		return nil, fmt.Errorf("GetAllBarcodes(): failed to execute query: %w", err)
+	}
+
+	barcodes := make(map[int][]domain.GTIN)
+	for _, b := range raw {
+		barcodes[b.ItemID] = append(barcodes[b.ItemID], domain.GTIN(b.Barcode))
+	}
+
+	return barcodes, nil
+}
+
+func (ir *SQLRepository) AddBarcode(itemID int, barcode domain.GTIN, createdAt time.Time) error {
+	if _, err := ir.db.Exec(`
+		INSERT INTO
+			item_barcodes (barcode, item_id, created_at)
+		VALUES
+			(?, ?, ?)
+		ON CONFLICT (barcode) DO NOTHING
+	`, barcode, itemID, createdAt); err != nil {
+		return fmt.Errorf("AddBarcode(): failed to execute query: %w", err)
+	}
+
+	// Whether the insert happened or not, the barcode now belongs to someone; make sure it's this item.
+	var ownerID int
+	if err := ir.db.Get(&ownerID, `
+		SELECT
+			item_id
+		FROM
+			item_barcodes
+		WHERE
+			barcode = ?
+	`, barcode); err != nil {
+		return fmt.Errorf("AddBarcode(): failed to get owner: %w", err)
+	}
+
+	if ownerID != itemID {
+		return fmt.Errorf("AddBarcode(): %w with id %d", ErrBarcodeTaken, ownerID)
+	}
+
+	return nil
+}
+
+func (ir *SQLRepository) RemoveBarcode(itemID int, barcode domain.GTIN) (bool, error) {
+	res, err := ir.db.Exec(`
+		DELETE FROM
+			item_barcodes
+		WHERE
+			item_id = ? AND barcode = ?
+	`, itemID, barcode)
+	if err != nil {
+		return false, fmt.Errorf("RemoveBarcode(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("RemoveBarcode(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
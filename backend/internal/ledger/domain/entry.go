@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidAmount = errors.New("invalid amount for entry kind")
+
+type EntryKind string
+
+const (
+	// EntryKindEarning credits the points of a claimed transaction.
+	EntryKindEarning EntryKind = "earning"
+	// EntryKindRedemption debits the points spent on a reward.
+	EntryKindRedemption EntryKind = "redemption"
+	// EntryKindAdjustment is a manual correction in either direction.
+	EntryKindAdjustment EntryKind = "adjustment"
+	// EntryKindExpiry debits points that are no longer valid.
+	EntryKindExpiry EntryKind = "expiry"
+)
+
+func NewEntryKind(value string) (EntryKind, error) {
+	switch k := EntryKind(value); k {
+	case EntryKindEarning, EntryKindRedemption, EntryKindAdjustment, EntryKindExpiry:
+		return k, nil
+	}
+
+	return "", fmt.Errorf("unknown entry kind %q", value)
+}
+
+func (k EntryKind) String() string {
+	return string(k)
+}
+
+// Entry is a single, never modified, movement of points. Credits are positive and debits negative.
+type Entry struct {
+	ID     int64
+	UserID string
+	Kind   EntryKind
+	Amount int
+	// TransactionID is set for earnings and for adjustments made to a transaction's points.
+	TransactionID *string
+	// Reference points at whatever else caused the entry, e.g. a redemption.
+	Reference *string
+	Note      string
+	CreatedAt time.Time
+}
+
+// NewEntry creates a new entry, making sure the amount's sign matches the kind.
+func NewEntry(
+	userID string,
+	kind EntryKind,
+	amount int,
+	transactionID *string,
+	reference *string,
+	note string,
+	createdAt time.Time,
+) (Entry, error) {
+	var valid bool
+
+	switch kind {
+	case EntryKindEarning:
+		valid = amount >= 0
+	case EntryKindRedemption, EntryKindExpiry:
+		valid = amount < 0
+	case EntryKindAdjustment:
+		valid = amount != 0
+	}
+
+	if !valid {
+		return Entry{}, fmt.Errorf("%w: %d for %s", ErrInvalidAmount, amount, kind)
+	}
+
+	return Entry{
+		UserID:        userID,
+		Kind:          kind,
+		Amount:        amount,
+		TransactionID: transactionID,
+		Reference:     reference,
+		Note:          note,
+		CreatedAt:     createdAt,
+	}, nil
+}
@@ -0,0 +1,29 @@
+package ledger
+
+import (
+	"errors"
+
+	"github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+type Repository interface {
+	// AppendEntry records the entry and applies its amount to the user's balance. Debits that would
+	// make the balance negative return ErrInsufficientBalance. Run it in a unit of work so that
+	// the entry and the balance can't get out of sync.
+	AppendEntry(entry domain.Entry) error
+	GetBalance(userID string) (int, error)
+	// GetBalances returns every stored balance by user id.
+	GetBalances() (map[string]int, error)
+	// GetEntryTotals returns the sum of every user's ledger entries by user id.
+	GetEntryTotals() (map[string]int, error)
+	// GetEarningTotals returns the sum of every user's earning entries, and of the adjustments made
+	// to their transactions, by user id.
+	GetEarningTotals() (map[string]int, error)
+	// GetDerivedEarnings returns, by user id, the points the items in the user's claimed transactions
+	// were credited with.
+	GetDerivedEarnings() (map[string]int, error)
+}
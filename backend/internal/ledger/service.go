@@ -0,0 +1,96 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Discrepancy describes a user whose stored balance doesn't agree with the ledger,
+// or whose earnings don't agree with their claimed transactions.
+type Discrepancy struct {
+	UserID string
+	// Balance is the maintained balance.
+	Balance int
+	// EntryTotal is the sum of all of the user's ledger entries; it should always equal Balance.
+	EntryTotal int
+	// EarningTotal is the sum of the user's earning entries and the adjustments made to their transactions.
+	EarningTotal int
+	// DerivedEarnings is what the items in the user's claimed transactions were credited with.
+	// Relabels update it along with their adjustments, so it should always equal EarningTotal.
+	DerivedEarnings int
+}
+
+// BalanceMismatch reports whether the balance has drifted from the ledger, which is always a bug.
+func (d Discrepancy) BalanceMismatch() bool {
+	return d.Balance != d.EntryTotal
+}
+
+type Service struct {
+	r Repository
+}
+
+func NewService(r Repository) *Service {
+	return &Service{r: r}
+}
+
+func (s *Service) GetBalance(userID string) (int, error) {
+	b, err := s.r.GetBalance(userID)
+	if err != nil {
+		return 0, fmt.Errorf("GetBalance(): failed to get balance: %w", err)
+	}
+
+	return b, nil
+}
+
+// Reconcile compares every user's balance with their ledger entries, and their earnings with
+// their claimed transactions, returning the users for whom either doesn't match, sorted by user id.
+func (s *Service) Reconcile() ([]Discrepancy, error) {
+	balances, err := s.r.GetBalances()
+	if err != nil {
+		return nil, fmt.Errorf("Reconcile(): failed to get balances: %w", err)
+	}
+
+	entryTotals, err := s.r.GetEntryTotals()
+	if err != nil {
+		return nil, fmt.Errorf("Reconcile(): failed to get entry totals: %w", err)
+	}
+
+	earningTotals, err := s.r.GetEarningTotals()
+	if err != nil {
+		return nil, fmt.Errorf("Reconcile(): failed to get earning totals: %w", err)
+	}
+
+	derivedEarnings, err := s.r.GetDerivedEarnings()
+	if err != nil {
+		return nil, fmt.Errorf("Reconcile(): failed to get derived earnings: %w", err)
+	}
+
+	userIDs := make(map[string]struct{})
+	for _, m := range []map[string]int{balances, entryTotals, earningTotals, derivedEarnings} {
+		for userID := range m {
+			userIDs[userID] = struct{}{}
+		}
+	}
+
+	var discrepancies []Discrepancy
+
+	for userID := range userIDs {
+		d := Discrepancy{
+			UserID:          userID,
+			Balance:         balances[userID],
+			EntryTotal:      entryTotals[userID],
+			EarningTotal:    earningTotals[userID],
+			DerivedEarnings: derivedEarnings[userID],
+		}
+
+		if d.BalanceMismatch() || d.EarningTotal != d.DerivedEarnings {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+
+	sort.Slice(discrepancies, func(i, j int) bool {
+		return discrepancies[i].UserID < discrepancies[j].UserID
+	})
+
+	return discrepancies, nil
+}
@@ -0,0 +1,118 @@
+package ledger_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction"
+	transactiondomain "github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
+)
+
+func TestReconcileAfterRepricingAndRelabel(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	r := ledger.NewSQLRepository(dbHandle)
+	tr := transaction.NewSQLRepository(dbHandle)
+	s := ledger.NewService(r)
+
+	const userID = "user-1"
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 30)
+
+	id := transactiondomain.TransactionID("5b8e2f0a-3c7d-4e1f-9a6b-0d4c8e2f1a37")
+	tid := id.String()
+	now := time.Now()
+
+	if err := tr.StartTransaction(id, dbtest.CreateMachine(t, dbHandle), now); err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	for _, itemID := range []int{bottleID, bottleID} {
+		if err := tr.AddItemToTransaction(id, itemID, nil, now); err != nil {
+			t.Fatalf("failed to add item %d: %v", itemID, err)
+		}
+	}
+
+	if ok, err := tr.CloseTransaction(id, now); err != nil || !ok {
+		t.Fatalf("failed to close transaction: ok=%v, err=%v", ok, err)
+	}
+
+	if ok, err := tr.ClaimTransaction(id, userID, now, now.Add(-time.Hour)); err != nil || !ok {
+		t.Fatalf("failed to claim transaction: ok=%v, err=%v", ok, err)
+	}
+
+	if err := tr.CreditTransactionItems(id); err != nil {
+		t.Fatalf("failed to credit transaction items: %v", err)
+	}
+
+	appendTransactionEntry(t, r, userID, domain.EntryKindEarning, 20, tid)
+
+	// Repricing the catalog after the claim doesn't change what was earned.
+	if _, err := dbHandle.Exec("UPDATE items SET points = ? WHERE item_id = ?", 50, bottleID); err != nil {
+		t.Fatalf("failed to update item points: %v", err)
+	}
+
+	// Relabeling one of the bottles as a can credits it with the can's points and adjusts for the difference,
+	// the way a review does.
+	if _, err := dbHandle.Exec(`
+		UPDATE
+			transaction_items
+		SET
+			item_id = ?, points = ?
+		WHERE
+			transaction_item_id = (SELECT MIN(transaction_item_id) FROM transaction_items WHERE transaction_id = ?)
+	`, canID, 30, id); err != nil {
+		t.Fatalf("failed to relabel transaction item: %v", err)
+	}
+
+	appendTransactionEntry(t, r, userID, domain.EntryKindAdjustment, 20, tid)
+
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	if len(discrepancies) != 0 {
+		t.Errorf("got discrepancies %+v, want none", discrepancies)
+	}
+
+	// An earning that no transaction item accounts for is reported.
+	appendEntry(t, r, userID, domain.EntryKindEarning, 5)
+
+	discrepancies, err = s.Reconcile()
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	if len(discrepancies) != 1 {
+		t.Fatalf("got discrepancies %+v, want one", discrepancies)
+	}
+
+	if d := discrepancies[0]; d.EarningTotal != 45 || d.DerivedEarnings != 40 || d.BalanceMismatch() {
+		t.Errorf("got discrepancy %+v, want earnings of 45 against 40 derived and a matching balance", d)
+	}
+}
+
+func appendTransactionEntry(
+	t *testing.T,
+	r *ledger.SQLRepository,
+	userID string,
+	kind domain.EntryKind,
+	amount int,
+	transactionID string,
+) {
+	t.Helper()
+
+	entry, err := domain.NewEntry(userID, kind, amount, &transactionID, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	if err = r.AppendEntry(entry); err != nil {
+		t.Fatalf("failed to append entry: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package ledger
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+)
+
+type userTotal struct {
+	UserID string `db:"user_id"`
+	Total  int    `db:"total"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (lr *SQLRepository) AppendEntry(entry domain.Entry) error {
+	if entry.Amount < 0 {
+		// The condition makes the check and the debit a single step, so concurrent debits
+		// can't both pass the check.
+		res, err := lr.db.Exec(`
+			UPDATE
+				point_balances
+			SET
+				balance = balance + ?,
+				updated_at = ?
+			WHERE
+				user_id = ? AND balance + ? >= 0
+		`, entry.Amount, entry.CreatedAt, entry.UserID, entry.Amount)
+		if err != nil {
+			return fmt.Errorf("AppendEntry(): failed to debit balance: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("AppendEntry(): failed to get affected rows: %w", err)
+		}
+
+		if n == 0 {
+			return fmt.Errorf("AppendEntry(): %w for user %s", ErrInsufficientBalance, entry.UserID)
+		}
+	} else {
+		if _, err := lr.db.Exec(`
+			INSERT INTO
+				point_balances (user_id, balance, updated_at)
+			VALUES
+				(?, ?, ?)
+			ON CONFLICT (user_id) DO UPDATE SET
+				balance = point_balances.balance + excluded.balance,
+				updated_at = excluded.updated_at
+		`, entry.UserID, entry.Amount, entry.CreatedAt); err != nil {
+			return fmt.Errorf("AppendEntry(): failed to credit balance: %w", err)
+		}
+	}
+
+	if _, err := lr.db.Exec(`
+		INSERT INTO
+			point_ledger (user_id, kind, amount, transaction_id, reference, note, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?)
+	`,
+		entry.UserID,
+		entry.Kind,
+		entry.Amount,
+		nullString(entry.TransactionID),
+		nullString(entry.Reference),
+		entry.Note,
+		entry.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("AppendEntry(): failed to insert entry: %w", err)
+	}
+
+	return nil
+}
+
+func (lr *SQLRepository) GetBalance(userID string) (int, error) {
+	var balance int
+	if err := lr.db.Get(&balance, `
+		SELECT
+			COALESCE(MAX(balance), 0)
+		FROM
+			point_balances
+		WHERE
+			user_id = ?
+	`, userID); err != nil {
+		return 0, fmt.Errorf("GetBalance(): failed to execute query: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (lr *SQLRepository) GetBalances() (map[string]int, error) {
+	var rows []userTotal
+	if err := lr.db.Select(&rows, `
+		SELECT
+			user_id, balance AS total
+		FROM
+			point_balances
+	`); err != nil {
+		return nil, fmt.Errorf("GetBalances(): failed to execute query: %w", err)
+	}
+
+	return toMap(rows), nil
+}
+
+func (lr *SQLRepository) GetEntryTotals() (map[string]int, error) {
+	var rows []userTotal
+	if err := lr.db.Select(&rows, `
+		SELECT
+			user_id, SUM(amount) AS total
+		FROM
+			point_ledger
+		GROUP BY
+			user_id
+	`); err != nil {
+		return nil, fmt.Errorf("GetEntryTotals(): failed to execute query: %w", err)
+	}
+
+	return toMap(rows), nil
+}
+
+func (lr *SQLRepository) GetEarningTotals() (map[string]int, error) {
+	var rows []userTotal
+	if err := lr.db.Select(&rows, `
+		SELECT
+			user_id, SUM(amount) AS total
+		FROM
+			point_ledger
+		WHERE
+			kind = ? OR (kind = ? AND transaction_id IS NOT NULL)
+		GROUP BY
+			user_id
+	`, domain.EntryKindEarning, domain.EntryKindAdjustment); err != nil {
+		return nil, fmt.Errorf("GetEarningTotals(): failed to execute query: %w", err)
+	}
+
+	return toMap(rows), nil
+}
+
+func (lr *SQLRepository) GetDerivedEarnings() (map[string]int, error) {
+	var rows []userTotal
+	if err := lr.db.Select(&rows, `
+		SELECT
+			transactions.user_id, COALESCE(SUM(transaction_items.points), 0) AS total
+		FROM
+			transactions
+		LEFT JOIN
+			transaction_items ON transaction_items.transaction_id = transactions.transaction_id
+		WHERE
+			transactions.user_id IS NOT NULL
+		GROUP BY
+			transactions.user_id
+	`); err != nil {
+		return nil, fmt.Errorf("GetDerivedEarnings(): failed to execute query: %w", err)
+	}
+
+	return toMap(rows), nil
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func toMap(rows []userTotal) map[string]int {
+	m := make(map[string]int, len(rows))
+	for _, row := range rows {
+		m[row.UserID] = row.Total
+	}
+
+	return m
+}
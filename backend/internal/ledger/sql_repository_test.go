@@ -0,0 +1,100 @@
+package ledger_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+)
+
+func TestAppendEntry(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	r := ledger.NewSQLRepository(dbHandle)
+
+	const userID = "user-1"
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	appendEntry(t, r, userID, domain.EntryKindEarning, 30)
+	appendEntry(t, r, userID, domain.EntryKindEarning, 20)
+	appendEntry(t, r, userID, domain.EntryKindRedemption, -40)
+
+	assertBalance(t, r, userID, 10)
+
+	entry, err := domain.NewEntry(userID, domain.EntryKindRedemption, -11, nil, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	if err = r.AppendEntry(entry); !errors.Is(err, ledger.ErrInsufficientBalance) {
+		t.Errorf("got error %v, want %v", err, ledger.ErrInsufficientBalance)
+	}
+
+	assertBalance(t, r, userID, 10)
+
+	totals, err := r.GetEntryTotals()
+	if err != nil {
+		t.Fatalf("failed to get entry totals: %v", err)
+	}
+
+	// The rejected debit must not have left an entry behind.
+	if totals[userID] != 10 {
+		t.Errorf("got entry total %d, want 10", totals[userID])
+	}
+
+	earnings, err := r.GetEarningTotals()
+	if err != nil {
+		t.Fatalf("failed to get earning totals: %v", err)
+	}
+
+	if earnings[userID] != 50 {
+		t.Errorf("got earning total %d, want 50", earnings[userID])
+	}
+}
+
+func TestGetBalanceWithoutEntries(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	r := ledger.NewSQLRepository(dbHandle)
+
+	const userID = "user-1"
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	assertBalance(t, r, userID, 0)
+
+	entry, err := domain.NewEntry(userID, domain.EntryKindRedemption, -1, nil, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	if err = r.AppendEntry(entry); !errors.Is(err, ledger.ErrInsufficientBalance) {
+		t.Errorf("got error %v, want %v", err, ledger.ErrInsufficientBalance)
+	}
+}
+
+func appendEntry(t *testing.T, r *ledger.SQLRepository, userID string, kind domain.EntryKind, amount int) {
+	t.Helper()
+
+	entry, err := domain.NewEntry(userID, kind, amount, nil, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	if err = r.AppendEntry(entry); err != nil {
+		t.Fatalf("failed to append entry: %v", err)
+	}
+}
+
+func assertBalance(t *testing.T, r *ledger.SQLRepository, userID string, want int) {
+	t.Helper()
+
+	got, err := r.GetBalance(userID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+
+	if got != want {
+		t.Errorf("got balance %d, want %d", got, want)
+	}
+}
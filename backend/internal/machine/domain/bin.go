@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	maxFillPercent = 100
+	percent        = 100
+)
+
+type BinLevel string
+
+const (
+	// BinLevelOK bins have room to spare.
+	BinLevelOK BinLevel = "ok"
+	// BinLevelNearlyFull bins are past the alert threshold and should be scheduled for collection.
+	BinLevelNearlyFull BinLevel = "nearly_full"
+	// BinLevelFull bins are at or over capacity. Their machine refuses new transactions until they are emptied.
+	BinLevelFull BinLevel = "full"
+)
+
+func NewBinLevel(value string) (BinLevel, error) {
+	switch l := BinLevel(value); l {
+	case BinLevelOK, BinLevelNearlyFull, BinLevelFull:
+		return l, nil
+	}
+
+	return "", fmt.Errorf("unknown bin level %q", value)
+}
+
+func (l BinLevel) String() string {
+	return string(l)
+}
+
+// Bin is where a machine keeps the containers inserted into it. How full it is gets estimated
+// from the items added to the machine's transactions, unless a sensor reported otherwise.
+type Bin struct {
+	MachineID int
+	// Capacity is how many containers fit in the bin.
+	Capacity int
+	// EmptiedAt is nil if the bin was never emptied.
+	EmptiedAt *time.Time
+	// SensorFillPercent is the latest reading of the bin's fill-level sensor, if it has one.
+	SensorFillPercent *int
+	SensorReportedAt  *time.Time
+	// ItemsSinceEmptied and ItemsSinceSensor count the containers inserted after the bin was emptied
+	// and after the sensor's latest reading.
+	ItemsSinceEmptied int
+	ItemsSinceSensor  int
+}
+
+// ValidateBinCapacity returns an *InvalidMachineError if the capacity isn't positive.
+func ValidateBinCapacity(capacity int) error {
+	if capacity <= 0 {
+		return &InvalidMachineError{Reason: "bin_capacity must be positive"}
+	}
+
+	return nil
+}
+
+// ValidateBinFillPercent returns an *InvalidHeartbeatError if the sensor reading isn't a percentage.
+func ValidateBinFillPercent(fillPercent int) error {
+	if fillPercent < 0 || fillPercent > maxFillPercent {
+		return &InvalidHeartbeatError{
+			Reason: fmt.Sprintf("bin_fill_percent must be between 0 and %d", maxFillPercent),
+		}
+	}
+
+	return nil
+}
+
+// EstimatedItems returns how many containers are in the bin. A sensor reading taken after the bin was
+// last emptied takes precedence over counting, with the containers inserted since added on top.
+func (b Bin) EstimatedItems() int {
+	if b.SensorFillPercent == nil || b.SensorReportedAt == nil {
+		return b.ItemsSinceEmptied
+	}
+
+	if b.EmptiedAt != nil && !b.SensorReportedAt.After(*b.EmptiedAt) {
+		return b.ItemsSinceEmptied
+	}
+
+	return *b.SensorFillPercent*b.Capacity/percent + b.ItemsSinceSensor
+}
+
+// FillPercent returns how full the bin is. It goes over 100 if more was inserted than the bin should hold.
+func (b Bin) FillPercent() int {
+	return b.EstimatedItems() * percent / b.Capacity
+}
+
+// Level returns whether the bin is full, or nearly so given the fraction of its capacity to alert at.
+func (b Bin) Level(alertThreshold float64) BinLevel {
+	items := b.EstimatedItems()
+
+	if items >= b.Capacity {
+		return BinLevelFull
+	}
+
+	if float64(items) >= alertThreshold*float64(b.Capacity) {
+		return BinLevelNearlyFull
+	}
+
+	return BinLevelOK
+}
+
+// BinCollection records a collector emptying a machine's bin.
+type BinCollection struct {
+	MachineID      int
+	EstimatedItems int
+	EmptiedBy      string
+	EmptiedAt      time.Time
+}
+
+// BinAlert is raised once each time a bin becomes nearly full or full.
+type BinAlert struct {
+	MachineID   int
+	Level       BinLevel
+	FillPercent int
+	RaisedAt    time.Time
+}
@@ -0,0 +1,90 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+)
+
+func TestBinLevel(t *testing.T) {
+	emptiedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := emptiedAt.Add(-time.Hour)
+	after := emptiedAt.Add(time.Hour)
+
+	half := 50
+
+	for _, tc := range []struct {
+		name      string
+		bin       domain.Bin
+		wantItems int
+		want      domain.BinLevel
+	}{
+		{
+			name:      "counted items below the threshold",
+			bin:       domain.Bin{Capacity: 100, ItemsSinceEmptied: 79},
+			wantItems: 79,
+			want:      domain.BinLevelOK,
+		},
+		{
+			name:      "counted items at the threshold",
+			bin:       domain.Bin{Capacity: 100, ItemsSinceEmptied: 80},
+			wantItems: 80,
+			want:      domain.BinLevelNearlyFull,
+		},
+		{
+			name:      "counted items at capacity",
+			bin:       domain.Bin{Capacity: 100, ItemsSinceEmptied: 100},
+			wantItems: 100,
+			want:      domain.BinLevelFull,
+		},
+		{
+			name: "sensor reading with items inserted since",
+			bin: domain.Bin{
+				Capacity:          100,
+				EmptiedAt:         &emptiedAt,
+				SensorFillPercent: &half,
+				SensorReportedAt:  &after,
+				ItemsSinceEmptied: 10,
+				ItemsSinceSensor:  35,
+			},
+			wantItems: 85,
+			want:      domain.BinLevelNearlyFull,
+		},
+		{
+			name: "sensor reading without the bin ever being emptied",
+			bin: domain.Bin{
+				Capacity:          200,
+				SensorFillPercent: &half,
+				SensorReportedAt:  &after,
+				ItemsSinceEmptied: 10,
+				ItemsSinceSensor:  5,
+			},
+			wantItems: 105,
+			want:      domain.BinLevelOK,
+		},
+		{
+			name: "sensor reading from before the bin was emptied",
+			bin: domain.Bin{
+				Capacity:          100,
+				EmptiedAt:         &emptiedAt,
+				SensorFillPercent: &half,
+				SensorReportedAt:  &before,
+				ItemsSinceEmptied: 10,
+				ItemsSinceSensor:  10,
+			},
+			wantItems: 10,
+			want:      domain.BinLevelOK,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.bin.EstimatedItems(); got != tc.wantItems {
+				t.Errorf("got %d items, want %d", got, tc.wantItems)
+			}
+
+			if got := tc.bin.Level(0.8); got != tc.want {
+				t.Errorf("got level %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
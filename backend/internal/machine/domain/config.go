@@ -0,0 +1,234 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// maxConfigStringLength matches CONFIG_STRING_MAX_LENGTH in the camera module, minus the null terminator.
+	maxConfigStringLength = 255
+	maxPort               = 65535
+	maxTimeoutMs          = 65535
+	minCameraLevel        = -2
+	maxCameraLevel        = 2
+	maxAECValue           = 1200
+	maxAGCGain            = 30
+	maxLEDIntensity       = 255
+)
+
+const (
+	stringReason         = " must be between 1 and 255 characters long"
+	optionalStringReason = " can't be longer than 255 characters"
+	portReason           = " must be between 1 and 65535"
+	endpointReason       = " must start with / and be at most 255 characters long"
+	cameraLevelReason    = " must be between -2 and 2"
+)
+
+// The names the camera module's camera_*.h headers accept.
+func cameraEffects() []string {
+	return []string{"Normal", "Negative", "Grayscale", "Red Tint", "Green Tint", "Blue Tint", "Sepia"}
+}
+
+func cameraWBModes() []string {
+	return []string{"Auto", "Sunny", "Cloudy", "Office", "Home"}
+}
+
+func cameraGainCeilings() []string {
+	return []string{"2X", "4X", "8X", "16X", "32X", "64X", "128X"}
+}
+
+// InvalidConfigError is returned when a configuration document can't be applied to a machine.
+type InvalidConfigError struct {
+	Reason string
+}
+
+func (e *InvalidConfigError) Error() string {
+	return "invalid machine config: " + e.Reason
+}
+
+type BackendServerConfig struct {
+	Hostname                    string `json:"hostname"`
+	Port                        int    `json:"port"`
+	TimeoutMs                   int    `json:"timeout_ms"`
+	ImageClassificationEndpoint string `json:"image_classification_endpoint"`
+	HeartbeatEndpoint           string `json:"heartbeat_endpoint"`
+}
+
+type MQTTConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type CameraConfig struct {
+	Brightness       int    `json:"brightness"`
+	Contrast         int    `json:"contrast"`
+	Saturation       int    `json:"saturation"`
+	Effect           string `json:"effect"`
+	WhiteBalance     bool   `json:"white_balance"`
+	WhiteBalanceGain bool   `json:"white_balance_gain"`
+	WhiteBalanceMode string `json:"white_balance_mode"`
+	ExposureControl  bool   `json:"exposure_control"`
+	AEC2             bool   `json:"aec2"`
+	AELevel          int    `json:"ae_level"`
+	AECValue         int    `json:"aec_value"`
+	GainControl      bool   `json:"gain_control"`
+	AGCGain          int    `json:"agc_gain"`
+	GainCeiling      string `json:"gain_ceiling"`
+	BPC              bool   `json:"bpc"`
+	WPC              bool   `json:"wpc"`
+	RawGamma         bool   `json:"raw_gamma"`
+	LensCorrection   bool   `json:"lens_correction"`
+	HorizontalMirror bool   `json:"horizontal_mirror"`
+	VerticalFlip     bool   `json:"vertical_flip"`
+	DCW              bool   `json:"dcw"`
+	Colorbar         bool   `json:"colorbar"`
+	LEDIntensity     int    `json:"led_intensity"`
+}
+
+// ConfigDocument is everything the camera module otherwise has to be configured with through its own portal.
+// The machine token isn't part of it, since the machine needs it to fetch the document in the first place.
+type ConfigDocument struct {
+	BackendServer BackendServerConfig `json:"backend_server"`
+	MQTT          MQTTConfig          `json:"mqtt"`
+	Camera        CameraConfig        `json:"camera"`
+}
+
+// DefaultConfigDocument returns the camera module's built-in defaults. It isn't valid on its own
+// since there are no defaults for the backend server's hostname and the MQTT broker's host.
+func DefaultConfigDocument() ConfigDocument {
+	return ConfigDocument{
+		BackendServer: BackendServerConfig{
+			Port:                        80,
+			TimeoutMs:                   5000,
+			ImageClassificationEndpoint: "/image-classification",
+			HeartbeatEndpoint:           "/machines/heartbeat",
+		},
+		MQTT: MQTTConfig{
+			Port: 8883,
+		},
+		Camera: CameraConfig{
+			Effect:           "Normal",
+			WhiteBalance:     true,
+			WhiteBalanceGain: true,
+			WhiteBalanceMode: "Auto",
+			ExposureControl:  true,
+			AEC2:             true,
+			AECValue:         300,
+			GainControl:      true,
+			GainCeiling:      "2X",
+			WPC:              true,
+			RawGamma:         true,
+			LensCorrection:   true,
+			DCW:              true,
+		},
+	}
+}
+
+// MergeConfigDocument overwrites the fields of base that are present in the JSON patch and validates the result.
+// Unknown fields and invalid values return an *InvalidConfigError.
+func MergeConfigDocument(base ConfigDocument, patch []byte) (ConfigDocument, error) {
+	doc := base
+
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&doc); err != nil {
+		return ConfigDocument{}, &InvalidConfigError{
+			Reason: "document has to be a JSON object of known settings: " + err.Error(),
+		}
+	}
+
+	if err := doc.Validate(); err != nil {
+		return ConfigDocument{}, err
+	}
+
+	return doc, nil
+}
+
+// Validate returns an *InvalidConfigError if any setting is out of the range the camera module accepts.
+func (d ConfigDocument) Validate() error {
+	checks := []struct {
+		ok     bool
+		reason string
+	}{
+		{isConfigString(d.BackendServer.Hostname, true), "backend_server.hostname" + stringReason},
+		{isPort(d.BackendServer.Port), "backend_server.port" + portReason},
+		{d.BackendServer.TimeoutMs > 0 && d.BackendServer.TimeoutMs <= maxTimeoutMs,
+			fmt.Sprintf("backend_server.timeout_ms must be between 1 and %d", maxTimeoutMs)},
+		{isEndpoint(d.BackendServer.ImageClassificationEndpoint),
+			"backend_server.image_classification_endpoint" + endpointReason},
+		{isEndpoint(d.BackendServer.HeartbeatEndpoint), "backend_server.heartbeat_endpoint" + endpointReason},
+		{isConfigString(d.MQTT.Host, true), "mqtt.host" + stringReason},
+		{isPort(d.MQTT.Port), "mqtt.port" + portReason},
+		{isConfigString(d.MQTT.Username, false), "mqtt.username" + optionalStringReason},
+		{isConfigString(d.MQTT.Password, false), "mqtt.password" + optionalStringReason},
+		{isCameraLevel(d.Camera.Brightness), "camera.brightness" + cameraLevelReason},
+		{isCameraLevel(d.Camera.Contrast), "camera.contrast" + cameraLevelReason},
+		{isCameraLevel(d.Camera.Saturation), "camera.saturation" + cameraLevelReason},
+		{isCameraLevel(d.Camera.AELevel), "camera.ae_level" + cameraLevelReason},
+		{isOneOf(d.Camera.Effect, cameraEffects()),
+			"camera.effect has to be one of " + strings.Join(cameraEffects(), ", ")},
+		{isOneOf(d.Camera.WhiteBalanceMode, cameraWBModes()),
+			"camera.white_balance_mode has to be one of " + strings.Join(cameraWBModes(), ", ")},
+		{isOneOf(d.Camera.GainCeiling, cameraGainCeilings()),
+			"camera.gain_ceiling has to be one of " + strings.Join(cameraGainCeilings(), ", ")},
+		{d.Camera.AECValue >= 0 && d.Camera.AECValue <= maxAECValue,
+			fmt.Sprintf("camera.aec_value must be between 0 and %d", maxAECValue)},
+		{d.Camera.AGCGain >= 0 && d.Camera.AGCGain <= maxAGCGain,
+			fmt.Sprintf("camera.agc_gain must be between 0 and %d", maxAGCGain)},
+		{d.Camera.LEDIntensity >= 0 && d.Camera.LEDIntensity <= maxLEDIntensity,
+			fmt.Sprintf("camera.led_intensity must be between 0 and %d", maxLEDIntensity)},
+	}
+
+	for _, c := range checks {
+		if !c.ok {
+			return &InvalidConfigError{Reason: c.reason}
+		}
+	}
+
+	return nil
+}
+
+func isConfigString(s string, required bool) bool {
+	return (!required || s != "") && len(s) <= maxConfigStringLength
+}
+
+func isPort(p int) bool {
+	return p > 0 && p <= maxPort
+}
+
+func isEndpoint(s string) bool {
+	return strings.HasPrefix(s, "/") && len(s) <= maxConfigStringLength
+}
+
+func isCameraLevel(v int) bool {
+	return v >= minCameraLevel && v <= maxCameraLevel
+}
+
+func isOneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConfigVersion is one saved revision of a machine's configuration.
+type ConfigVersion struct {
+	MachineID int
+	// Version starts at 1 and goes up by one with every change, rollbacks included.
+	Version  int
+	Document ConfigDocument
+	// RolledBackFrom is the version whose document this one restored, or nil if it wasn't a rollback.
+	RolledBackFrom *int
+	CreatedBy      string
+	CreatedAt      time.Time
+}
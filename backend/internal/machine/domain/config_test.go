@@ -0,0 +1,81 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+)
+
+func TestMergeConfigDocument(t *testing.T) {
+	base := domain.DefaultConfigDocument()
+	base.BackendServer.Hostname = "backend.example.com"
+	base.MQTT.Host = "mqtt.example.com"
+
+	for _, tc := range []struct {
+		name    string
+		base    domain.ConfigDocument
+		patch   string
+		wantErr bool
+		check   func(d domain.ConfigDocument) bool
+	}{
+		{
+			name:  "setting overwritten, the rest kept",
+			base:  base,
+			patch: `{"camera": {"brightness": 2}}`,
+			check: func(d domain.ConfigDocument) bool {
+				return d.Camera.Brightness == 2 && d.Camera.Effect == "Normal" && d.MQTT.Host == "mqtt.example.com"
+			},
+		},
+		{
+			name:  "empty patch",
+			base:  base,
+			patch: `{}`,
+			check: func(d domain.ConfigDocument) bool {
+				return d == base
+			},
+		},
+		{
+			name:  "defaults completed",
+			base:  domain.DefaultConfigDocument(),
+			patch: `{"backend_server": {"hostname": "backend.example.com"}, "mqtt": {"host": "mqtt.example.com"}}`,
+			check: func(d domain.ConfigDocument) bool {
+				return d == base
+			},
+		},
+		{name: "defaults alone", base: domain.DefaultConfigDocument(), patch: `{}`, wantErr: true},
+		{name: "unknown setting", base: base, patch: `{"camera": {"zoom": 2}}`, wantErr: true},
+		{name: "wrong type", base: base, patch: `{"mqtt": {"port": "8883"}}`, wantErr: true},
+		{name: "not an object", base: base, patch: `[]`, wantErr: true},
+		{name: "camera level out of range", base: base, patch: `{"camera": {"contrast": 3}}`, wantErr: true},
+		{name: "unknown effect", base: base, patch: `{"camera": {"effect": "Vintage"}}`, wantErr: true},
+		{name: "port out of range", base: base, patch: `{"backend_server": {"port": 65536}}`, wantErr: true},
+		{
+			name:    "endpoint without a leading slash",
+			base:    base,
+			patch:   `{"backend_server": {"heartbeat_endpoint": "heartbeat"}}`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := domain.MergeConfigDocument(tc.base, []byte(tc.patch))
+
+			if tc.wantErr {
+				var invalid *domain.InvalidConfigError
+				if !errors.As(err, &invalid) {
+					t.Errorf("got error %v, want an *InvalidConfigError", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to merge: %v", err)
+			}
+
+			if !tc.check(got) {
+				t.Errorf("got document %+v", got)
+			}
+		})
+	}
+}
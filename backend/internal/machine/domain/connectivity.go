@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type Connectivity string
+
+const (
+	// ConnectivityOnline machines sent a heartbeat within the grace period.
+	ConnectivityOnline Connectivity = "online"
+	// ConnectivityOffline machines haven't sent a heartbeat within the grace period, or never sent one.
+	ConnectivityOffline Connectivity = "offline"
+)
+
+func NewConnectivity(value string) (Connectivity, error) {
+	switch c := Connectivity(value); c {
+	case ConnectivityOnline, ConnectivityOffline:
+		return c, nil
+	}
+
+	return "", fmt.Errorf("unknown connectivity %q", value)
+}
+
+func (c Connectivity) String() string {
+	return string(c)
+}
+
+// ConnectivityEvent records a machine going online or offline.
+type ConnectivityEvent struct {
+	MachineID    int
+	Connectivity Connectivity
+	OccurredAt   time.Time
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	maxFirmwareVersionLength = 64
+	// minWiFiRSSI is the weakest signal an ESP32 reports, in dBm.
+	minWiFiRSSI = -127
+)
+
+// InvalidHeartbeatError is returned when a heartbeat's values are out of range.
+type InvalidHeartbeatError struct {
+	Reason string
+}
+
+func (e *InvalidHeartbeatError) Error() string {
+	return "invalid heartbeat: " + e.Reason
+}
+
+// Heartbeat is a machine's periodic report that it is still running.
+type Heartbeat struct {
+	MachineID       int
+	FirmwareVersion string
+	UptimeSeconds   int64
+	FreeHeapBytes   int64
+	// WiFiRSSI is the strength of the machine's WiFi signal in dBm.
+	WiFiRSSI   int
+	ReceivedAt time.Time
+}
+
+// NewHeartbeat creates a new heartbeat, returning an *InvalidHeartbeatError if any value is out of range.
+func NewHeartbeat(
+	machineID int,
+	firmwareVersion string,
+	uptimeSeconds int64,
+	freeHeapBytes int64,
+	wifiRSSI int,
+	receivedAt time.Time,
+) (Heartbeat, error) {
+	if firmwareVersion == "" || len(firmwareVersion) > maxFirmwareVersionLength {
+		return Heartbeat{}, &InvalidHeartbeatError{
+			Reason: fmt.Sprintf("firmware_version must be between 1 and %d characters long", maxFirmwareVersionLength),
+		}
+	}
+
+	if uptimeSeconds < 0 {
+		return Heartbeat{}, &InvalidHeartbeatError{Reason: "uptime_seconds can't be negative"}
+	}
+
+	if freeHeapBytes < 0 {
+		return Heartbeat{}, &InvalidHeartbeatError{Reason: "free_heap_bytes can't be negative"}
+	}
+
+	if wifiRSSI < minWiFiRSSI || wifiRSSI > 0 {
+		return Heartbeat{}, &InvalidHeartbeatError{
+			Reason: fmt.Sprintf("wifi_rssi must be between %d and 0", minWiFiRSSI),
+		}
+	}
+
+	return Heartbeat{
+		MachineID:       machineID,
+		FirmwareVersion: firmwareVersion,
+		UptimeSeconds:   uptimeSeconds,
+		FreeHeapBytes:   freeHeapBytes,
+		WiFiRSSI:        wifiRSSI,
+		ReceivedAt:      receivedAt,
+	}, nil
+}
+
+// IsStaleAt reports whether the heartbeat is too old at t for its machine to still count as online.
+func (h Heartbeat) IsStaleAt(at time.Time, gracePeriod time.Duration) bool {
+	return h.ReceivedAt.Add(gracePeriod).Before(at)
+}
+
+// OfflineAt returns when the machine went offline if no heartbeat came after this one.
+func (h Heartbeat) OfflineAt(gracePeriod time.Duration) time.Time {
+	return h.ReceivedAt.Add(gracePeriod)
+}
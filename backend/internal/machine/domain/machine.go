@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	maxNameLength     = 255
+	maxLocationLength = 255
+)
+
+// InvalidMachineError is returned when a machine's fields are out of range. Reason is meant for whoever entered them.
+type InvalidMachineError struct {
+	Reason string
+}
+
+func (e *InvalidMachineError) Error() string {
+	return "invalid machine: " + e.Reason
+}
+
+// Machine is a reverse vending machine. Every machine token belongs to one, so the backend knows
+// which machine made a request.
+type Machine struct {
+	ID   int
+	Name string
+	// Location is a free-form description of where the machine stands, e.g. an address.
+	Location  string
+	Status    Status
+	CreatedAt time.Time
+	// LastHeartbeat is nil if the machine never sent one.
+	LastHeartbeat *Heartbeat
+}
+
+// ConnectivityAt returns whether the machine counts as online at t, given how long it may go
+// without a heartbeat.
+func (m Machine) ConnectivityAt(at time.Time, gracePeriod time.Duration) Connectivity {
+	if m.LastHeartbeat == nil || m.LastHeartbeat.IsStaleAt(at, gracePeriod) {
+		return ConnectivityOffline
+	}
+
+	return ConnectivityOnline
+}
+
+// NewMachine creates a new machine, returning an *InvalidMachineError if any field is out of range.
+func NewMachine(id int, name string, location string, status Status, createdAt time.Time) (Machine, error) {
+	if name == "" || len(name) > maxNameLength {
+		return Machine{}, &InvalidMachineError{
+			Reason: fmt.Sprintf("name must be between 1 and %d characters long", maxNameLength),
+		}
+	}
+
+	if len(location) > maxLocationLength {
+		return Machine{}, &InvalidMachineError{
+			Reason: fmt.Sprintf("location must be at most %d characters long", maxLocationLength),
+		}
+	}
+
+	return Machine{
+		ID:        id,
+		Name:      name,
+		Location:  location,
+		Status:    status,
+		CreatedAt: createdAt,
+	}, nil
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "fmt"
+
+type Status string
+
+const (
+	// StatusActive machines are in service and can run transactions.
+	StatusActive Status = "active"
+	// StatusMaintenance machines are being serviced. Their tokens still work so that they can be tested.
+	StatusMaintenance Status = "maintenance"
+	// StatusRetired machines are out of service for good and their tokens are no longer accepted.
+	StatusRetired Status = "retired"
+)
+
+func NewStatus(value string) (Status, error) {
+	switch s := Status(value); s {
+	case StatusActive, StatusMaintenance, StatusRetired:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("unknown machine status %q", value)
+}
+
+func (s Status) String() string {
+	return string(s)
+}
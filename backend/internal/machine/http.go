@@ -0,0 +1,859 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/auth"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// maxConfigDocumentSize caps the body of a configuration update. Whole documents are well under 4 KiB.
+const maxConfigDocumentSize = 64 * 1024
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeMachineDoesNotExist       = "machine_does_not_exist"
+	codeMachineRetired            = "machine_retired"
+	codeMachineNotConfigured      = "machine_not_configured"
+	codeConfigVersionDoesNotExist = "config_version_does_not_exist"
+)
+
+type heartbeatResponse struct {
+	FirmwareVersion string    `json:"firmware_version"`
+	UptimeSeconds   int64     `json:"uptime_seconds"`
+	FreeHeapBytes   int64     `json:"free_heap_bytes"`
+	WiFiRSSI        int       `json:"wifi_rssi"`
+	ReceivedAt      time.Time `json:"received_at"`
+}
+
+type machineResponse struct {
+	ID            int                `json:"id"`
+	Name          string             `json:"name"`
+	Location      string             `json:"location"`
+	Status        string             `json:"status"`
+	Connectivity  string             `json:"connectivity"`
+	LastSeenAt    *time.Time         `json:"last_seen_at"`
+	LastHeartbeat *heartbeatResponse `json:"last_heartbeat"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type binResponse struct {
+	MachineID         int        `json:"machine_id"`
+	Capacity          int        `json:"capacity"`
+	EstimatedItems    int        `json:"estimated_items"`
+	FillPercent       int        `json:"fill_percent"`
+	Level             string     `json:"level"`
+	EmptiedAt         *time.Time `json:"emptied_at"`
+	SensorFillPercent *int       `json:"sensor_fill_percent"`
+	SensorReportedAt  *time.Time `json:"sensor_reported_at"`
+}
+
+type binCollectionResponse struct {
+	MachineID      int       `json:"machine_id"`
+	EstimatedItems int       `json:"estimated_items"`
+	EmptiedBy      string    `json:"emptied_by"`
+	EmptiedAt      time.Time `json:"emptied_at"`
+}
+
+type binAlertResponse struct {
+	MachineID   int       `json:"machine_id"`
+	Level       string    `json:"level"`
+	FillPercent int       `json:"fill_percent"`
+	RaisedAt    time.Time `json:"raised_at"`
+}
+
+type connectivityEventResponse struct {
+	Connectivity string    `json:"connectivity"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+type configResponse struct {
+	MachineID      int                   `json:"machine_id"`
+	Version        int                   `json:"version"`
+	Document       domain.ConfigDocument `json:"document"`
+	RolledBackFrom *int                  `json:"rolled_back_from"`
+	CreatedBy      string                `json:"created_by"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+type machineConfigResponse struct {
+	Version  int                   `json:"version"`
+	Document domain.ConfigDocument `json:"document"`
+}
+
+type tokenResponse struct {
+	Token      string     `json:"token"`
+	MachineID  int        `json:"machine_id"`
+	ExpiringAt *time.Time `json:"expiring_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new machine HTTP handler for operators.
+// It expects auth.LoggedInMiddleware and auth.AdminOnlyMiddleware to run before it.
+//   - GET /machines - returns every machine as JSON, including whether it is online and its last heartbeat.
+//   - POST /machines - registers an active machine and returns it as JSON.
+//     name is a required form value; location is optional.
+//   - PUT /machines/{machineID} - updates the machine and returns it as JSON.
+//     name and status (active, maintenance or retired) are required form values; location is optional.
+//   - POST /machines/{machineID}/tokens - issues a new machine token and returns it as JSON.
+//     expiring_at is an optional RFC 3339 form value; tokens without one never expire.
+//   - GET /machines/{machineID}/connectivity - returns the latest times the machine went online or offline
+//     as JSON, newest first.
+//   - GET /machines/bins - returns the bins of the machines in service as JSON, fullest first.
+//   - GET /machines/bins/alerts - returns the latest nearly full and full bin alerts as JSON, newest first.
+//   - PUT /machines/{machineID}/bin - sets the bin's capacity and returns the bin as JSON.
+//     capacity, in containers, is a required form value.
+//   - POST /machines/{machineID}/bin/empty - records the logged-in collector emptying the bin
+//     and returns the collection as JSON.
+//   - GET /machines/{machineID}/bin/collections - returns the latest times the bin was emptied as JSON,
+//     newest first.
+//   - GET /machines/{machineID}/config - returns the machine's current configuration as JSON.
+//   - PUT /machines/{machineID}/config - saves a new configuration version and returns it as JSON.
+//     The body is a JSON document with the settings to change; the rest are kept from the current version.
+//   - GET /machines/{machineID}/config/history - returns the latest configuration versions as JSON, newest first.
+//   - POST /machines/{machineID}/config/rollback - saves a copy of an earlier configuration version
+//     as the newest one and returns it as JSON. version is a required form value.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getMachines))
+	r.Post("/", httputils.HandlerFunc(handler.createMachine))
+	r.Put("/{machineID}", httputils.HandlerFunc(handler.updateMachine))
+	r.Post("/{machineID}/tokens", httputils.HandlerFunc(handler.issueToken))
+	r.Get("/{machineID}/connectivity", httputils.HandlerFunc(handler.getConnectivityHistory))
+	r.Get("/bins", httputils.HandlerFunc(handler.getBins))
+	r.Get("/bins/alerts", httputils.HandlerFunc(handler.getBinAlerts))
+	r.Put("/{machineID}/bin", httputils.HandlerFunc(handler.setBinCapacity))
+	r.Post("/{machineID}/bin/empty", httputils.HandlerFunc(handler.emptyBin))
+	r.Get("/{machineID}/bin/collections", httputils.HandlerFunc(handler.getBinCollections))
+	r.Get("/{machineID}/config", httputils.HandlerFunc(handler.getConfig))
+	r.Put("/{machineID}/config", httputils.HandlerFunc(handler.updateConfig))
+	r.Get("/{machineID}/config/history", httputils.HandlerFunc(handler.getConfigHistory))
+	r.Post("/{machineID}/config/rollback", httputils.HandlerFunc(handler.rollbackConfig))
+
+	handler.Router = r
+	return handler
+}
+
+// NewHeartbeatHTTPHandler creates a new HTTP handler for machines to report that they are running.
+// It expects apitoken.ValidTokenMiddleware to run before it.
+//   - POST /machines/heartbeat - records a heartbeat from the token's machine.
+//     firmware_version, uptime_seconds, free_heap_bytes and wifi_rssi (in dBm) are required form values.
+//     bin_fill_percent is an optional form value for machines with a bin fill-level sensor.
+func NewHeartbeatHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Post("/", httputils.HandlerFunc(handler.recordHeartbeat))
+
+	handler.Router = r
+	return handler
+}
+
+// NewConfigHTTPHandler creates a new HTTP handler for machines to fetch their configuration.
+// It expects apitoken.ValidTokenMiddleware to run before it.
+//   - GET /machines/config - returns the token's machine's current configuration and its version as JSON.
+//     The version is also sent as the ETag, so a machine that already applied it gets 304 Not Modified
+//     by sending it back in If-None-Match. Machines that were never configured get 404 Not Found.
+func NewConfigHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.fetchConfig))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getMachines(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machines, err := h.s.GetMachines()
+	if err != nil {
+		oplog.Error("failed to get machines", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]machineResponse, 0, len(machines))
+	for _, m := range machines {
+		res = append(res, h.toMachineResponse(m))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) createMachine(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	m, err := h.s.CreateMachine(r.FormValue("name"), r.FormValue("location"))
+	if err != nil {
+		var invalidErr *domain.InvalidMachineError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid machine", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to create machine", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, h.toMachineResponse(m))
+}
+
+func (h *HTTPHandler) updateMachine(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	status, err := domain.NewStatus(r.FormValue("status"))
+	if err != nil {
+		oplog.Error("invalid machine status", logging.ErrAttr(err))
+
+		w.TryWriteProblem(
+			&oplog,
+			http.StatusBadRequest,
+			httputils.CodeInvalidRequest,
+			"status has to be one of active, maintenance or retired",
+		)
+
+		return
+	}
+
+	m, err := h.s.UpdateMachine(machineID, r.FormValue("name"), r.FormValue("location"), status)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		var invalidErr *domain.InvalidMachineError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid machine", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to update machine", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, h.toMachineResponse(m))
+}
+
+func (h *HTTPHandler) issueToken(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	var expiringAt *time.Time
+
+	if v := r.FormValue("expiring_at"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			oplog.Error("invalid expiring_at", logging.ErrAttr(err))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusBadRequest,
+				httputils.CodeInvalidRequest,
+				"expiring_at has to be an RFC 3339 timestamp",
+			)
+
+			return
+		}
+
+		expiringAt = &parsed
+	}
+
+	token, err := h.s.IssueToken(machineID, expiringAt)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrMachineRetired) {
+			oplog.Error("machine is retired", slog.Int("machine_id", machineID))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeMachineRetired, "machine is retired")
+
+			return
+		}
+
+		oplog.Error("failed to issue token", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, tokenResponse{
+		Token:      token,
+		MachineID:  machineID,
+		ExpiringAt: expiringAt,
+	})
+}
+
+func (h *HTTPHandler) recordHeartbeat(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID := apitoken.MachineIDFromCtx(r.Context())
+
+	badRequest := func(msg string) {
+		oplog.Error("invalid heartbeat", slog.String("reason", msg), slog.Int("machine_id", machineID))
+
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, msg)
+	}
+
+	uptimeSeconds, err := strconv.ParseInt(r.FormValue("uptime_seconds"), 10, 64)
+	if err != nil {
+		badRequest("uptime_seconds has to be an integer")
+		return
+	}
+
+	freeHeapBytes, err := strconv.ParseInt(r.FormValue("free_heap_bytes"), 10, 64)
+	if err != nil {
+		badRequest("free_heap_bytes has to be an integer")
+		return
+	}
+
+	wifiRSSI, err := strconv.Atoi(r.FormValue("wifi_rssi"))
+	if err != nil {
+		badRequest("wifi_rssi has to be an integer")
+		return
+	}
+
+	var binFillPercent *int
+
+	if v := r.FormValue("bin_fill_percent"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			badRequest("bin_fill_percent has to be an integer")
+			return
+		}
+
+		binFillPercent = &parsed
+	}
+
+	err = h.s.RecordHeartbeat(
+		machineID,
+		r.FormValue("firmware_version"),
+		uptimeSeconds,
+		freeHeapBytes,
+		wifiRSSI,
+		binFillPercent,
+	)
+	if err != nil {
+		var invalidErr *domain.InvalidHeartbeatError
+		if errors.As(err, &invalidErr) {
+			badRequest(invalidErr.Reason)
+			return
+		}
+
+		oplog.Error("failed to record heartbeat", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) getConnectivityHistory(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	events, err := h.s.GetConnectivityHistory(machineID)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		oplog.Error("failed to get connectivity history", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]connectivityEventResponse, 0, len(events))
+	for _, e := range events {
+		res = append(res, connectivityEventResponse{
+			Connectivity: e.Connectivity.String(),
+			OccurredAt:   e.OccurredAt,
+		})
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) getBins(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	bins, err := h.s.GetBins()
+	if err != nil {
+		oplog.Error("failed to get bins", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]binResponse, 0, len(bins))
+	for _, b := range bins {
+		res = append(res, h.toBinResponse(b))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) getBinAlerts(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	alerts, err := h.s.GetBinAlerts()
+	if err != nil {
+		oplog.Error("failed to get bin alerts", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]binAlertResponse, 0, len(alerts))
+	for _, a := range alerts {
+		res = append(res, binAlertResponse{
+			MachineID:   a.MachineID,
+			Level:       a.Level.String(),
+			FillPercent: a.FillPercent,
+			RaisedAt:    a.RaisedAt,
+		})
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) setBinCapacity(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	capacity, err := strconv.Atoi(r.FormValue("capacity"))
+	if err != nil {
+		oplog.Error("invalid bin capacity", logging.ErrAttr(err))
+
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, "capacity has to be an integer")
+
+		return
+	}
+
+	b, err := h.s.SetBinCapacity(machineID, capacity)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		var invalidErr *domain.InvalidMachineError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid bin capacity", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to set bin capacity", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, h.toBinResponse(b))
+}
+
+func (h *HTTPHandler) emptyBin(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	c, err := h.s.EmptyBin(machineID, auth.UIDFromCtx(r.Context()))
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		oplog.Error("failed to empty bin", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, toBinCollectionResponse(c))
+}
+
+func (h *HTTPHandler) getBinCollections(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	collections, err := h.s.GetBinCollections(machineID)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		oplog.Error("failed to get bin collections", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]binCollectionResponse, 0, len(collections))
+	for _, c := range collections {
+		res = append(res, toBinCollectionResponse(c))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) getConfig(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	c, err := h.s.GetConfig(machineID)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrMachineNotConfigured) {
+			oplog.Error("machine has no configuration", slog.Int("machine_id", machineID))
+
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineNotConfigured, "machine has no configuration")
+
+			return
+		}
+
+		oplog.Error("failed to get config", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toConfigResponse(c))
+}
+
+func (h *HTTPHandler) updateConfig(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigDocumentSize))
+	if err != nil {
+		oplog.Error("failed to read config document", logging.ErrAttr(err))
+
+		w.TryWriteProblem(
+			&oplog,
+			http.StatusBadRequest,
+			httputils.CodeInvalidRequest,
+			fmt.Sprintf("document can't be larger than %d bytes", maxConfigDocumentSize),
+		)
+
+		return
+	}
+
+	c, err := h.s.UpdateConfig(machineID, patch, auth.UIDFromCtx(r.Context()))
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		var invalidErr *domain.InvalidConfigError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid config", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to update config", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, toConfigResponse(c))
+}
+
+func (h *HTTPHandler) getConfigHistory(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	history, err := h.s.GetConfigHistory(machineID)
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		oplog.Error("failed to get config history", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]configResponse, 0, len(history))
+	for _, c := range history {
+		res = append(res, toConfigResponse(c))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) rollbackConfig(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID, err := strconv.Atoi(chi.URLParam(r, "machineID"))
+	if err != nil {
+		oplog.Error("failed to convert machine id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+		return
+	}
+
+	version, err := strconv.Atoi(r.FormValue("version"))
+	if err != nil {
+		oplog.Error("invalid config version", logging.ErrAttr(err))
+
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, "version has to be an integer")
+
+		return
+	}
+
+	c, err := h.s.RollbackConfig(machineID, version, auth.UIDFromCtx(r.Context()))
+	if err != nil {
+		if errors.Is(err, ErrMachineDoesNotExist) {
+			oplog.Error("machine not found", slog.Int("machine_id", machineID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineDoesNotExist, "machine not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrConfigVersionDoesNotExist) {
+			oplog.Error("config version not found", slog.Int("machine_id", machineID), slog.Int("version", version))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusNotFound,
+				codeConfigVersionDoesNotExist,
+				"config version does not exist",
+			)
+
+			return
+		}
+
+		oplog.Error("failed to roll back config", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, toConfigResponse(c))
+}
+
+func (h *HTTPHandler) fetchConfig(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	machineID := apitoken.MachineIDFromCtx(r.Context())
+
+	c, err := h.s.GetConfig(machineID)
+	if err != nil {
+		if errors.Is(err, ErrMachineNotConfigured) {
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMachineNotConfigured, "machine has no configuration")
+
+			return
+		}
+
+		oplog.Error("failed to get config", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d"`, c.Version)
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, machineConfigResponse{
+		Version:  c.Version,
+		Document: c.Document,
+	})
+}
+
+func toConfigResponse(c domain.ConfigVersion) configResponse {
+	return configResponse{
+		MachineID:      c.MachineID,
+		Version:        c.Version,
+		Document:       c.Document,
+		RolledBackFrom: c.RolledBackFrom,
+		CreatedBy:      c.CreatedBy,
+		CreatedAt:      c.CreatedAt,
+	}
+}
+
+func (h *HTTPHandler) toBinResponse(b domain.Bin) binResponse {
+	return binResponse{
+		MachineID:         b.MachineID,
+		Capacity:          b.Capacity,
+		EstimatedItems:    b.EstimatedItems(),
+		FillPercent:       b.FillPercent(),
+		Level:             h.s.BinLevel(b).String(),
+		EmptiedAt:         b.EmptiedAt,
+		SensorFillPercent: b.SensorFillPercent,
+		SensorReportedAt:  b.SensorReportedAt,
+	}
+}
+
+func toBinCollectionResponse(c domain.BinCollection) binCollectionResponse {
+	return binCollectionResponse{
+		MachineID:      c.MachineID,
+		EstimatedItems: c.EstimatedItems,
+		EmptiedBy:      c.EmptiedBy,
+		EmptiedAt:      c.EmptiedAt,
+	}
+}
+
+func (h *HTTPHandler) toMachineResponse(m domain.Machine) machineResponse {
+	res := machineResponse{
+		ID:           m.ID,
+		Name:         m.Name,
+		Location:     m.Location,
+		Status:       m.Status.String(),
+		Connectivity: h.s.Connectivity(m).String(),
+		CreatedAt:    m.CreatedAt,
+	}
+
+	if hb := m.LastHeartbeat; hb != nil {
+		res.LastSeenAt = &hb.ReceivedAt
+		res.LastHeartbeat = &heartbeatResponse{
+			FirmwareVersion: hb.FirmwareVersion,
+			UptimeSeconds:   hb.UptimeSeconds,
+			FreeHeapBytes:   hb.FreeHeapBytes,
+			WiFiRSSI:        hb.WiFiRSSI,
+			ReceivedAt:      hb.ReceivedAt,
+		}
+	}
+
+	return res
+}
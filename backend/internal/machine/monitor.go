@@ -0,0 +1,71 @@
+package machine
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+)
+
+// Monitor periodically records machines that stopped sending heartbeats as offline
+// and raises alerts for bins that are filling up.
+type Monitor struct {
+	s        *Service
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewMonitor(s *Service, interval time.Duration, logger *slog.Logger) *Monitor {
+	return &Monitor{s: s, interval: interval, logger: logger}
+}
+
+// Run checks once immediately and then every interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) check() {
+	m.checkConnectivity()
+	m.checkBins()
+}
+
+func (m *Monitor) checkConnectivity() {
+	n, err := m.s.MarkOfflineMachines()
+	if err != nil {
+		m.logger.Error("failed to mark offline machines", logging.ErrAttr(err))
+		return
+	}
+
+	if n > 0 {
+		m.logger.Warn("machines went offline", slog.Int("count", n))
+	}
+}
+
+func (m *Monitor) checkBins() {
+	alerts, err := m.s.RaiseBinAlerts()
+	if err != nil {
+		m.logger.Error("failed to raise bin alerts", logging.ErrAttr(err))
+		return
+	}
+
+	for _, a := range alerts {
+		m.logger.Warn(
+			"bin needs collecting",
+			slog.Int("machine_id", a.MachineID),
+			slog.String("level", a.Level.String()),
+			slog.Int("fill_percent", a.FillPercent),
+		)
+	}
+}
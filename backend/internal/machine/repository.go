@@ -0,0 +1,56 @@
+package machine
+
+import (
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+)
+
+type Repository interface {
+	GetMachines() ([]domain.Machine, error)
+	// GetMachine returns ErrMachineDoesNotExist if there is no machine with the given id.
+	GetMachine(id int) (*domain.Machine, error)
+	CreateMachine(m domain.Machine) (int, error)
+	// UpdateMachine overwrites the machine's name, location and status, reporting whether it exists.
+	UpdateMachine(m domain.Machine) (bool, error)
+	// CreateToken stores a machine token for the machine under the token's hash. A nil expiringAt never expires.
+	CreateToken(tokenHash string, machineID int, expiringAt *time.Time, createdAt time.Time) error
+	// GetHeartbeat returns the machine's latest heartbeat, reporting false if it never sent one.
+	GetHeartbeat(machineID int) (domain.Heartbeat, bool, error)
+	// GetAllHeartbeats returns every machine's latest heartbeat by machine id.
+	GetAllHeartbeats() (map[int]domain.Heartbeat, error)
+	// SaveHeartbeat replaces the machine's latest heartbeat.
+	SaveHeartbeat(h domain.Heartbeat) error
+	// GetStaleHeartbeats returns the heartbeats received before receivedBefore of machines
+	// whose latest connectivity event is still online.
+	GetStaleHeartbeats(receivedBefore time.Time) ([]domain.Heartbeat, error)
+	// GetLatestConnectivity reports false if the machine has no connectivity events.
+	GetLatestConnectivity(machineID int) (domain.Connectivity, bool, error)
+	AddConnectivityEvent(e domain.ConnectivityEvent) error
+	// GetConnectivityEvents returns the machine's latest connectivity events, newest first.
+	GetConnectivityEvents(machineID int, limit int) ([]domain.ConnectivityEvent, error)
+	// GetBin returns ErrMachineDoesNotExist if there is no machine with the given id.
+	GetBin(machineID int) (domain.Bin, error)
+	// GetBins returns the bins of every machine that isn't retired.
+	GetBins() ([]domain.Bin, error)
+	// SetBinCapacity reports whether the machine exists.
+	SetBinCapacity(machineID int, capacity int) (bool, error)
+	SetBinEmptied(machineID int, emptiedAt time.Time) error
+	SetBinSensorReading(machineID int, fillPercent int, reportedAt time.Time) error
+	AddBinCollection(c domain.BinCollection) error
+	// GetBinCollections returns the machine's latest collections, newest first.
+	GetBinCollections(machineID int, limit int) ([]domain.BinCollection, error)
+	// HasBinAlertSince reports whether an alert of the level was raised for the machine at or after since.
+	// A nil since checks every alert.
+	HasBinAlertSince(machineID int, level domain.BinLevel, since *time.Time) (bool, error)
+	AddBinAlert(a domain.BinAlert) error
+	// GetBinAlerts returns the latest alerts of every machine, newest first.
+	GetBinAlerts(limit int) ([]domain.BinAlert, error)
+	// GetLatestConfig returns the machine's current configuration, reporting false if it was never configured.
+	GetLatestConfig(machineID int) (domain.ConfigVersion, bool, error)
+	// GetConfig reports false if the machine has no configuration with the given version.
+	GetConfig(machineID int, version int) (domain.ConfigVersion, bool, error)
+	// GetConfigHistory returns the machine's latest configuration versions, newest first.
+	GetConfigHistory(machineID int, limit int) ([]domain.ConfigVersion, error)
+	AddConfig(c domain.ConfigVersion) error
+}
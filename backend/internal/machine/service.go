@@ -0,0 +1,562 @@
+package machine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	apitokendomain "github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+)
+
+const (
+	connectivityHistoryLimit = 100
+	binCollectionsLimit      = 100
+	binAlertsLimit           = 100
+	configHistoryLimit       = 100
+)
+
+var (
+	ErrMachineDoesNotExist       = fmt.Errorf("machine does not exist")
+	ErrMachineRetired            = fmt.Errorf("machine is retired")
+	ErrMachineNotConfigured      = fmt.Errorf("machine has no configuration")
+	ErrConfigVersionDoesNotExist = fmt.Errorf("config version does not exist")
+)
+
+type Config struct {
+	// HeartbeatGracePeriod is how long a machine can go without a heartbeat before it counts as offline.
+	HeartbeatGracePeriod time.Duration
+	// BinAlertThreshold is the fraction of a bin's capacity at which it counts as nearly full.
+	BinAlertThreshold float64
+}
+
+type Service struct {
+	r   Repository
+	uow db.UnitOfWork[Repository]
+	tg  apitoken.TokenGenerator
+	cfg Config
+}
+
+func NewService(r Repository, uow db.UnitOfWork[Repository], tg apitoken.TokenGenerator, cfg Config) *Service {
+	return &Service{r: r, uow: uow, tg: tg, cfg: cfg}
+}
+
+// GetMachines returns every machine along with its latest heartbeat.
+func (s *Service) GetMachines() ([]domain.Machine, error) {
+	machines, err := s.r.GetMachines()
+	if err != nil {
+		return nil, fmt.Errorf("GetMachines(): failed to get machines: %w", err)
+	}
+
+	heartbeats, err := s.r.GetAllHeartbeats()
+	if err != nil {
+		return nil, fmt.Errorf("GetMachines(): failed to get heartbeats: %w", err)
+	}
+
+	for i := range machines {
+		if h, ok := heartbeats[machines[i].ID]; ok {
+			machines[i].LastHeartbeat = &h
+		}
+	}
+
+	return machines, nil
+}
+
+// Connectivity returns whether the machine counts as online right now.
+func (s *Service) Connectivity(m domain.Machine) domain.Connectivity {
+	return m.ConnectivityAt(time.Now(), s.cfg.HeartbeatGracePeriod)
+}
+
+// CreateMachine registers an active machine. Invalid fields return a *domain.InvalidMachineError.
+func (s *Service) CreateMachine(name string, location string) (domain.Machine, error) {
+	m, err := domain.NewMachine(0, name, location, domain.StatusActive, time.Now())
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("CreateMachine(): %w", err)
+	}
+
+	m.ID, err = s.r.CreateMachine(m)
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("CreateMachine(): failed to create machine: %w", err)
+	}
+
+	return m, nil
+}
+
+// UpdateMachine changes the machine's details. Retiring a machine stops its tokens from being accepted;
+// setting it back to active or maintenance lets them through again.
+func (s *Service) UpdateMachine(id int, name string, location string, status domain.Status) (domain.Machine, error) {
+	current, err := s.r.GetMachine(id)
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("UpdateMachine(): failed to get machine with id %d: %w", id, err)
+	}
+
+	m, err := domain.NewMachine(id, name, location, status, current.CreatedAt)
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("UpdateMachine(): %w", err)
+	}
+
+	ok, err := s.r.UpdateMachine(m)
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("UpdateMachine(): failed to update machine: %w", err)
+	}
+
+	if !ok {
+		return domain.Machine{}, fmt.Errorf("UpdateMachine(): %w with id %d", ErrMachineDoesNotExist, id)
+	}
+
+	h, seen, err := s.r.GetHeartbeat(id)
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("UpdateMachine(): failed to get heartbeat: %w", err)
+	}
+
+	if seen {
+		m.LastHeartbeat = &h
+	}
+
+	return m, nil
+}
+
+// IssueToken creates a new machine token for the machine and returns it. This is the only time the token
+// is handed out, so it has to be copied onto the machine straight away. A nil expiringAt never expires.
+func (s *Service) IssueToken(machineID int, expiringAt *time.Time) (string, error) {
+	m, err := s.r.GetMachine(machineID)
+	if err != nil {
+		return "", fmt.Errorf("IssueToken(): failed to get machine with id %d: %w", machineID, err)
+	}
+
+	if m.Status == domain.StatusRetired {
+		return "", fmt.Errorf("IssueToken(): machine with id %d: %w", machineID, ErrMachineRetired)
+	}
+
+	token, err := s.tg.Generate()
+	if err != nil {
+		return "", fmt.Errorf("IssueToken(): failed to generate token: %w", err)
+	}
+
+	if err = s.r.CreateToken(apitokendomain.HashToken(token), machineID, expiringAt, time.Now()); err != nil {
+		return "", fmt.Errorf("IssueToken(): failed to create token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RecordHeartbeat stores the machine's heartbeat, recording that it came back online if it was offline.
+// binFillPercent is the reading of the machine's bin sensor, if it has one.
+// Invalid values return a *domain.InvalidHeartbeatError.
+func (s *Service) RecordHeartbeat(
+	machineID int,
+	firmwareVersion string,
+	uptimeSeconds int64,
+	freeHeapBytes int64,
+	wifiRSSI int,
+	binFillPercent *int,
+) error {
+	now := time.Now()
+
+	h, err := domain.NewHeartbeat(machineID, firmwareVersion, uptimeSeconds, freeHeapBytes, wifiRSSI, now)
+	if err != nil {
+		return fmt.Errorf("RecordHeartbeat(): %w", err)
+	}
+
+	if binFillPercent != nil {
+		if err = domain.ValidateBinFillPercent(*binFillPercent); err != nil {
+			return fmt.Errorf("RecordHeartbeat(): %w", err)
+		}
+	}
+
+	err = s.uow.Do(func(r Repository) error {
+		last, seen, err := r.GetHeartbeat(machineID)
+		if err != nil {
+			return fmt.Errorf("failed to get last heartbeat: %w", err)
+		}
+
+		latest, ok, err := r.GetLatestConnectivity(machineID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest connectivity: %w", err)
+		}
+
+		online := ok && latest == domain.ConnectivityOnline
+
+		// The monitor hasn't noticed the machine went offline yet, so the gap would otherwise be lost.
+		if online && seen && last.IsStaleAt(now, s.cfg.HeartbeatGracePeriod) {
+			offlineAt := last.OfflineAt(s.cfg.HeartbeatGracePeriod)
+			if err = addConnectivityEvent(r, machineID, domain.ConnectivityOffline, offlineAt); err != nil {
+				return err
+			}
+
+			online = false
+		}
+
+		if !online {
+			if err = addConnectivityEvent(r, machineID, domain.ConnectivityOnline, now); err != nil {
+				return err
+			}
+		}
+
+		if err = r.SaveHeartbeat(h); err != nil {
+			return fmt.Errorf("failed to save heartbeat: %w", err)
+		}
+
+		if binFillPercent != nil {
+			if err = r.SetBinSensorReading(machineID, *binFillPercent, now); err != nil {
+				return fmt.Errorf("failed to save bin sensor reading: %w", err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("RecordHeartbeat(): %w", err)
+	}
+
+	return nil
+}
+
+// MarkOfflineMachines records that the machines which stopped sending heartbeats went offline,
+// returning how many did. They count as offline from the moment their grace period ran out.
+func (s *Service) MarkOfflineMachines() (int, error) {
+	var n int
+
+	err := s.uow.Do(func(r Repository) error {
+		stale, err := r.GetStaleHeartbeats(time.Now().Add(-s.cfg.HeartbeatGracePeriod))
+		if err != nil {
+			return fmt.Errorf("failed to get stale heartbeats: %w", err)
+		}
+
+		for _, h := range stale {
+			offlineAt := h.OfflineAt(s.cfg.HeartbeatGracePeriod)
+			if err = addConnectivityEvent(r, h.MachineID, domain.ConnectivityOffline, offlineAt); err != nil {
+				return err
+			}
+		}
+
+		n = len(stale)
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("MarkOfflineMachines(): %w", err)
+	}
+
+	return n, nil
+}
+
+// GetConnectivityHistory returns the machine's latest times going online or offline, newest first.
+func (s *Service) GetConnectivityHistory(machineID int) ([]domain.ConnectivityEvent, error) {
+	if _, err := s.r.GetMachine(machineID); err != nil {
+		return nil, fmt.Errorf("GetConnectivityHistory(): failed to get machine with id %d: %w", machineID, err)
+	}
+
+	events, err := s.r.GetConnectivityEvents(machineID, connectivityHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetConnectivityHistory(): failed to get connectivity events: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetBins returns the bins of the machines in service, fullest first so that collections can be planned.
+func (s *Service) GetBins() ([]domain.Bin, error) {
+	bins, err := s.r.GetBins()
+	if err != nil {
+		return nil, fmt.Errorf("GetBins(): failed to get bins: %w", err)
+	}
+
+	sort.SliceStable(bins, func(i, j int) bool {
+		return bins[i].FillPercent() > bins[j].FillPercent()
+	})
+
+	return bins, nil
+}
+
+// BinLevel returns whether the bin is full or nearly so.
+func (s *Service) BinLevel(b domain.Bin) domain.BinLevel {
+	return b.Level(s.cfg.BinAlertThreshold)
+}
+
+// SetBinCapacity changes how many containers fit in the machine's bin.
+// A capacity that isn't positive returns a *domain.InvalidMachineError.
+func (s *Service) SetBinCapacity(machineID int, capacity int) (domain.Bin, error) {
+	if err := domain.ValidateBinCapacity(capacity); err != nil {
+		return domain.Bin{}, fmt.Errorf("SetBinCapacity(): %w", err)
+	}
+
+	ok, err := s.r.SetBinCapacity(machineID, capacity)
+	if err != nil {
+		return domain.Bin{}, fmt.Errorf("SetBinCapacity(): failed to set bin capacity: %w", err)
+	}
+
+	if !ok {
+		return domain.Bin{}, fmt.Errorf("SetBinCapacity(): %w with id %d", ErrMachineDoesNotExist, machineID)
+	}
+
+	b, err := s.r.GetBin(machineID)
+	if err != nil {
+		return domain.Bin{}, fmt.Errorf("SetBinCapacity(): failed to get bin: %w", err)
+	}
+
+	return b, nil
+}
+
+// EmptyBin records the collector emptying the machine's bin, which starts its fill level again from zero.
+func (s *Service) EmptyBin(machineID int, userID string) (domain.BinCollection, error) {
+	var c domain.BinCollection
+
+	err := s.uow.Do(func(r Repository) error {
+		b, err := r.GetBin(machineID)
+		if err != nil {
+			return fmt.Errorf("failed to get bin of machine with id %d: %w", machineID, err)
+		}
+
+		c = domain.BinCollection{
+			MachineID:      machineID,
+			EstimatedItems: b.EstimatedItems(),
+			EmptiedBy:      userID,
+			EmptiedAt:      time.Now(),
+		}
+
+		if err = r.AddBinCollection(c); err != nil {
+			return fmt.Errorf("failed to add bin collection: %w", err)
+		}
+
+		if err = r.SetBinEmptied(machineID, c.EmptiedAt); err != nil {
+			return fmt.Errorf("failed to set bin emptied: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return domain.BinCollection{}, fmt.Errorf("EmptyBin(): %w", err)
+	}
+
+	return c, nil
+}
+
+// GetBinCollections returns the latest times the machine's bin was emptied, newest first.
+func (s *Service) GetBinCollections(machineID int) ([]domain.BinCollection, error) {
+	if _, err := s.r.GetMachine(machineID); err != nil {
+		return nil, fmt.Errorf("GetBinCollections(): failed to get machine with id %d: %w", machineID, err)
+	}
+
+	collections, err := s.r.GetBinCollections(machineID, binCollectionsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetBinCollections(): failed to get bin collections: %w", err)
+	}
+
+	return collections, nil
+}
+
+// IsBinFull reports whether the machine's bin has no room left.
+func (s *Service) IsBinFull(machineID int) (bool, error) {
+	b, err := s.r.GetBin(machineID)
+	if err != nil {
+		return false, fmt.Errorf("IsBinFull(): failed to get bin of machine with id %d: %w", machineID, err)
+	}
+
+	return s.BinLevel(b) == domain.BinLevelFull, nil
+}
+
+// RaiseBinAlerts raises an alert for every bin that became nearly full or full since it was last emptied,
+// returning the new alerts.
+func (s *Service) RaiseBinAlerts() ([]domain.BinAlert, error) {
+	var alerts []domain.BinAlert
+
+	err := s.uow.Do(func(r Repository) error {
+		bins, err := r.GetBins()
+		if err != nil {
+			return fmt.Errorf("failed to get bins: %w", err)
+		}
+
+		now := time.Now()
+
+		for _, b := range bins {
+			level := s.BinLevel(b)
+			if level == domain.BinLevelOK {
+				continue
+			}
+
+			raised, err := r.HasBinAlertSince(b.MachineID, level, b.EmptiedAt)
+			if err != nil {
+				return fmt.Errorf("failed to check bin alerts of machine with id %d: %w", b.MachineID, err)
+			}
+
+			if raised {
+				continue
+			}
+
+			a := domain.BinAlert{
+				MachineID:   b.MachineID,
+				Level:       level,
+				FillPercent: b.FillPercent(),
+				RaisedAt:    now,
+			}
+
+			if err = r.AddBinAlert(a); err != nil {
+				return fmt.Errorf("failed to add bin alert: %w", err)
+			}
+
+			alerts = append(alerts, a)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("RaiseBinAlerts(): %w", err)
+	}
+
+	return alerts, nil
+}
+
+// GetBinAlerts returns the latest bin alerts of every machine, newest first.
+func (s *Service) GetBinAlerts() ([]domain.BinAlert, error) {
+	alerts, err := s.r.GetBinAlerts(binAlertsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetBinAlerts(): failed to get bin alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+func addConnectivityEvent(r Repository, machineID int, c domain.Connectivity, occurredAt time.Time) error {
+	if err := r.AddConnectivityEvent(domain.ConnectivityEvent{
+		MachineID:    machineID,
+		Connectivity: c,
+		OccurredAt:   occurredAt,
+	}); err != nil {
+		return fmt.Errorf("failed to record machine %d going %s: %w", machineID, c, err)
+	}
+
+	return nil
+}
+
+// GetConfig returns the machine's current configuration, or ErrMachineNotConfigured if it was never given one.
+func (s *Service) GetConfig(machineID int) (domain.ConfigVersion, error) {
+	if _, err := s.r.GetMachine(machineID); err != nil {
+		return domain.ConfigVersion{}, fmt.Errorf("GetConfig(): failed to get machine with id %d: %w", machineID, err)
+	}
+
+	c, ok, err := s.r.GetLatestConfig(machineID)
+	if err != nil {
+		return domain.ConfigVersion{}, fmt.Errorf("GetConfig(): failed to get latest config: %w", err)
+	}
+
+	if !ok {
+		return domain.ConfigVersion{}, fmt.Errorf("GetConfig(): %w", ErrMachineNotConfigured)
+	}
+
+	return c, nil
+}
+
+// UpdateConfig saves a new version of the machine's configuration, made of the current one with the settings
+// in the JSON patch overwritten. Machines that were never configured start from the camera module's defaults.
+// An invalid patch returns a *domain.InvalidConfigError.
+func (s *Service) UpdateConfig(machineID int, patch []byte, userID string) (domain.ConfigVersion, error) {
+	var c domain.ConfigVersion
+
+	err := s.uow.Do(func(r Repository) error {
+		if _, err := r.GetMachine(machineID); err != nil {
+			return fmt.Errorf("failed to get machine with id %d: %w", machineID, err)
+		}
+
+		latest, ok, err := r.GetLatestConfig(machineID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest config: %w", err)
+		}
+
+		base := domain.DefaultConfigDocument()
+		if ok {
+			base = latest.Document
+		}
+
+		doc, err := domain.MergeConfigDocument(base, patch)
+		if err != nil {
+			return err
+		}
+
+		c = domain.ConfigVersion{
+			MachineID: machineID,
+			Version:   latest.Version + 1,
+			Document:  doc,
+			CreatedBy: userID,
+			CreatedAt: time.Now(),
+		}
+
+		if err = r.AddConfig(c); err != nil {
+			return fmt.Errorf("failed to add config: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return domain.ConfigVersion{}, fmt.Errorf("UpdateConfig(): %w", err)
+	}
+
+	return c, nil
+}
+
+// RollbackConfig saves a copy of an earlier version of the machine's configuration as its newest version,
+// so the history keeps the versions that were rolled back.
+func (s *Service) RollbackConfig(machineID int, version int, userID string) (domain.ConfigVersion, error) {
+	var c domain.ConfigVersion
+
+	err := s.uow.Do(func(r Repository) error {
+		if _, err := r.GetMachine(machineID); err != nil {
+			return fmt.Errorf("failed to get machine with id %d: %w", machineID, err)
+		}
+
+		target, ok, err := r.GetConfig(machineID, version)
+		if err != nil {
+			return fmt.Errorf("failed to get config version %d: %w", version, err)
+		}
+
+		if !ok {
+			return ErrConfigVersionDoesNotExist
+		}
+
+		latest, _, err := r.GetLatestConfig(machineID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest config: %w", err)
+		}
+
+		c = domain.ConfigVersion{
+			MachineID:      machineID,
+			Version:        latest.Version + 1,
+			Document:       target.Document,
+			RolledBackFrom: &target.Version,
+			CreatedBy:      userID,
+			CreatedAt:      time.Now(),
+		}
+
+		if err = r.AddConfig(c); err != nil {
+			return fmt.Errorf("failed to add config: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return domain.ConfigVersion{}, fmt.Errorf("RollbackConfig(): %w", err)
+	}
+
+	return c, nil
+}
+
+// GetConfigHistory returns the latest versions of the machine's configuration, newest first.
+func (s *Service) GetConfigHistory(machineID int) ([]domain.ConfigVersion, error) {
+	if _, err := s.r.GetMachine(machineID); err != nil {
+		return nil, fmt.Errorf("GetConfigHistory(): failed to get machine with id %d: %w", machineID, err)
+	}
+
+	history, err := s.r.GetConfigHistory(machineID, configHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetConfigHistory(): failed to get config history: %w", err)
+	}
+
+	return history, nil
+}
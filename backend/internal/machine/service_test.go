@@ -0,0 +1,541 @@
+package machine_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/machine"
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction"
+	transactiondomain "github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
+)
+
+const gracePeriod = time.Minute
+
+func newService(dbHandle *db.DB) *machine.Service {
+	return machine.NewService(
+		machine.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) machine.Repository {
+			return machine.NewSQLRepository(q)
+		}),
+		apitoken.NewRandomTokenGenerator(),
+		machine.Config{
+			HeartbeatGracePeriod: gracePeriod,
+			BinAlertThreshold:    0.8,
+		},
+	)
+}
+
+func createMachine(t *testing.T, s *machine.Service) domain.Machine {
+	t.Helper()
+
+	m, err := s.CreateMachine("Test machine", "Test location")
+	if err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	return m
+}
+
+func recordHeartbeat(t *testing.T, s *machine.Service, machineID int) {
+	t.Helper()
+
+	if err := s.RecordHeartbeat(machineID, "1.0.0", 60, 100000, -60, nil); err != nil {
+		t.Fatalf("failed to record heartbeat: %v", err)
+	}
+}
+
+// backdateHeartbeat makes the machine, which has only come online once, look like it did so and sent its latest
+// heartbeat at the given time.
+func backdateHeartbeat(t *testing.T, dbHandle *db.DB, machineID int, receivedAt time.Time) {
+	t.Helper()
+
+	for _, query := range []string{
+		"UPDATE machine_heartbeats SET received_at = ? WHERE machine_id = ?",
+		"UPDATE machine_connectivity_events SET occurred_at = ? WHERE machine_id = ?",
+	} {
+		if _, err := dbHandle.Exec(query, receivedAt, machineID); err != nil {
+			t.Fatalf("failed to backdate heartbeat: %v", err)
+		}
+	}
+}
+
+func getMachine(t *testing.T, s *machine.Service, machineID int) domain.Machine {
+	t.Helper()
+
+	machines, err := s.GetMachines()
+	if err != nil {
+		t.Fatalf("failed to get machines: %v", err)
+	}
+
+	for _, m := range machines {
+		if m.ID == machineID {
+			return m
+		}
+	}
+
+	t.Fatalf("machine %d is not listed", machineID)
+
+	return domain.Machine{}
+}
+
+// assertConnectivityHistory checks the machine's connectivity events, oldest first.
+func assertConnectivityHistory(t *testing.T, s *machine.Service, machineID int, want ...domain.Connectivity) {
+	t.Helper()
+
+	events, err := s.GetConnectivityHistory(machineID)
+	if err != nil {
+		t.Fatalf("failed to get connectivity history: %v", err)
+	}
+
+	got := make([]domain.Connectivity, len(events))
+	for i, e := range events {
+		got[len(events)-1-i] = e.Connectivity
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got connectivity history %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got connectivity history %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCreateMachine(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		mName    string
+		location string
+		wantErr  bool
+	}{
+		{name: "valid", mName: "Lobby machine", location: "Main building"},
+		{name: "no location", mName: "Lobby machine"},
+		{name: "no name", mName: "", location: "Main building", wantErr: true},
+		{name: "name too long", mName: strings.Repeat("a", 256), wantErr: true},
+		{name: "location too long", mName: "Lobby machine", location: strings.Repeat("a", 256), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			s := newService(dbHandle)
+
+			m, err := s.CreateMachine(tc.mName, tc.location)
+
+			if tc.wantErr {
+				var invalid *domain.InvalidMachineError
+				if !errors.As(err, &invalid) {
+					t.Errorf("got error %v, want an *InvalidMachineError", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to create machine: %v", err)
+			}
+
+			got := getMachine(t, s, m.ID)
+			if got.Name != tc.mName || got.Location != tc.location || got.Status != domain.StatusActive {
+				t.Errorf("got machine %+v, want an active machine named %q at %q", got, tc.mName, tc.location)
+			}
+
+			// A machine that never sent a heartbeat is offline.
+			if c := s.Connectivity(got); c != domain.ConnectivityOffline {
+				t.Errorf("got connectivity %s, want %s", c, domain.ConnectivityOffline)
+			}
+		})
+	}
+}
+
+func TestIssueToken(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+	tokens := apitoken.NewService(apitoken.NewSQLRepository(dbHandle))
+
+	m := createMachine(t, s)
+
+	token, err := s.IssueToken(m.ID, nil)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	machineID, ok, err := tokens.GetMachineID(token)
+	if err != nil || !ok {
+		t.Fatalf("failed to resolve issued token: ok=%v, err=%v", ok, err)
+	}
+
+	if machineID != m.ID {
+		t.Errorf("got machine %d, want %d", machineID, m.ID)
+	}
+
+	if _, err = s.IssueToken(m.ID+1, nil); !errors.Is(err, machine.ErrMachineDoesNotExist) {
+		t.Errorf("unknown machine: got error %v, want %v", err, machine.ErrMachineDoesNotExist)
+	}
+
+	if _, err = s.UpdateMachine(m.ID, m.Name, m.Location, domain.StatusRetired); err != nil {
+		t.Fatalf("failed to retire machine: %v", err)
+	}
+
+	if _, err = s.IssueToken(m.ID, nil); !errors.Is(err, machine.ErrMachineRetired) {
+		t.Errorf("retired machine: got error %v, want %v", err, machine.ErrMachineRetired)
+	}
+}
+
+func TestRecordHeartbeatRejectsInvalidValues(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+	tooFull := 101
+
+	for _, tc := range []struct {
+		name            string
+		firmwareVersion string
+		uptimeSeconds   int64
+		freeHeapBytes   int64
+		wifiRSSI        int
+		binFillPercent  *int
+	}{
+		{name: "no firmware version", firmwareVersion: "", wifiRSSI: -60},
+		{name: "firmware version too long", firmwareVersion: strings.Repeat("1", 65), wifiRSSI: -60},
+		{name: "negative uptime", firmwareVersion: "1.0.0", uptimeSeconds: -1, wifiRSSI: -60},
+		{name: "negative free heap", firmwareVersion: "1.0.0", freeHeapBytes: -1, wifiRSSI: -60},
+		{name: "positive rssi", firmwareVersion: "1.0.0", wifiRSSI: 1},
+		{name: "rssi too weak", firmwareVersion: "1.0.0", wifiRSSI: -128},
+		{name: "bin fill over 100", firmwareVersion: "1.0.0", wifiRSSI: -60, binFillPercent: &tooFull},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := s.RecordHeartbeat(
+				m.ID,
+				tc.firmwareVersion,
+				tc.uptimeSeconds,
+				tc.freeHeapBytes,
+				tc.wifiRSSI,
+				tc.binFillPercent,
+			)
+
+			var invalid *domain.InvalidHeartbeatError
+			if !errors.As(err, &invalid) {
+				t.Errorf("got error %v, want an *InvalidHeartbeatError", err)
+			}
+		})
+	}
+
+	// None of them count as the machine being alive.
+	if got := getMachine(t, s, m.ID); got.LastHeartbeat != nil {
+		t.Errorf("got heartbeat %+v, want none", got.LastHeartbeat)
+	}
+}
+
+func TestConnectivityTransitions(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+
+	recordHeartbeat(t, s, m.ID)
+
+	if c := s.Connectivity(getMachine(t, s, m.ID)); c != domain.ConnectivityOnline {
+		t.Errorf("after a heartbeat: got connectivity %s, want %s", c, domain.ConnectivityOnline)
+	}
+
+	// Heartbeats while online don't add events.
+	recordHeartbeat(t, s, m.ID)
+	assertConnectivityHistory(t, s, m.ID, domain.ConnectivityOnline)
+
+	backdateHeartbeat(t, dbHandle, m.ID, time.Now().Add(-2*gracePeriod))
+
+	if c := s.Connectivity(getMachine(t, s, m.ID)); c != domain.ConnectivityOffline {
+		t.Errorf("past the grace period: got connectivity %s, want %s", c, domain.ConnectivityOffline)
+	}
+
+	n, err := s.MarkOfflineMachines()
+	if err != nil {
+		t.Fatalf("failed to mark offline machines: %v", err)
+	}
+
+	if n != 1 {
+		t.Errorf("got %d machines marked offline, want 1", n)
+	}
+
+	// Machines already marked offline aren't marked again.
+	if n, err = s.MarkOfflineMachines(); err != nil || n != 0 {
+		t.Errorf("marking again: got %d, err=%v, want 0", n, err)
+	}
+
+	recordHeartbeat(t, s, m.ID)
+	assertConnectivityHistory(
+		t,
+		s,
+		m.ID,
+		domain.ConnectivityOnline,
+		domain.ConnectivityOffline,
+		domain.ConnectivityOnline,
+	)
+}
+
+func TestHeartbeatAfterUnnoticedOutage(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+
+	recordHeartbeat(t, s, m.ID)
+
+	lastSeen := time.Now().Add(-time.Hour)
+	backdateHeartbeat(t, dbHandle, m.ID, lastSeen)
+
+	// The monitor never ran, so the heartbeat has to record the outage itself.
+	recordHeartbeat(t, s, m.ID)
+	assertConnectivityHistory(
+		t,
+		s,
+		m.ID,
+		domain.ConnectivityOnline,
+		domain.ConnectivityOffline,
+		domain.ConnectivityOnline,
+	)
+
+	events, err := s.GetConnectivityHistory(m.ID)
+	if err != nil {
+		t.Fatalf("failed to get connectivity history: %v", err)
+	}
+
+	// Newest first, so the offline event is the second one.
+	// Postgres keeps timestamps to the microsecond.
+	if want := lastSeen.Add(gracePeriod); events[1].OccurredAt.Sub(want).Abs() > time.Millisecond {
+		t.Errorf("got offline at %v, want %v", events[1].OccurredAt, want)
+	}
+}
+
+// insertItems adds count containers to a new transaction on the machine.
+func insertItems(t *testing.T, dbHandle *db.DB, machineID int, id transactiondomain.TransactionID, count int) {
+	t.Helper()
+
+	r := transaction.NewSQLRepository(dbHandle)
+	itemID := dbtest.CreateItem(t, dbHandle, 10)
+	now := time.Now()
+
+	if err := r.StartTransaction(id, machineID, now); err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	for i := 0; i < count; i++ {
+		if err := r.AddItemToTransaction(id, itemID, nil, now); err != nil {
+			t.Fatalf("failed to add item: %v", err)
+		}
+	}
+}
+
+func TestSetBinCapacity(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+
+	for _, tc := range []struct {
+		name      string
+		machineID int
+		capacity  int
+		wantErr   bool
+		want      error
+	}{
+		{name: "positive capacity", machineID: m.ID, capacity: 10},
+		{name: "zero capacity", machineID: m.ID, capacity: 0, wantErr: true},
+		{name: "negative capacity", machineID: m.ID, capacity: -1, wantErr: true},
+		{name: "unknown machine", machineID: m.ID + 1, capacity: 10, wantErr: true, want: machine.ErrMachineDoesNotExist},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := s.SetBinCapacity(tc.machineID, tc.capacity)
+
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("failed to set bin capacity: %v", err)
+				}
+
+				if b.Capacity != tc.capacity {
+					t.Errorf("got capacity %d, want %d", b.Capacity, tc.capacity)
+				}
+
+				return
+			}
+
+			if tc.want != nil {
+				if !errors.Is(err, tc.want) {
+					t.Errorf("got error %v, want %v", err, tc.want)
+				}
+
+				return
+			}
+
+			var invalid *domain.InvalidMachineError
+			if !errors.As(err, &invalid) {
+				t.Errorf("got error %v, want an *InvalidMachineError", err)
+			}
+		})
+	}
+}
+
+func TestBinFillsUpAndEmpties(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+	dbtest.CreateUser(t, dbHandle, "collector")
+
+	if _, err := s.SetBinCapacity(m.ID, 10); err != nil {
+		t.Fatalf("failed to set bin capacity: %v", err)
+	}
+
+	insertItems(t, dbHandle, m.ID, "6a1f0c3e-8b2d-4e5f-9a7c-1d3b5e7f9a20", 8)
+
+	alerts, err := s.RaiseBinAlerts()
+	if err != nil {
+		t.Fatalf("failed to raise bin alerts: %v", err)
+	}
+
+	if len(alerts) != 1 || alerts[0].Level != domain.BinLevelNearlyFull || alerts[0].FillPercent != 80 {
+		t.Errorf("got alerts %+v, want one for the bin being 80%% full", alerts)
+	}
+
+	// An alert is only raised once per level.
+	if alerts, err = s.RaiseBinAlerts(); err != nil || len(alerts) != 0 {
+		t.Errorf("raising again: got alerts %+v, err=%v, want none", alerts, err)
+	}
+
+	insertItems(t, dbHandle, m.ID, "6a1f0c3e-8b2d-4e5f-9a7c-1d3b5e7f9a21", 2)
+
+	full, err := s.IsBinFull(m.ID)
+	if err != nil || !full {
+		t.Errorf("at capacity: got full=%v, err=%v, want full", full, err)
+	}
+
+	if alerts, err = s.RaiseBinAlerts(); err != nil || len(alerts) != 1 || alerts[0].Level != domain.BinLevelFull {
+		t.Errorf("at capacity: got alerts %+v, err=%v, want one for the bin being full", alerts, err)
+	}
+
+	c, err := s.EmptyBin(m.ID, "collector")
+	if err != nil {
+		t.Fatalf("failed to empty bin: %v", err)
+	}
+
+	if c.EstimatedItems != 10 {
+		t.Errorf("got %d items collected, want 10", c.EstimatedItems)
+	}
+
+	if full, err = s.IsBinFull(m.ID); err != nil || full {
+		t.Errorf("after emptying: got full=%v, err=%v, want not full", full, err)
+	}
+
+	collections, err := s.GetBinCollections(m.ID)
+	if err != nil || len(collections) != 1 {
+		t.Errorf("got collections %+v, err=%v, want one", collections, err)
+	}
+}
+
+func TestHeartbeatBinSensorReading(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+
+	if _, err := s.SetBinCapacity(m.ID, 10); err != nil {
+		t.Fatalf("failed to set bin capacity: %v", err)
+	}
+
+	insertItems(t, dbHandle, m.ID, "6a1f0c3e-8b2d-4e5f-9a7c-1d3b5e7f9a22", 2)
+
+	// The sensor knows better than the count, e.g. because the bin was filled by hand.
+	fillPercent := 100
+	if err := s.RecordHeartbeat(m.ID, "1.0.0", 60, 100000, -60, &fillPercent); err != nil {
+		t.Fatalf("failed to record heartbeat: %v", err)
+	}
+
+	full, err := s.IsBinFull(m.ID)
+	if err != nil || !full {
+		t.Errorf("got full=%v, err=%v, want full", full, err)
+	}
+}
+
+func TestConfigVersions(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	m := createMachine(t, s)
+
+	const userID = "operator"
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	if _, err := s.GetConfig(m.ID); !errors.Is(err, machine.ErrMachineNotConfigured) {
+		t.Errorf("before any update: got error %v, want %v", err, machine.ErrMachineNotConfigured)
+	}
+
+	// The defaults have no hosts, so the first update has to set them.
+	if _, err := s.UpdateConfig(m.ID, []byte(`{"camera": {"brightness": 1}}`), userID); err == nil {
+		t.Error("incomplete first config: got no error, want one")
+	}
+
+	for i, patch := range []string{
+		`{"backend_server": {"hostname": "backend.example.com"}, "mqtt": {"host": "mqtt.example.com"}}`,
+		`{"camera": {"brightness": 1}}`,
+		`{"camera": {"brightness": 2}}`,
+	} {
+		c, err := s.UpdateConfig(m.ID, []byte(patch), userID)
+		if err != nil {
+			t.Fatalf("failed to update config: %v", err)
+		}
+
+		if c.Version != i+1 {
+			t.Errorf("got version %d, want %d", c.Version, i+1)
+		}
+	}
+
+	if _, err := s.UpdateConfig(m.ID, []byte(`{"camera": {"brightness": 3}}`), userID); err == nil {
+		t.Error("invalid patch: got no error, want one")
+	}
+
+	rolledBack, err := s.RollbackConfig(m.ID, 2, userID)
+	if err != nil {
+		t.Fatalf("failed to roll back config: %v", err)
+	}
+
+	if rolledBack.Version != 4 || rolledBack.RolledBackFrom == nil || *rolledBack.RolledBackFrom != 2 {
+		t.Errorf("got version %d rolled back from %v, want version 4 rolled back from 2",
+			rolledBack.Version, rolledBack.RolledBackFrom)
+	}
+
+	current, err := s.GetConfig(m.ID)
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	if current.Version != 4 || current.Document.Camera.Brightness != 1 {
+		t.Errorf("got version %d with brightness %d, want version 4 with brightness 1",
+			current.Version, current.Document.Camera.Brightness)
+	}
+
+	if _, err = s.RollbackConfig(m.ID, 5, userID); !errors.Is(err, machine.ErrConfigVersionDoesNotExist) {
+		t.Errorf("unknown version: got error %v, want %v", err, machine.ErrConfigVersionDoesNotExist)
+	}
+
+	history, err := s.GetConfigHistory(m.ID)
+	if err != nil {
+		t.Fatalf("failed to get config history: %v", err)
+	}
+
+	// The rejected updates left nothing behind.
+	if len(history) != 4 {
+		t.Fatalf("got %d versions, want 4", len(history))
+	}
+
+	if history[0].Version != 4 {
+		t.Errorf("got version %d first, want the rollback", history[0].Version)
+	}
+}
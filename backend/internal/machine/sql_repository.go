@@ -0,0 +1,744 @@
+package machine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	apitokendomain "github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/machine/domain"
+)
+
+type machine struct {
+	MachineID int       `db:"machine_id"`
+	Name      string    `db:"name"`
+	Location  string    `db:"location"`
+	Status    string    `db:"status"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (m machine) toDomain() (domain.Machine, error) {
+	status, err := domain.NewStatus(m.Status)
+	if err != nil {
+		return domain.Machine{}, fmt.Errorf("failed to parse status: %w", err)
+	}
+
+	return domain.Machine{
+		ID:        m.MachineID,
+		Name:      m.Name,
+		Location:  m.Location,
+		Status:    status,
+		CreatedAt: m.CreatedAt,
+	}, nil
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (mr *SQLRepository) GetMachines() ([]domain.Machine, error) {
+	var raw []machine
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, name, location, status, created_at
+		FROM
+			machines
+		ORDER BY
+			machine_id
+	`); err != nil {
+		return nil, fmt.Errorf("GetMachines(): failed to execute query: %w", err)
+	}
+
+	machines := make([]domain.Machine, 0, len(raw))
+	for _, m := range raw {
+		converted, err := m.toDomain()
+		if err != nil {
+			return nil, fmt.Errorf("GetMachines(): %w", err)
+		}
+
+		machines = append(machines, converted)
+	}
+
+	return machines, nil
+}
+
+func (mr *SQLRepository) GetMachine(id int) (*domain.Machine, error) {
+	var raw machine
+	if err := mr.db.Get(&raw, `
+		SELECT
+			machine_id, name, location, status, created_at
+		FROM
+			machines
+		WHERE
+			machine_id = ?
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMachineDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetMachine(): failed to execute query: %w", err)
+	}
+
+	m, err := raw.toDomain()
+	if err != nil {
+		return nil, fmt.Errorf("GetMachine(): %w", err)
+	}
+
+	return &m, nil
+}
+
+func (mr *SQLRepository) CreateMachine(m domain.Machine) (int, error) {
+	var id int
+	if err := mr.db.Get(&id, `
+		INSERT INTO
+			machines (name, location, status, created_at)
+		VALUES
+			(?, ?, ?, ?)
+		RETURNING
+			machine_id
+	`, m.Name, m.Location, m.Status, m.CreatedAt); err != nil {
+		return 0, fmt.Errorf("CreateMachine(): failed to execute query: %w", err)
+	}
+
+	return id, nil
+}
+
+func (mr *SQLRepository) UpdateMachine(m domain.Machine) (bool, error) {
+	res, err := mr.db.Exec(`
+		UPDATE
+			machines
+		SET
+			name = ?,
+			location = ?,
+			status = ?
+		WHERE
+			machine_id = ?
+	`, m.Name, m.Location, m.Status, m.ID)
+	if err != nil {
+		return false, fmt.Errorf("UpdateMachine(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("UpdateMachine(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (mr *SQLRepository) CreateToken(
+	tokenHash string,
+	machineID int,
+	expiringAt *time.Time,
+	createdAt time.Time,
+) error {
+	if _, err := mr.db.Exec(`
+		INSERT INTO
+			api_tokens (api_token_id, kind, machine_id, is_hashed, expiring_at, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+	`, tokenHash, apitokendomain.TokenKindMachine, machineID, true, expiringAt, createdAt); err != nil {
+		return fmt.Errorf("CreateToken(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+type heartbeat struct {
+	MachineID       int       `db:"machine_id"`
+	FirmwareVersion string    `db:"firmware_version"`
+	UptimeSeconds   int64     `db:"uptime_seconds"`
+	FreeHeapBytes   int64     `db:"free_heap_bytes"`
+	WiFiRSSI        int       `db:"wifi_rssi"`
+	ReceivedAt      time.Time `db:"received_at"`
+}
+
+func (h heartbeat) toDomain() domain.Heartbeat {
+	return domain.Heartbeat{
+		MachineID:       h.MachineID,
+		FirmwareVersion: h.FirmwareVersion,
+		UptimeSeconds:   h.UptimeSeconds,
+		FreeHeapBytes:   h.FreeHeapBytes,
+		WiFiRSSI:        h.WiFiRSSI,
+		ReceivedAt:      h.ReceivedAt,
+	}
+}
+
+type connectivityEvent struct {
+	MachineID    int       `db:"machine_id"`
+	Connectivity string    `db:"connectivity"`
+	OccurredAt   time.Time `db:"occurred_at"`
+}
+
+func (mr *SQLRepository) GetHeartbeat(machineID int) (domain.Heartbeat, bool, error) {
+	var raw heartbeat
+	if err := mr.db.Get(&raw, `
+		SELECT
+			machine_id, firmware_version, uptime_seconds, free_heap_bytes, wifi_rssi, received_at
+		FROM
+			machine_heartbeats
+		WHERE
+			machine_id = ?
+	`, machineID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Heartbeat{}, false, nil
+		}
+
+		return domain.Heartbeat{}, false, fmt.Errorf("GetHeartbeat(): failed to execute query: %w", err)
+	}
+
+	return raw.toDomain(), true, nil
+}
+
+func (mr *SQLRepository) GetAllHeartbeats() (map[int]domain.Heartbeat, error) {
+	var raw []heartbeat
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, firmware_version, uptime_seconds, free_heap_bytes, wifi_rssi, received_at
+		FROM
+			machine_heartbeats
+	`); err != nil {
+		return nil, fmt.Errorf("GetAllHeartbeats(): failed to execute query: %w", err)
+	}
+
+	heartbeats := make(map[int]domain.Heartbeat, len(raw))
+	for _, h := range raw {
+		heartbeats[h.MachineID] = h.toDomain()
+	}
+
+	return heartbeats, nil
+}
+
+func (mr *SQLRepository) SaveHeartbeat(h domain.Heartbeat) error {
+	if _, err := mr.db.Exec(`
+		INSERT INTO
+			machine_heartbeats (machine_id, firmware_version, uptime_seconds, free_heap_bytes, wifi_rssi, received_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+		ON CONFLICT (machine_id) DO UPDATE SET
+			firmware_version = excluded.firmware_version,
+			uptime_seconds = excluded.uptime_seconds,
+			free_heap_bytes = excluded.free_heap_bytes,
+			wifi_rssi = excluded.wifi_rssi,
+			received_at = excluded.received_at
+	`, h.MachineID, h.FirmwareVersion, h.UptimeSeconds, h.FreeHeapBytes, h.WiFiRSSI, h.ReceivedAt); err != nil {
+		return fmt.Errorf("SaveHeartbeat(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *SQLRepository) GetStaleHeartbeats(receivedBefore time.Time) ([]domain.Heartbeat, error) {
+	var raw []heartbeat
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, firmware_version, uptime_seconds, free_heap_bytes, wifi_rssi, received_at
+		FROM
+			machine_heartbeats
+		WHERE
+			received_at < ?
+			AND (
+				SELECT
+					connectivity
+				FROM
+					machine_connectivity_events
+				WHERE
+					machine_connectivity_events.machine_id = machine_heartbeats.machine_id
+				ORDER BY
+					occurred_at DESC, machine_connectivity_event_id DESC
+				LIMIT 1
+			) = ?
+	`, receivedBefore, domain.ConnectivityOnline); err != nil {
+		return nil, fmt.Errorf("GetStaleHeartbeats(): failed to execute query: %w", err)
+	}
+
+	heartbeats := make([]domain.Heartbeat, 0, len(raw))
+	for _, h := range raw {
+		heartbeats = append(heartbeats, h.toDomain())
+	}
+
+	return heartbeats, nil
+}
+
+func (mr *SQLRepository) GetLatestConnectivity(machineID int) (domain.Connectivity, bool, error) {
+	var raw string
+	if err := mr.db.Get(&raw, `
+		SELECT
+			connectivity
+		FROM
+			machine_connectivity_events
+		WHERE
+			machine_id = ?
+		ORDER BY
+			occurred_at DESC, machine_connectivity_event_id DESC
+		LIMIT 1
+	`, machineID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+
+		return "", false, fmt.Errorf("GetLatestConnectivity(): failed to execute query: %w", err)
+	}
+
+	c, err := domain.NewConnectivity(raw)
+	if err != nil {
+		return "", false, fmt.Errorf("GetLatestConnectivity(): failed to parse connectivity: %w", err)
+	}
+
+	return c, true, nil
+}
+
+func (mr *SQLRepository) AddConnectivityEvent(e domain.ConnectivityEvent) error {
+	if _, err := mr.db.Exec(`
+		INSERT INTO
+			machine_connectivity_events (machine_id, connectivity, occurred_at)
+		VALUES
+			(?, ?, ?)
+	`, e.MachineID, e.Connectivity, e.OccurredAt); err != nil {
+		return fmt.Errorf("AddConnectivityEvent(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *SQLRepository) GetConnectivityEvents(machineID int, limit int) ([]domain.ConnectivityEvent, error) {
+	var raw []connectivityEvent
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, connectivity, occurred_at
+		FROM
+			machine_connectivity_events
+		WHERE
+			machine_id = ?
+		ORDER BY
+			occurred_at DESC, machine_connectivity_event_id DESC
+		LIMIT ?
+	`, machineID, limit); err != nil {
+		return nil, fmt.Errorf("GetConnectivityEvents(): failed to execute query: %w", err)
+	}
+
+	events := make([]domain.ConnectivityEvent, 0, len(raw))
+	for _, e := range raw {
+		c, err := domain.NewConnectivity(e.Connectivity)
+		if err != nil {
+			return nil, fmt.Errorf("GetConnectivityEvents(): failed to parse connectivity: %w", err)
+		}
+
+		events = append(events, domain.ConnectivityEvent{
+			MachineID:    e.MachineID,
+			Connectivity: c,
+			OccurredAt:   e.OccurredAt,
+		})
+	}
+
+	return events, nil
+}
+
+type bin struct {
+	MachineID         int           `db:"machine_id"`
+	Capacity          int           `db:"bin_capacity"`
+	EmptiedAt         sql.NullTime  `db:"bin_emptied_at"`
+	SensorFillPercent sql.NullInt64 `db:"bin_sensor_fill_percent"`
+	SensorReportedAt  sql.NullTime  `db:"bin_sensor_reported_at"`
+	ItemsSinceEmptied int           `db:"items_since_emptied"`
+	ItemsSinceSensor  int           `db:"items_since_sensor"`
+}
+
+func (b bin) toDomain() domain.Bin {
+	res := domain.Bin{
+		MachineID:         b.MachineID,
+		Capacity:          b.Capacity,
+		ItemsSinceEmptied: b.ItemsSinceEmptied,
+		ItemsSinceSensor:  b.ItemsSinceSensor,
+	}
+
+	if b.EmptiedAt.Valid {
+		res.EmptiedAt = &b.EmptiedAt.Time
+	}
+
+	if b.SensorFillPercent.Valid && b.SensorReportedAt.Valid {
+		fillPercent := int(b.SensorFillPercent.Int64)
+		res.SensorFillPercent = &fillPercent
+		res.SensorReportedAt = &b.SensorReportedAt.Time
+	}
+
+	return res
+}
+
+type binCollection struct {
+	MachineID      int       `db:"machine_id"`
+	EstimatedItems int       `db:"estimated_items"`
+	EmptiedBy      string    `db:"emptied_by"`
+	EmptiedAt      time.Time `db:"emptied_at"`
+}
+
+type binAlert struct {
+	MachineID   int       `db:"machine_id"`
+	Level       string    `db:"level"`
+	FillPercent int       `db:"fill_percent"`
+	RaisedAt    time.Time `db:"raised_at"`
+}
+
+// binQuery selects the machines' bins, counting the containers added to their transactions
+// after the bin was emptied and after the sensor's latest reading.
+const binQuery = `
+	SELECT
+		machine_id,
+		bin_capacity,
+		bin_emptied_at,
+		bin_sensor_fill_percent,
+		bin_sensor_reported_at,
+		(
+			SELECT
+				COUNT(*)
+			FROM
+				transaction_items
+			INNER JOIN
+				transactions ON transactions.transaction_id = transaction_items.transaction_id
+			WHERE
+				transactions.machine_id = machines.machine_id
+				AND (machines.bin_emptied_at IS NULL OR transaction_items.created_at > machines.bin_emptied_at)
+		) AS items_since_emptied,
+		(
+			SELECT
+				COUNT(*)
+			FROM
+				transaction_items
+			INNER JOIN
+				transactions ON transactions.transaction_id = transaction_items.transaction_id
+			WHERE
+				transactions.machine_id = machines.machine_id
+				AND machines.bin_sensor_reported_at IS NOT NULL
+				AND transaction_items.created_at > machines.bin_sensor_reported_at
+		) AS items_since_sensor
+	FROM
+		machines
+`
+
+func (mr *SQLRepository) GetBin(machineID int) (domain.Bin, error) {
+	var raw bin
+	if err := mr.db.Get(&raw, binQuery+`
+		WHERE
+			machine_id = ?
+	`, machineID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Bin{}, ErrMachineDoesNotExist
+		}
+
+		return domain.Bin{}, fmt.Errorf("GetBin(): failed to execute query: %w", err)
+	}
+
+	return raw.toDomain(), nil
+}
+
+func (mr *SQLRepository) GetBins() ([]domain.Bin, error) {
+	var raw []bin
+	if err := mr.db.Select(&raw, binQuery+`
+		WHERE
+			status != ?
+		ORDER BY
+			machine_id
+	`, domain.StatusRetired); err != nil {
+		return nil, fmt.Errorf("GetBins(): failed to execute query: %w", err)
+	}
+
+	bins := make([]domain.Bin, 0, len(raw))
+	for _, b := range raw {
+		bins = append(bins, b.toDomain())
+	}
+
+	return bins, nil
+}
+
+func (mr *SQLRepository) SetBinCapacity(machineID int, capacity int) (bool, error) {
+	res, err := mr.db.Exec(`
+		UPDATE
+			machines
+		SET
+			bin_capacity = ?
+		WHERE
+			machine_id = ?
+	`, capacity, machineID)
+	if err != nil {
+		return false, fmt.Errorf("SetBinCapacity(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("SetBinCapacity(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (mr *SQLRepository) SetBinEmptied(machineID int, emptiedAt time.Time) error {
+	if _, err := mr.db.Exec(`
+		UPDATE
+			machines
+		SET
+			bin_emptied_at = ?
+		WHERE
+			machine_id = ?
+	`, emptiedAt, machineID); err != nil {
+		return fmt.Errorf("SetBinEmptied(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *SQLRepository) SetBinSensorReading(machineID int, fillPercent int, reportedAt time.Time) error {
+	if _, err := mr.db.Exec(`
+		UPDATE
+			machines
+		SET
+			bin_sensor_fill_percent = ?,
+			bin_sensor_reported_at = ?
+		WHERE
+			machine_id = ?
+	`, fillPercent, reportedAt, machineID); err != nil {
+		return fmt.Errorf("SetBinSensorReading(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *SQLRepository) AddBinCollection(c domain.BinCollection) error {
+	if _, err := mr.db.Exec(`
+		INSERT INTO
+			bin_collections (machine_id, estimated_items, emptied_by, emptied_at)
+		VALUES
+			(?, ?, ?, ?)
+	`, c.MachineID, c.EstimatedItems, c.EmptiedBy, c.EmptiedAt); err != nil {
+		return fmt.Errorf("AddBinCollection(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *SQLRepository) GetBinCollections(machineID int, limit int) ([]domain.BinCollection, error) {
+	var raw []binCollection
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, estimated_items, emptied_by, emptied_at
+		FROM
+			bin_collections
+		WHERE
+			machine_id = ?
+		ORDER BY
+			emptied_at DESC, bin_collection_id DESC
+		LIMIT ?
+	`, machineID, limit); err != nil {
+		return nil, fmt.Errorf("GetBinCollections(): failed to execute query: %w", err)
+	}
+
+	collections := make([]domain.BinCollection, 0, len(raw))
+	for _, c := range raw {
+		collections = append(collections, domain.BinCollection(c))
+	}
+
+	return collections, nil
+}
+
+func (mr *SQLRepository) HasBinAlertSince(machineID int, level domain.BinLevel, since *time.Time) (bool, error) {
+	var after time.Time
+	if since != nil {
+		after = *since
+	}
+
+	var count int
+	if err := mr.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			bin_alerts
+		WHERE
+			machine_id = ? AND level = ? AND raised_at >= ?
+	`, machineID, level, after); err != nil {
+		return false, fmt.Errorf("HasBinAlertSince(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (mr *SQLRepository) AddBinAlert(a domain.BinAlert) error {
+	if _, err := mr.db.Exec(`
+		INSERT INTO
+			bin_alerts (machine_id, level, fill_percent, raised_at)
+		VALUES
+			(?, ?, ?, ?)
+	`, a.MachineID, a.Level, a.FillPercent, a.RaisedAt); err != nil {
+		return fmt.Errorf("AddBinAlert(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *SQLRepository) GetBinAlerts(limit int) ([]domain.BinAlert, error) {
+	var raw []binAlert
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, level, fill_percent, raised_at
+		FROM
+			bin_alerts
+		ORDER BY
+			raised_at DESC, bin_alert_id DESC
+		LIMIT ?
+	`, limit); err != nil {
+		return nil, fmt.Errorf("GetBinAlerts(): failed to execute query: %w", err)
+	}
+
+	alerts := make([]domain.BinAlert, 0, len(raw))
+	for _, a := range raw {
+		level, err := domain.NewBinLevel(a.Level)
+		if err != nil {
+			return nil, fmt.Errorf("GetBinAlerts(): failed to parse level: %w", err)
+		}
+
+		alerts = append(alerts, domain.BinAlert{
+			MachineID:   a.MachineID,
+			Level:       level,
+			FillPercent: a.FillPercent,
+			RaisedAt:    a.RaisedAt,
+		})
+	}
+
+	return alerts, nil
+}
+
+type configVersion struct {
+	MachineID      int           `db:"machine_id"`
+	Version        int           `db:"version"`
+	Document       string        `db:"document"`
+	RolledBackFrom sql.NullInt64 `db:"rolled_back_from"`
+	CreatedBy      string        `db:"created_by"`
+	CreatedAt      time.Time     `db:"created_at"`
+}
+
+func (c configVersion) toDomain() (domain.ConfigVersion, error) {
+	var doc domain.ConfigDocument
+	if err := json.Unmarshal([]byte(c.Document), &doc); err != nil {
+		return domain.ConfigVersion{}, fmt.Errorf("failed to unmarshal document of version %d: %w", c.Version, err)
+	}
+
+	res := domain.ConfigVersion{
+		MachineID: c.MachineID,
+		Version:   c.Version,
+		Document:  doc,
+		CreatedBy: c.CreatedBy,
+		CreatedAt: c.CreatedAt,
+	}
+
+	if c.RolledBackFrom.Valid {
+		v := int(c.RolledBackFrom.Int64)
+		res.RolledBackFrom = &v
+	}
+
+	return res, nil
+}
+
+func (mr *SQLRepository) GetLatestConfig(machineID int) (domain.ConfigVersion, bool, error) {
+	var raw configVersion
+	if err := mr.db.Get(&raw, `
+		SELECT
+			machine_id, version, document, rolled_back_from, created_by, created_at
+		FROM
+			machine_configs
+		WHERE
+			machine_id = ?
+		ORDER BY
+			version DESC
+		LIMIT 1
+	`, machineID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ConfigVersion{}, false, nil
+		}
+
+		return domain.ConfigVersion{}, false, fmt.Errorf("GetLatestConfig(): failed to execute query: %w", err)
+	}
+
+	c, err := raw.toDomain()
+	if err != nil {
+		return domain.ConfigVersion{}, false, fmt.Errorf("GetLatestConfig(): %w", err)
+	}
+
+	return c, true, nil
+}
+
+func (mr *SQLRepository) GetConfig(machineID int, version int) (domain.ConfigVersion, bool, error) {
+	var raw configVersion
+	if err := mr.db.Get(&raw, `
+		SELECT
+			machine_id, version, document, rolled_back_from, created_by, created_at
+		FROM
+			machine_configs
+		WHERE
+			machine_id = ? AND version = ?
+	`, machineID, version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ConfigVersion{}, false, nil
+		}
+
+		return domain.ConfigVersion{}, false, fmt.Errorf("GetConfig(): failed to execute query: %w", err)
+	}
+
+	c, err := raw.toDomain()
+	if err != nil {
+		return domain.ConfigVersion{}, false, fmt.Errorf("GetConfig(): %w", err)
+	}
+
+	return c, true, nil
+}
+
+func (mr *SQLRepository) GetConfigHistory(machineID int, limit int) ([]domain.ConfigVersion, error) {
+	var raw []configVersion
+	if err := mr.db.Select(&raw, `
+		SELECT
+			machine_id, version, document, rolled_back_from, created_by, created_at
+		FROM
+			machine_configs
+		WHERE
+			machine_id = ?
+		ORDER BY
+			version DESC
+		LIMIT ?
+	`, machineID, limit); err != nil {
+		return nil, fmt.Errorf("GetConfigHistory(): failed to execute query: %w", err)
+	}
+
+	history := make([]domain.ConfigVersion, 0, len(raw))
+	for _, r := range raw {
+		c, err := r.toDomain()
+		if err != nil {
+			return nil, fmt.Errorf("GetConfigHistory(): %w", err)
+		}
+
+		history = append(history, c)
+	}
+
+	return history, nil
+}
+
+func (mr *SQLRepository) AddConfig(c domain.ConfigVersion) error {
+	doc, err := json.Marshal(c.Document)
+	if err != nil {
+		return fmt.Errorf("AddConfig(): failed to marshal document: %w", err)
+	}
+
+	if _, err = mr.db.Exec(`
+		INSERT INTO
+			machine_configs (machine_id, version, document, rolled_back_from, created_by, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+	`, c.MachineID, c.Version, string(doc), c.RolledBackFrom, c.CreatedBy, c.CreatedAt); err != nil {
+		return fmt.Errorf("AddConfig(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
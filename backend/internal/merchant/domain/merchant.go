@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const maxNameLength = 255
+
+// InvalidMerchantError is returned when a merchant's fields are out of range. Reason is meant for whoever entered them.
+type InvalidMerchantError struct {
+	Reason string
+}
+
+func (e *InvalidMerchantError) Error() string {
+	return "invalid merchant: " + e.Reason
+}
+
+// Merchant is a partner shop that accepts vouchers. Every merchant token belongs to one, so the backend
+// knows which shop looked up or redeemed a voucher.
+type Merchant struct {
+	ID        int
+	Name      string
+	CreatedAt time.Time
+}
+
+// NewMerchant creates a new merchant, returning an *InvalidMerchantError if any field is out of range.
+func NewMerchant(id int, name string, createdAt time.Time) (Merchant, error) {
+	if name == "" || len(name) > maxNameLength {
+		return Merchant{}, &InvalidMerchantError{
+			Reason: fmt.Sprintf("name must be between 1 and %d characters long", maxNameLength),
+		}
+	}
+
+	return Merchant{
+		ID:        id,
+		Name:      name,
+		CreatedAt: createdAt,
+	}, nil
+}
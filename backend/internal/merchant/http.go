@@ -0,0 +1,160 @@
+package merchant
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/JosephJoshua/rvm/backend/internal/merchant/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeMerchantDoesNotExist = "merchant_does_not_exist"
+)
+
+type merchantResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type tokenResponse struct {
+	Token      string     `json:"token"`
+	MerchantID int        `json:"merchant_id"`
+	ExpiringAt *time.Time `json:"expiring_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new merchant HTTP handler for operators.
+// It expects auth.LoggedInMiddleware and auth.AdminOnlyMiddleware to run before it.
+//   - GET /merchants - returns every merchant as JSON.
+//   - POST /merchants - registers a merchant and returns it as JSON. name is a required form value.
+//   - POST /merchants/{merchantID}/tokens - issues a new merchant token and returns it as JSON.
+//     The token can't be retrieved again afterwards.
+//     expiring_at is an optional RFC 3339 form value; tokens without one never expire.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getMerchants))
+	r.Post("/", httputils.HandlerFunc(handler.createMerchant))
+	r.Post("/{merchantID}/tokens", httputils.HandlerFunc(handler.issueToken))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getMerchants(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	merchants, err := h.s.GetMerchants()
+	if err != nil {
+		oplog.Error("failed to get merchants", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]merchantResponse, 0, len(merchants))
+	for _, m := range merchants {
+		res = append(res, toMerchantResponse(m))
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) createMerchant(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	m, err := h.s.CreateMerchant(r.FormValue("name"))
+	if err != nil {
+		var invalidErr *domain.InvalidMerchantError
+		if errors.As(err, &invalidErr) {
+			oplog.Error("invalid merchant", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, invalidErr.Reason)
+
+			return
+		}
+
+		oplog.Error("failed to create merchant", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, toMerchantResponse(m))
+}
+
+func (h *HTTPHandler) issueToken(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	merchantID, err := strconv.Atoi(chi.URLParam(r, "merchantID"))
+	if err != nil {
+		oplog.Error("failed to convert merchant id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeMerchantDoesNotExist, "merchant not found")
+
+		return
+	}
+
+	var expiringAt *time.Time
+
+	if v := r.FormValue("expiring_at"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			oplog.Error("invalid expiring_at", logging.ErrAttr(err))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusBadRequest,
+				httputils.CodeInvalidRequest,
+				"expiring_at has to be an RFC 3339 timestamp",
+			)
+
+			return
+		}
+
+		expiringAt = &parsed
+	}
+
+	token, err := h.s.IssueToken(merchantID, expiringAt)
+	if err != nil {
+		if errors.Is(err, ErrMerchantDoesNotExist) {
+			oplog.Error("merchant not found", slog.Int("merchant_id", merchantID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeMerchantDoesNotExist, "merchant not found")
+
+			return
+		}
+
+		oplog.Error("failed to issue token", logging.ErrAttr(err), slog.Int("merchant_id", merchantID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusCreated, tokenResponse{
+		Token:      token,
+		MerchantID: merchantID,
+		ExpiringAt: expiringAt,
+	})
+}
+
+func toMerchantResponse(m domain.Merchant) merchantResponse {
+	return merchantResponse{
+		ID:        m.ID,
+		Name:      m.Name,
+		CreatedAt: m.CreatedAt,
+	}
+}
@@ -0,0 +1,17 @@
+package merchant
+
+import (
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/merchant/domain"
+)
+
+type Repository interface {
+	GetMerchants() ([]domain.Merchant, error)
+	// GetMerchant returns ErrMerchantDoesNotExist if there is no merchant with the given id.
+	GetMerchant(id int) (*domain.Merchant, error)
+	CreateMerchant(m domain.Merchant) (int, error)
+	// CreateToken stores a merchant token for the merchant under the token's hash.
+	// A nil expiringAt never expires.
+	CreateToken(tokenHash string, merchantID int, expiringAt *time.Time, createdAt time.Time) error
+}
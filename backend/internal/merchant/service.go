@@ -0,0 +1,67 @@
+package merchant
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	apitokendomain "github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/merchant/domain"
+)
+
+var (
+	ErrMerchantDoesNotExist = fmt.Errorf("merchant does not exist")
+)
+
+type Service struct {
+	r  Repository
+	tg apitoken.TokenGenerator
+}
+
+func NewService(r Repository, tg apitoken.TokenGenerator) *Service {
+	return &Service{r: r, tg: tg}
+}
+
+func (s *Service) GetMerchants() ([]domain.Merchant, error) {
+	merchants, err := s.r.GetMerchants()
+	if err != nil {
+		return nil, fmt.Errorf("GetMerchants(): failed to get merchants: %w", err)
+	}
+
+	return merchants, nil
+}
+
+// CreateMerchant registers a partner shop. Invalid fields return a *domain.InvalidMerchantError.
+func (s *Service) CreateMerchant(name string) (domain.Merchant, error) {
+	m, err := domain.NewMerchant(0, name, time.Now())
+	if err != nil {
+		return domain.Merchant{}, fmt.Errorf("CreateMerchant(): %w", err)
+	}
+
+	m.ID, err = s.r.CreateMerchant(m)
+	if err != nil {
+		return domain.Merchant{}, fmt.Errorf("CreateMerchant(): failed to create merchant: %w", err)
+	}
+
+	return m, nil
+}
+
+// IssueToken creates a new merchant token for the merchant and returns it. Only its hash is stored,
+// so this is the only time the token can be handed to the merchant. A nil expiringAt never expires.
+func (s *Service) IssueToken(merchantID int, expiringAt *time.Time) (string, error) {
+	if _, err := s.r.GetMerchant(merchantID); err != nil {
+		return "", fmt.Errorf("IssueToken(): failed to get merchant with id %d: %w", merchantID, err)
+	}
+
+	token, err := s.tg.Generate()
+	if err != nil {
+		return "", fmt.Errorf("IssueToken(): failed to generate token: %w", err)
+	}
+
+	err = s.r.CreateToken(apitokendomain.HashToken(token), merchantID, expiringAt, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("IssueToken(): failed to create token: %w", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,49 @@
+package merchant_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/merchant"
+)
+
+func TestIssueToken(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+
+	s := merchant.NewService(merchant.NewSQLRepository(dbHandle), apitoken.NewRandomTokenGenerator())
+	tokens := apitoken.NewService(apitoken.NewSQLRepository(dbHandle))
+
+	m, err := s.CreateMerchant("Corner shop")
+	if err != nil {
+		t.Fatalf("failed to create merchant: %v", err)
+	}
+
+	token, err := s.IssueToken(m.ID, nil)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	merchantID, ok, err := tokens.GetMerchantID(token)
+	if err != nil || !ok {
+		t.Fatalf("failed to resolve issued token: ok=%v, err=%v", ok, err)
+	}
+
+	if merchantID != m.ID {
+		t.Errorf("got merchant %d, want %d", merchantID, m.ID)
+	}
+
+	var stored int
+	if err = dbHandle.Get(&stored, "SELECT COUNT(*) FROM api_tokens WHERE api_token_id = ?", token); err != nil {
+		t.Fatalf("failed to look up token: %v", err)
+	}
+
+	if stored != 0 {
+		t.Error("the plaintext token was stored")
+	}
+
+	if _, err = s.IssueToken(m.ID+1, nil); !errors.Is(err, merchant.ErrMerchantDoesNotExist) {
+		t.Errorf("got error %v, want %v", err, merchant.ErrMerchantDoesNotExist)
+	}
+}
@@ -0,0 +1,110 @@
+package merchant
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	apitokendomain "github.com/JosephJoshua/rvm/backend/internal/apitoken/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/merchant/domain"
+)
+
+type merchant struct {
+	MerchantID int       `db:"merchant_id"`
+	Name       string    `db:"name"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (m merchant) toDomain() domain.Merchant {
+	return domain.Merchant{
+		ID:        m.MerchantID,
+		Name:      m.Name,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (mr *SQLRepository) GetMerchants() ([]domain.Merchant, error) {
+	var raw []merchant
+	if err := mr.db.Select(&raw, `
+		SELECT
+			merchant_id, name, created_at
+		FROM
+			merchants
+		ORDER BY
+			merchant_id
+	`); err != nil {
+		return nil, fmt.Errorf("GetMerchants(): failed to execute query: %w", err)
+	}
+
+	merchants := make([]domain.Merchant, 0, len(raw))
+	for _, m := range raw {
+		merchants = append(merchants, m.toDomain())
+	}
+
+	return merchants, nil
+}
+
+func (mr *SQLRepository) GetMerchant(id int) (*domain.Merchant, error) {
+	var raw merchant
+	if err := mr.db.Get(&raw, `
+		SELECT
+			merchant_id, name, created_at
+		FROM
+			merchants
+		WHERE
+			merchant_id = ?
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMerchantDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetMerchant(): failed to execute query: %w", err)
+	}
+
+	m := raw.toDomain()
+	return &m, nil
+}
+
+func (mr *SQLRepository) CreateMerchant(m domain.Merchant) (int, error) {
+	var id int
+	if err := mr.db.Get(&id, `
+		INSERT INTO
+			merchants (name, created_at)
+		VALUES
+			(?, ?)
+		RETURNING
+			merchant_id
+	`, m.Name, m.CreatedAt); err != nil {
+		return 0, fmt.Errorf("CreateMerchant(): failed to execute query: %w", err)
+	}
+
+	return id, nil
+}
+
+func (mr *SQLRepository) CreateToken(
+	tokenHash string,
+	merchantID int,
+	expiringAt *time.Time,
+	createdAt time.Time,
+) error {
+	if _, err := mr.db.Exec(`
+		INSERT INTO
+			api_tokens (api_token_id, kind, merchant_id, is_hashed, expiring_at, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+	`, tokenHash, apitokendomain.TokenKindMerchant, merchantID, true, expiringAt, createdAt); err != nil {
+		return fmt.Errorf("CreateToken(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
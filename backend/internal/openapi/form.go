@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// kin-openapi keeps its body decoders in a package-level registry, so ours is registered once for the process
+// rather than every time a Spec is created.
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/x-www-form-urlencoded", decodeFormBody)
+}
+
+// decodeFormBody decodes an application/x-www-form-urlencoded body into an object to validate against the schema.
+// kin-openapi's own decoder turns missing optional fields into nulls and drops the ones it can't parse, which
+// would reject every heartbeat without bin_fill_percent and report points=abc as a missing field.
+// Empty values are left out, since the handlers treat them as missing too.
+func decodeFormBody(
+	body io.Reader,
+	_ http.Header,
+	schema *openapi3.SchemaRef,
+	encFn openapi3filter.EncodingFn,
+) (any, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("decodeFormBody(): failed to read body: %w", err)
+	}
+
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("decodeFormBody(): failed to parse body: %w", err)
+	}
+
+	obj := make(map[string]any, len(values))
+
+	for name, prop := range schema.Value.Properties {
+		vs := values[name]
+		if len(vs) == 0 || vs[0] == "" {
+			continue
+		}
+
+		if !prop.Value.Type.Is("array") {
+			obj[name] = formValue(vs[0], prop.Value)
+			continue
+		}
+
+		// Arrays are sent as repeated fields, or comma-separated if their encoding doesn't explode.
+		if enc := encFn(name); enc != nil && enc.Explode != nil && !*enc.Explode {
+			vs = strings.Split(vs[0], ",")
+		}
+
+		items := make([]any, 0, len(vs))
+		for _, v := range vs {
+			items = append(items, formValue(strings.TrimSpace(v), prop.Value.Items.Value))
+		}
+
+		obj[name] = items
+	}
+
+	return obj, nil
+}
+
+// formValue converts v to the schema's type like a JSON decoder would. Values that can't be converted
+// are left as strings so that validation reports them as the wrong type.
+func formValue(v string, schema *openapi3.Schema) any {
+	switch {
+	case schema.Type.Is("integer"):
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return float64(n)
+		}
+	case schema.Type.Is("number"):
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case schema.Type.Is("boolean"):
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+
+	return v
+}
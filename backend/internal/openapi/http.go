@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// maxBodySize is the largest request body validated, other than uploads, which their handlers cap themselves.
+// It matches the JSON bodies httputils.JSONFormMiddleware accepts.
+const maxBodySize = 1 << 20
+
+type HTTPHandler struct {
+	chi.Router
+	s *Spec
+}
+
+// NewHTTPHandler creates a new HTTP handler for the API's contract.
+//   - GET /openapi.json - returns the OpenAPI document describing every route as JSON.
+func NewHTTPHandler(s *Spec) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getSpec))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getSpec(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	w.TryWriteJSON(&oplog, http.StatusOK, h.s.doc)
+}
+
+// ValidRequestMiddleware only lets through requests that match their operation in the spec.
+// Other requests get a 400, or a 404 if a path parameter is malformed, since no resource can have that id.
+// Requests for routes that aren't in the spec are left to the router.
+// It is meant to run after the route's auth middleware, if any, so that unauthenticated requests get a 401.
+func ValidRequestMiddleware(s *Spec) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return httputils.HandlerFunc(func(w httputils.ResponseWriter, r *http.Request) {
+			oplog := httplog.LogEntry(r.Context())
+
+			// StripSlashes routes /items/ to /items, but only after this runs.
+			routeReq := r.Clone(r.Context())
+			if p := routeReq.URL.Path; len(p) > 1 {
+				routeReq.URL.Path = strings.TrimSuffix(p, "/")
+			}
+
+			route, pathParams, err := s.router.FindRoute(routeReq)
+			if err != nil {
+				next.ServeHTTP(w.ResponseWriter, r)
+				return
+			}
+
+			// Uploads are checked by their handlers, which cap their size before reading them.
+			multipart := isMultipart(r)
+			if !multipart && r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w.ResponseWriter, r.Body, maxBodySize)
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					ExcludeRequestBody: multipart,
+					// Authentication is up to the auth and apitoken middlewares.
+					AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+					SkipSettingDefaults: true,
+				},
+			})
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					oplog.Error("request body is too large")
+					w.TryWriteProblem(
+						&oplog,
+						http.StatusRequestEntityTooLarge,
+						httputils.CodeRequestTooLarge,
+						"request body is too large",
+					)
+
+					return
+				}
+
+				var reqErr *openapi3filter.RequestError
+				if errors.As(err, &reqErr) && reqErr.Parameter != nil && reqErr.Parameter.In == openapi3.ParameterInPath {
+					oplog.Error("malformed path parameter", logging.ErrAttr(err))
+					w.TryWriteProblem(&oplog, http.StatusNotFound, httputils.CodeNotFound, "not found")
+
+					return
+				}
+
+				oplog.Error("request doesn't match the spec", logging.ErrAttr(err))
+				w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, problemDetail(err))
+
+				return
+			}
+
+			next.ServeHTTP(w.ResponseWriter, r)
+		})
+	}
+}
+
+// problemDetail turns a validation error into a detail fit to show the client, e.g. "points: value must be
+// an integer", leaving out the schema that kin-openapi includes.
+func problemDetail(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return "request doesn't match the API spec"
+	}
+
+	var field string
+	if reqErr.Parameter != nil {
+		field = reqErr.Parameter.Name
+	}
+
+	reason := reqErr.Reason
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		reason = schemaErr.Reason
+
+		// Missing properties are already named in the reason.
+		if ptr := schemaErr.JSONPointer(); len(ptr) > 0 && schemaErr.SchemaField != "required" {
+			field = strings.Join(ptr, ".")
+		}
+	} else if reqErr.Err != nil {
+		reason = reqErr.Err.Error()
+	}
+
+	if field == "" {
+		return reason
+	}
+
+	return field + ": " + reason
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
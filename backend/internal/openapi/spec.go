@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5"
+)
+
+// specYAML is the hand-written contract the apps and the camera module are built against.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Spec is the OpenAPI document describing every route of the API.
+type Spec struct {
+	doc    *openapi3.T
+	router routers.Router
+}
+
+// NewSpec loads and validates the embedded OpenAPI document.
+func NewSpec() (*Spec, error) {
+	return newSpec(specYAML)
+}
+
+func newSpec(data []byte) (*Spec, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("NewSpec(): failed to load spec: %w", err)
+	}
+
+	// The router validates the document before adding its operations.
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("NewSpec(): failed to create router: %w", err)
+	}
+
+	return &Spec{doc: doc, router: router}, nil
+}
+
+// CheckRoutes returns an error listing where the router and the spec disagree, so that the two can't drift
+// apart: routes that have no operation in the spec, operations that aren't routed, and operations whose path
+// parameters aren't the ones their route captures.
+func (s *Spec) CheckRoutes(routes chi.Routes) error {
+	// Paths are matched by their shape, so that a parameter named differently is reported as such
+	// rather than as two unrelated paths.
+	routed := make(map[string]*routedPath)
+
+	err := chi.Walk(routes, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// Mounted handlers serve their root with a trailing slash, which StripSlashes makes optional.
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+
+		shape := pathParamPattern.ReplaceAllString(route, "{}")
+
+		p, ok := routed[shape]
+		if !ok {
+			p = &routedPath{route: route, methods: make(map[string]bool)}
+			routed[shape] = p
+		}
+
+		p.methods[method] = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("CheckRoutes(): failed to walk routes: %w", err)
+	}
+
+	documented := make(map[string]bool)
+
+	var mismatches []string
+
+	for path, item := range s.doc.Paths.Map() {
+		shape := pathParamPattern.ReplaceAllString(path, "{}")
+		p, ok := routed[shape]
+
+		for method, op := range item.Operations() {
+			documented[method+" "+shape] = true
+
+			if !ok || !p.methods[method] {
+				mismatches = append(mismatches, method+" "+path+" is not routed")
+				continue
+			}
+
+			routeParams := paramNames(p.route)
+
+			// The parameters have to be in the same places, and the operation has to declare all of them.
+			declared := operationPathParams(item, op)
+			captured := slices.Clone(routeParams)
+			slices.Sort(captured)
+
+			if !slices.Equal(paramNames(path), routeParams) || !slices.Equal(declared, captured) {
+				mismatches = append(mismatches, fmt.Sprintf(
+					"%s %s declares path parameters %v but its route %s captures %v",
+					method,
+					path,
+					declared,
+					p.route,
+					routeParams,
+				))
+			}
+		}
+	}
+
+	for shape, p := range routed {
+		for method := range p.methods {
+			if !documented[method+" "+shape] {
+				mismatches = append(mismatches, method+" "+p.route+" is not in the spec")
+			}
+		}
+	}
+
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("CheckRoutes(): routes don't match the spec: %s", strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+// pathParamPattern matches the parameters of both chi routes and spec paths, including chi's regexp
+// constraints, e.g. {itemID} and {itemID:[0-9]+}.
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+type routedPath struct {
+	// route is the path as the router has it.
+	route   string
+	methods map[string]bool
+}
+
+// paramNames returns the names of the parameters in the path, in order.
+func paramNames(path string) []string {
+	var names []string
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		names = append(names, m[1])
+	}
+
+	return names
+}
+
+// operationPathParams returns the sorted names of the path parameters the operation declares,
+// including the ones its path item declares for all of its operations.
+func operationPathParams(item *openapi3.PathItem, op *openapi3.Operation) []string {
+	var names []string
+	for _, params := range []openapi3.Parameters{item.Parameters, op.Parameters} {
+		for _, p := range params {
+			if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+				names = append(names, p.Value.Name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const testSpecYAML = `
+openapi: 3.0.3
+info:
+  title: Test
+  version: 1.0.0
+paths:
+  /items:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        '201':
+          description: Created.
+  /items/{itemID}:
+    parameters:
+      - name: itemID
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      responses:
+        '200':
+          description: The item.
+  /items/{itemID}/barcodes/{barcode}:
+    get:
+      parameters:
+        - name: itemID
+          in: path
+          required: true
+          schema:
+            type: integer
+        - name: barcode
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: The barcode.
+`
+
+func newTestSpec(t *testing.T) *Spec {
+	t.Helper()
+
+	s, err := newSpec([]byte(testSpecYAML))
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	return s
+}
+
+func TestCheckRoutes(t *testing.T) {
+	s := newTestSpec(t)
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	for _, tc := range []struct {
+		name   string
+		routes func(r chi.Router)
+		// want are the parts the error has to mention; none means the routes match.
+		want []string
+	}{
+		{
+			name: "matching routes",
+			routes: func(r chi.Router) {
+				r.Post("/items", noop)
+				r.Get("/items/{itemID:[0-9]+}", noop)
+				r.Get("/items/{itemID}/barcodes/{barcode}", noop)
+			},
+		},
+		{
+			name: "route missing from the spec",
+			routes: func(r chi.Router) {
+				r.Post("/items", noop)
+				r.Get("/items/{itemID}", noop)
+				r.Get("/items/{itemID}/barcodes/{barcode}", noop)
+				r.Get("/machines", noop)
+			},
+			want: []string{"GET /machines is not in the spec"},
+		},
+		{
+			name: "method missing from the spec",
+			routes: func(r chi.Router) {
+				r.Post("/items", noop)
+				r.Get("/items/{itemID}", noop)
+				r.Delete("/items/{itemID}", noop)
+				r.Get("/items/{itemID}/barcodes/{barcode}", noop)
+			},
+			want: []string{"DELETE /items/{itemID} is not in the spec"},
+		},
+		{
+			name: "operation not routed",
+			routes: func(r chi.Router) {
+				r.Get("/items/{itemID}", noop)
+				r.Get("/items/{itemID}/barcodes/{barcode}", noop)
+			},
+			want: []string{"POST /items is not routed"},
+		},
+		{
+			name: "path parameter named differently",
+			routes: func(r chi.Router) {
+				r.Post("/items", noop)
+				r.Get("/items/{id}", noop)
+				r.Get("/items/{itemID}/barcodes/{barcode}", noop)
+			},
+			want: []string{"GET /items/{itemID} declares path parameters [itemID] but its route /items/{id} captures [id]"},
+		},
+		{
+			name: "path parameters swapped",
+			routes: func(r chi.Router) {
+				r.Post("/items", noop)
+				r.Get("/items/{itemID}", noop)
+				r.Get("/items/{barcode}/barcodes/{itemID}", noop)
+			},
+			want: []string{"GET /items/{itemID}/barcodes/{barcode} declares path parameters"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			tc.routes(r)
+
+			err := s.CheckRoutes(r)
+
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Errorf("got error %v, want nil", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("got no error, want one mentioning %q", tc.want)
+			}
+
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got error %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidRequestMiddlewareLimitsBodySize(t *testing.T) {
+	s := newTestSpec(t)
+
+	r := chi.NewRouter()
+	r.Use(ValidRequestMiddleware(s))
+	r.Post("/items", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, tc := range []struct {
+		name string
+		size int
+		want int
+	}{
+		{name: "small body", size: 10, want: http.StatusCreated},
+		{name: "body over the limit", size: maxBodySize + 1, want: http.StatusRequestEntityTooLarge},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := url.Values{"name": {strings.Repeat("a", tc.size)}}.Encode()
+
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
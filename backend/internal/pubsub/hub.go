@@ -0,0 +1,94 @@
+package pubsub
+
+import "sync"
+
+// Hub fans out the messages published to a topic to every subscriber of that topic within the process.
+// Publishing never blocks: a subscriber that falls more than its buffer behind is unsubscribed,
+// closing its channel, so that it can catch up from the source of truth and subscribe again.
+type Hub[T any] struct {
+	mu         sync.Mutex
+	topics     map[string]map[chan T]struct{}
+	bufferSize int
+	closed     bool
+}
+
+func NewHub[T any](bufferSize int) *Hub[T] {
+	return &Hub[T]{topics: make(map[string]map[chan T]struct{}), bufferSize: bufferSize}
+}
+
+// Subscribe returns a channel that receives the messages published to the topic from now on,
+// along with a function that unsubscribes and closes it. The channel is closed right away if the hub is.
+func (h *Hub[T]) Subscribe(topic string) (<-chan T, func()) {
+	ch := make(chan T, h.bufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	subs, ok := h.topics[topic]
+	if !ok {
+		subs = make(map[chan T]struct{})
+		h.topics[topic] = subs
+	}
+
+	subs[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.unsubscribe(topic, ch)
+	}
+}
+
+// Publish sends the message to the topic's current subscribers.
+func (h *Hub[T]) Publish(topic string, msg T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.topics[topic] {
+		select {
+		case ch <- msg:
+		default:
+			h.unsubscribe(topic, ch)
+		}
+	}
+}
+
+// Close unsubscribes everyone, e.g. so that long-lived streams end when the server shuts down.
+// Subscribing afterwards returns closed channels.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for topic, subs := range h.topics {
+		for ch := range subs {
+			h.unsubscribe(topic, ch)
+		}
+	}
+
+	h.closed = true
+}
+
+// unsubscribe must be called with mu held. It does nothing if ch was already unsubscribed.
+func (h *Hub[T]) unsubscribe(topic string, ch chan T) {
+	subs, ok := h.topics[topic]
+	if !ok {
+		return
+	}
+
+	if _, ok = subs[ch]; !ok {
+		return
+	}
+
+	delete(subs, ch)
+	close(ch)
+
+	if len(subs) == 0 {
+		delete(h.topics, topic)
+	}
+}
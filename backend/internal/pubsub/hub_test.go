@@ -0,0 +1,148 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/JosephJoshua/rvm/backend/internal/pubsub"
+)
+
+const bufferSize = 2
+
+// drain returns the messages already sent to ch and whether it was closed. Publish doesn't block,
+// so anything published before is in the buffer by now.
+func drain(ch <-chan int) ([]int, bool) {
+	var msgs []int
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return msgs, true
+			}
+
+			msgs = append(msgs, msg)
+		default:
+			return msgs, false
+		}
+	}
+}
+
+func TestHubFanOut(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		topics []string
+		topic  string
+		// want is whether each subscriber receives the message.
+		want []bool
+	}{
+		{name: "no subscribers", topic: "machine-1"},
+		{name: "one subscriber", topics: []string{"machine-1"}, topic: "machine-1", want: []bool{true}},
+		{
+			name:   "every subscriber of the topic",
+			topics: []string{"machine-1", "machine-1", "machine-1"},
+			topic:  "machine-1",
+			want:   []bool{true, true, true},
+		},
+		{
+			name:   "only the topic's subscribers",
+			topics: []string{"machine-1", "machine-2", "machine-1"},
+			topic:  "machine-1",
+			want:   []bool{true, false, true},
+		},
+		{name: "topic without subscribers", topics: []string{"machine-2"}, topic: "machine-1", want: []bool{false}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := pubsub.NewHub[int](bufferSize)
+			defer h.Close()
+
+			subs := make([]<-chan int, 0, len(tc.topics))
+			for _, topic := range tc.topics {
+				ch, _ := h.Subscribe(topic)
+				subs = append(subs, ch)
+			}
+
+			h.Publish(tc.topic, 1)
+
+			for i, ch := range subs {
+				msgs, closed := drain(ch)
+				if closed {
+					t.Errorf("subscriber %d: got a closed channel, want an open one", i)
+				}
+
+				if got := len(msgs) == 1 && msgs[0] == 1; got != tc.want[i] {
+					t.Errorf("subscriber %d: got messages %v, want the message: %t", i, msgs, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	h := pubsub.NewHub[int](bufferSize)
+	defer h.Close()
+
+	gone, unsubscribe := h.Subscribe("machine-1")
+	stays, _ := h.Subscribe("machine-1")
+
+	h.Publish("machine-1", 1)
+	unsubscribe()
+	h.Publish("machine-1", 2)
+
+	// Unsubscribing again does nothing rather than closing the channel twice.
+	unsubscribe()
+
+	if msgs, closed := drain(gone); !closed || len(msgs) != 1 || msgs[0] != 1 {
+		t.Errorf("unsubscribed: got messages %v and closed %t, want [1] and closed", msgs, closed)
+	}
+
+	if msgs, closed := drain(stays); closed || len(msgs) != 2 {
+		t.Errorf("still subscribed: got messages %v and closed %t, want [1 2] and open", msgs, closed)
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	h := pubsub.NewHub[int](bufferSize)
+	defer h.Close()
+
+	slow, _ := h.Subscribe("machine-1")
+	fast, _ := h.Subscribe("machine-1")
+
+	var received []int
+
+	for msg := 1; msg <= bufferSize+1; msg++ {
+		h.Publish("machine-1", msg)
+
+		msgs, _ := drain(fast)
+		received = append(received, msgs...)
+	}
+
+	// The slow subscriber keeps what fit in its buffer and is closed instead of blocking the publisher.
+	if msgs, closed := drain(slow); !closed || len(msgs) != bufferSize {
+		t.Errorf("slow: got messages %v and closed %t, want %d messages and closed", msgs, closed, bufferSize)
+	}
+
+	if msgs, closed := drain(fast); closed || len(received)+len(msgs) != bufferSize+1 {
+		t.Errorf("fast: got messages %v and closed %t, want %d messages and open", received, closed, bufferSize+1)
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	h := pubsub.NewHub[int](bufferSize)
+
+	before, unsubscribe := h.Subscribe("machine-1")
+
+	h.Close()
+
+	if _, closed := drain(before); !closed {
+		t.Error("got an open channel after closing the hub, want it closed")
+	}
+
+	// Neither unsubscribing nor publishing after closing closes a channel twice.
+	unsubscribe()
+	h.Publish("machine-1", 1)
+
+	after, _ := h.Subscribe("machine-1")
+	if _, closed := drain(after); !closed {
+		t.Error("got an open channel when subscribing to a closed hub, want it closed")
+	}
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type Outcome string
+
+const (
+	// OutcomeApproved confirms that the machine recognized the item correctly.
+	OutcomeApproved Outcome = "approved"
+	// OutcomeRelabeled replaces the item the machine recognized with the one the operator saw.
+	OutcomeRelabeled Outcome = "relabeled"
+)
+
+func (o Outcome) String() string {
+	return string(o)
+}
+
+// QueueEntry is a transaction item whose capture was uncertain and hasn't been reviewed yet.
+type QueueEntry struct {
+	TransactionItemID int64
+	TransactionID     string
+	ItemID            int
+	ItemName          string
+	CaptureRequestID  string
+	// FusedItemID and Confidence are what the capture settled on; both are nil if the cameras
+	// didn't reach a consensus.
+	FusedItemID *int
+	Confidence  *float64
+	// ImageSides are the camera sides whose images were kept.
+	ImageSides []string
+	CreatedAt  time.Time
+}
+
+// TransactionItem is the part of a transaction item a review needs.
+type TransactionItem struct {
+	ID            int64
+	TransactionID string
+	ItemID        int
+	// UserID is who claimed the transaction, if anyone has yet.
+	UserID *string
+	// CreditedPoints is what the item was worth when the transaction was claimed, or nil if it wasn't yet.
+	CreditedPoints *int
+}
+
+// Review records an operator's verdict on a transaction item.
+type Review struct {
+	TransactionItemID int64
+	Outcome           Outcome
+	OriginalItemID    int
+	ItemID            int
+	// PointsAdjustment is what was credited or debited to the user who claimed the transaction
+	// because of a relabel, or 0 if it wasn't claimed yet.
+	PointsAdjustment int
+	ReviewedBy       string
+	ReviewedAt       time.Time
+}
+
+func NewApproval(ti TransactionItem, reviewedBy string, reviewedAt time.Time) Review {
+	return Review{
+		TransactionItemID: ti.ID,
+		Outcome:           OutcomeApproved,
+		OriginalItemID:    ti.ItemID,
+		ItemID:            ti.ItemID,
+		ReviewedBy:        reviewedBy,
+		ReviewedAt:        reviewedAt,
+	}
+}
+
+func NewRelabel(
+	ti TransactionItem,
+	itemID int,
+	pointsAdjustment int,
+	reviewedBy string,
+	reviewedAt time.Time,
+) (Review, error) {
+	if itemID == ti.ItemID {
+		return Review{}, fmt.Errorf("relabel must change the item, got %d again", itemID)
+	}
+
+	return Review{
+		TransactionItemID: ti.ID,
+		Outcome:           OutcomeRelabeled,
+		OriginalItemID:    ti.ItemID,
+		ItemID:            itemID,
+		PointsAdjustment:  pointsAdjustment,
+		ReviewedBy:        reviewedBy,
+		ReviewedAt:        reviewedAt,
+	}, nil
+}
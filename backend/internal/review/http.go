@@ -0,0 +1,286 @@
+package review
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/auth"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/JosephJoshua/rvm/backend/internal/review/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeTransactionItemDoesNotExist = "transaction_item_does_not_exist"
+	codeAlreadyReviewed             = "already_reviewed"
+	codeImageDoesNotExist           = "image_does_not_exist"
+	codeItemDoesNotExist            = "item_does_not_exist"
+	codeSameItem                    = "same_item"
+	codeInsufficientPoints          = "insufficient_points"
+	codeNotQueued                   = "not_queued"
+	codeItemArchived                = "item_archived"
+)
+
+type queueEntryResponse struct {
+	TransactionItemID int64     `json:"transaction_item_id"`
+	TransactionID     string    `json:"transaction_id"`
+	ItemID            int       `json:"item_id"`
+	ItemName          string    `json:"item_name"`
+	CaptureRequestID  string    `json:"capture_request_id"`
+	FusedItemID       *int      `json:"fused_item_id"`
+	Confidence        *float64  `json:"confidence"`
+	ImageSides        []string  `json:"image_sides"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type reviewResponse struct {
+	TransactionItemID int64     `json:"transaction_item_id"`
+	Outcome           string    `json:"outcome"`
+	OriginalItemID    int       `json:"original_item_id"`
+	ItemID            int       `json:"item_id"`
+	PointsAdjustment  int       `json:"points_adjustment"`
+	ReviewedAt        time.Time `json:"reviewed_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new review HTTP handler for operators.
+// It expects auth.LoggedInMiddleware and auth.AdminOnlyMiddleware to run before it.
+//   - GET /reviews - returns the transaction items awaiting review, oldest first, as JSON.
+//   - GET /reviews/{transactionItemID}/images/{side} - returns the image the camera side took of the item.
+//   - POST /reviews/{transactionItemID}/approve - confirms the item the machine recognized.
+//   - POST /reviews/{transactionItemID}/relabel - replaces the item with item_id, a form value or query
+//     parameter, adjusting the points of the user who claimed the transaction if needed. Only queued items
+//     can be relabeled, and only to active items.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getQueue))
+	r.Get("/{transactionItemID}/images/{side}", httputils.HandlerFunc(handler.getImage))
+	r.Post("/{transactionItemID}/approve", httputils.HandlerFunc(handler.approve))
+	r.Post("/{transactionItemID}/relabel", httputils.HandlerFunc(handler.relabel))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getQueue(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	entries, err := h.s.GetQueue()
+	if err != nil {
+		oplog.Error("failed to get review queue", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]queueEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		sides := e.ImageSides
+		if sides == nil {
+			sides = []string{}
+		}
+
+		res = append(res, queueEntryResponse{
+			TransactionItemID: e.TransactionItemID,
+			TransactionID:     e.TransactionID,
+			ItemID:            e.ItemID,
+			ItemName:          e.ItemName,
+			CaptureRequestID:  e.CaptureRequestID,
+			FusedItemID:       e.FusedItemID,
+			Confidence:        e.Confidence,
+			ImageSides:        sides,
+			CreatedAt:         e.CreatedAt,
+		})
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) getImage(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	transactionItemID, ok := parseTransactionItemID(w, r, &oplog)
+	if !ok {
+		return
+	}
+
+	side := chi.URLParam(r, "side")
+
+	image, err := h.s.GetImage(r.Context(), transactionItemID, side)
+	if err != nil {
+		if errors.Is(err, ErrImageDoesNotExist) {
+			oplog.Info("image not found", slog.Int64("transaction_item_id", transactionItemID), slog.String("side", side))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeImageDoesNotExist, "image not found")
+
+			return
+		}
+
+		oplog.Error("failed to get image", logging.ErrAttr(err), slog.Int64("transaction_item_id", transactionItemID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	w.TryWrite(&oplog, image)
+}
+
+func (h *HTTPHandler) approve(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	transactionItemID, ok := parseTransactionItemID(w, r, &oplog)
+	if !ok {
+		return
+	}
+
+	review, err := h.s.Approve(transactionItemID, auth.UIDFromCtx(r.Context()))
+	if err != nil {
+		h.writeReviewError(w, &oplog, err, transactionItemID)
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toReviewResponse(review))
+}
+
+func (h *HTTPHandler) relabel(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	transactionItemID, ok := parseTransactionItemID(w, r, &oplog)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.Atoi(r.FormValue("item_id"))
+	if err != nil {
+		oplog.Error("failed to convert item_id to int", logging.ErrAttr(err))
+
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, "item_id has to be an integer")
+
+		return
+	}
+
+	review, err := h.s.Relabel(transactionItemID, itemID, auth.UIDFromCtx(r.Context()))
+	if err != nil {
+		if errors.Is(err, ErrItemDoesNotExist) {
+			oplog.Error("item not found", slog.Int("item_id", itemID))
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, codeItemDoesNotExist, "item not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrSameItem) {
+			oplog.Error("relabel doesn't change the item", slog.Int("item_id", itemID))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusUnprocessableEntity,
+				codeSameItem,
+				"item is unchanged, approve it instead",
+			)
+
+			return
+		}
+
+		if errors.Is(err, ErrInsufficientPoints) {
+			oplog.Info("user can't cover the points adjustment", logging.ErrAttr(err))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusConflict,
+				codeInsufficientPoints,
+				"user has already spent the points the relabel would take back",
+			)
+
+			return
+		}
+
+		h.writeReviewError(w, &oplog, err, transactionItemID)
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toReviewResponse(review))
+}
+
+// writeReviewError responds to the errors approving and relabeling have in common.
+func (h *HTTPHandler) writeReviewError(
+	w httputils.ResponseWriter,
+	oplog *slog.Logger,
+	err error,
+	transactionItemID int64,
+) {
+	if errors.Is(err, ErrTransactionItemDoesNotExist) {
+		oplog.Error("transaction item not found", slog.Int64("transaction_item_id", transactionItemID))
+		w.TryWriteProblem(oplog, http.StatusNotFound, codeTransactionItemDoesNotExist, "transaction item not found")
+
+		return
+	}
+
+	if errors.Is(err, ErrAlreadyReviewed) {
+		oplog.Info("transaction item already reviewed", slog.Int64("transaction_item_id", transactionItemID))
+
+		w.TryWriteProblem(oplog, http.StatusConflict, codeAlreadyReviewed, "transaction item has already been reviewed")
+
+		return
+	}
+
+	if errors.Is(err, ErrNotQueued) {
+		oplog.Info("transaction item isn't awaiting review", slog.Int64("transaction_item_id", transactionItemID))
+
+		w.TryWriteProblem(oplog, http.StatusConflict, codeNotQueued, "transaction item is not awaiting review")
+
+		return
+	}
+
+	if errors.Is(err, ErrItemArchived) {
+		oplog.Error("item is archived", logging.ErrAttr(err))
+
+		w.TryWriteProblem(oplog, http.StatusUnprocessableEntity, codeItemArchived, "item is archived")
+
+		return
+	}
+
+	oplog.Error(
+		"failed to review transaction item",
+		logging.ErrAttr(err),
+		slog.Int64("transaction_item_id", transactionItemID),
+	)
+
+	w.TryWriteInternalError(oplog)
+}
+
+func parseTransactionItemID(w httputils.ResponseWriter, r *http.Request, oplog *slog.Logger) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "transactionItemID"), 10, 64)
+	if err != nil {
+		oplog.Error("failed to convert transaction item id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(oplog, http.StatusNotFound, codeTransactionItemDoesNotExist, "transaction item not found")
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+func toReviewResponse(review domain.Review) reviewResponse {
+	return reviewResponse{
+		TransactionItemID: review.TransactionItemID,
+		Outcome:           review.Outcome.String(),
+		OriginalItemID:    review.OriginalItemID,
+		ItemID:            review.ItemID,
+		PointsAdjustment:  review.PointsAdjustment,
+		ReviewedAt:        review.ReviewedAt,
+	}
+}
@@ -0,0 +1,34 @@
+package review
+
+import (
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	"github.com/JosephJoshua/rvm/backend/internal/review/domain"
+)
+
+// Repositories are the repositories a Service uses inside a unit of work, all sharing its transaction.
+type Repositories struct {
+	Reviews Repository
+	Ledger  ledger.Repository
+}
+
+type Repository interface {
+	// GetQueue returns the unreviewed transaction items, oldest first, whose capture had no consensus,
+	// was less confident than threshold or settled on a different item than the one that was added.
+	GetQueue(threshold float64, limit int) ([]domain.QueueEntry, error)
+	// NeedsReview reports whether the transaction item's capture puts it in the queue GetQueue returns,
+	// whether or not it has been reviewed since.
+	NeedsReview(transactionItemID int64, threshold float64) (bool, error)
+	// GetTransactionItem returns ErrTransactionItemDoesNotExist if there is no such transaction item.
+	GetTransactionItem(id int64) (*domain.TransactionItem, error)
+	// GetItemPoints returns ErrItemDoesNotExist if there is no such item.
+	GetItemPoints(itemID int) (int, error)
+	IsItemActive(itemID int) (bool, error)
+	// CreateReview adds the review only if the transaction item hasn't been reviewed yet,
+	// reporting whether it was added.
+	CreateReview(r domain.Review) (bool, error)
+	// SetTransactionItemItem replaces the transaction item's item along with the points it was credited with,
+	// which stay nil until the transaction is claimed.
+	SetTransactionItemItem(transactionItemID int64, itemID int, creditedPoints *int) error
+	// GetImageKey returns ErrImageDoesNotExist if the side's image for the transaction item's capture wasn't kept.
+	GetImageKey(transactionItemID int64, side string) (string, error)
+}
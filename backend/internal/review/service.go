@@ -0,0 +1,247 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/blob"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	ledgerdomain "github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/review/domain"
+)
+
+// queueLimit is how many transaction items awaiting review are returned at once.
+const queueLimit = 100
+
+var (
+	ErrTransactionItemDoesNotExist = fmt.Errorf("transaction item does not exist")
+	ErrItemDoesNotExist            = fmt.Errorf("item does not exist")
+	ErrImageDoesNotExist           = fmt.Errorf("image does not exist")
+	ErrAlreadyReviewed             = fmt.Errorf("transaction item has already been reviewed")
+	ErrSameItem                    = fmt.Errorf("relabel must change the item")
+	ErrNotQueued                   = fmt.Errorf("transaction item is not in the review queue")
+	ErrItemArchived                = fmt.Errorf("item is archived")
+	ErrInsufficientPoints          = fmt.Errorf("insufficient points")
+)
+
+type Config struct {
+	// ConfidenceThreshold is the fused confidence below which an item needs to be reviewed.
+	ConfidenceThreshold float64
+}
+
+type Service struct {
+	r   Repository
+	uow db.UnitOfWork[Repositories]
+	bs  blob.Store
+	cfg Config
+}
+
+func NewService(r Repository, uow db.UnitOfWork[Repositories], bs blob.Store, cfg Config) *Service {
+	return &Service{r: r, uow: uow, bs: bs, cfg: cfg}
+}
+
+// GetQueue returns the transaction items awaiting review, oldest first.
+func (s *Service) GetQueue() ([]domain.QueueEntry, error) {
+	entries, err := s.r.GetQueue(s.cfg.ConfidenceThreshold, queueLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetQueue(): failed to get queue: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetImage returns the image the given camera side took of the transaction item.
+func (s *Service) GetImage(ctx context.Context, transactionItemID int64, side string) ([]byte, error) {
+	key, err := s.r.GetImageKey(transactionItemID, side)
+	if err != nil {
+		return nil, fmt.Errorf("GetImage(): failed to get image key: %w", err)
+	}
+
+	image, err := s.bs.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrBlobDoesNotExist) {
+			return nil, fmt.Errorf("GetImage(): %w: %s", ErrImageDoesNotExist, key)
+		}
+
+		return nil, fmt.Errorf("GetImage(): failed to get image: %w", err)
+	}
+
+	return image, nil
+}
+
+// Approve confirms the item the machine recognized, taking the transaction item out of the queue;
+// items that aren't in it fail with ErrNotQueued, and items recognized as an archived item fail with
+// ErrItemArchived.
+func (s *Service) Approve(transactionItemID int64, reviewerID string) (domain.Review, error) {
+	var review domain.Review
+
+	err := s.uow.Do(func(repos Repositories) error {
+		r := repos.Reviews
+
+		ti, err := r.GetTransactionItem(transactionItemID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction item: %w", err)
+		}
+
+		if err = s.checkQueued(r, transactionItemID); err != nil {
+			return err
+		}
+
+		if err = checkActive(r, ti.ItemID); err != nil {
+			return err
+		}
+
+		review = domain.NewApproval(*ti, reviewerID, time.Now())
+
+		return s.createReview(r, review)
+	})
+	if err != nil {
+		return domain.Review{}, fmt.Errorf("Approve(): %w", err)
+	}
+
+	return review, nil
+}
+
+// Relabel replaces the item the machine recognized with the given active one, taking the transaction item
+// out of the queue; items that aren't in it fail with ErrNotQueued. If the transaction was already claimed,
+// the difference between the new item's points and what the transaction item was credited with is credited
+// or debited to the user who claimed it; a debit the user's balance can't cover fails with
+// ErrInsufficientPoints. Otherwise the transaction is simply worth the new item's points when claimed.
+func (s *Service) Relabel(transactionItemID int64, itemID int, reviewerID string) (domain.Review, error) {
+	var review domain.Review
+
+	err := s.uow.Do(func(repos Repositories) error {
+		r := repos.Reviews
+		now := time.Now()
+
+		ti, err := r.GetTransactionItem(transactionItemID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction item: %w", err)
+		}
+
+		if err = s.checkQueued(r, transactionItemID); err != nil {
+			return err
+		}
+
+		if itemID == ti.ItemID {
+			return fmt.Errorf("%w: item %d", ErrSameItem, itemID)
+		}
+
+		newPoints, err := r.GetItemPoints(itemID)
+		if err != nil {
+			return fmt.Errorf("failed to get points of new item: %w", err)
+		}
+
+		if err = checkActive(r, itemID); err != nil {
+			return err
+		}
+
+		// The catalog's points may have changed since the claim, so the adjustment is against
+		// what was actually credited for the original item.
+		adjustment := 0
+
+		var creditedPoints *int
+		if ti.CreditedPoints != nil {
+			adjustment = newPoints - *ti.CreditedPoints
+			creditedPoints = &newPoints
+		}
+
+		review, err = domain.NewRelabel(*ti, itemID, adjustment, reviewerID, now)
+		if err != nil {
+			return fmt.Errorf("failed to create review: %w", err)
+		}
+
+		if err = s.createReview(r, review); err != nil {
+			return err
+		}
+
+		if err = r.SetTransactionItemItem(transactionItemID, itemID, creditedPoints); err != nil {
+			return fmt.Errorf("failed to relabel transaction item: %w", err)
+		}
+
+		if adjustment == 0 {
+			return nil
+		}
+
+		return s.adjustPoints(repos.Ledger, *ti, review)
+	})
+	if err != nil {
+		return domain.Review{}, fmt.Errorf("Relabel(): %w", err)
+	}
+
+	return review, nil
+}
+
+// checkQueued returns ErrNotQueued unless the transaction item is awaiting review.
+func (s *Service) checkQueued(r Repository, transactionItemID int64) error {
+	queued, err := r.NeedsReview(transactionItemID, s.cfg.ConfidenceThreshold)
+	if err != nil {
+		return fmt.Errorf("failed to check review queue: %w", err)
+	}
+
+	if !queued {
+		return fmt.Errorf("%w: transaction item %d", ErrNotQueued, transactionItemID)
+	}
+
+	return nil
+}
+
+// checkActive returns ErrItemArchived if the item is archived.
+func checkActive(r Repository, itemID int) error {
+	active, err := r.IsItemActive(itemID)
+	if err != nil {
+		return fmt.Errorf("failed to check if item is active: %w", err)
+	}
+
+	if !active {
+		return fmt.Errorf("%w: item %d", ErrItemArchived, itemID)
+	}
+
+	return nil
+}
+
+func (s *Service) createReview(r Repository, review domain.Review) error {
+	ok, err := r.CreateReview(review)
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w", err)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: transaction item %d", ErrAlreadyReviewed, review.TransactionItemID)
+	}
+
+	return nil
+}
+
+// adjustPoints credits or debits the relabel's points adjustment to the user who claimed the transaction.
+func (s *Service) adjustPoints(l ledger.Repository, ti domain.TransactionItem, review domain.Review) error {
+	reference := "transaction_item:" + strconv.FormatInt(ti.ID, 10)
+	note := fmt.Sprintf("relabeled item %d to %d", review.OriginalItemID, review.ItemID)
+
+	entry, err := ledgerdomain.NewEntry(
+		*ti.UserID,
+		ledgerdomain.EntryKindAdjustment,
+		review.PointsAdjustment,
+		&ti.TransactionID,
+		&reference,
+		note,
+		review.ReviewedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create adjustment entry: %w", err)
+	}
+
+	if err = l.AppendEntry(entry); err != nil {
+		if errors.Is(err, ledger.ErrInsufficientBalance) {
+			return fmt.Errorf("%w to undo %d points", ErrInsufficientPoints, -review.PointsAdjustment)
+		}
+
+		return fmt.Errorf("failed to adjust points: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package review_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	ledgerdomain "github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/review"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction"
+	transactiondomain "github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
+)
+
+const (
+	threshold  = 0.8
+	reviewerID = "reviewer"
+	userID     = "user"
+)
+
+// newService returns a service whose reviews are made by reviewerID.
+func newService(t *testing.T, dbHandle *db.DB) *review.Service {
+	t.Helper()
+
+	dbtest.CreateUser(t, dbHandle, reviewerID)
+
+	return review.NewService(
+		review.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) review.Repositories {
+			return review.Repositories{
+				Reviews: review.NewSQLRepository(q),
+				Ledger:  ledger.NewSQLRepository(q),
+			}
+		}),
+		nil,
+		review.Config{ConfidenceThreshold: threshold},
+	)
+}
+
+// addCapturedItem adds the item to a new transaction along with the capture it was recognized in,
+// returning the transaction's id and the transaction item's id.
+func addCapturedItem(
+	t *testing.T,
+	dbHandle *db.DB,
+	itemID int,
+	confidence float64,
+) (transactiondomain.TransactionID, int64) {
+	t.Helper()
+
+	r := transaction.NewSQLRepository(dbHandle)
+	id := transactiondomain.TransactionID("3c2a9a4e-6f1b-4d7e-8a0c-5e2f7b9d1a64")
+	requestID := "9e4d2c1b-7a3f-4b8e-9c6d-1f0a2b3c4d5e"
+	now := time.Now()
+
+	if err := r.StartTransaction(id, dbtest.CreateMachine(t, dbHandle), now); err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	if _, err := dbHandle.Exec(`
+		INSERT INTO
+			captures (request_id, strategy, item_id, confidence, sides_agree, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?)
+	`, requestID, "average", itemID, confidence, true, now); err != nil {
+		t.Fatalf("failed to create capture: %v", err)
+	}
+
+	if err := r.AddItemToTransaction(id, itemID, &requestID, now); err != nil {
+		t.Fatalf("failed to add item: %v", err)
+	}
+
+	if ok, err := r.CloseTransaction(id, now); err != nil || !ok {
+		t.Fatalf("failed to close transaction: ok=%v, err=%v", ok, err)
+	}
+
+	var transactionItemID int64
+	if err := dbHandle.Get(&transactionItemID, `
+		SELECT
+			transaction_item_id
+		FROM
+			transaction_items
+		WHERE
+			transaction_id = ?
+	`, id); err != nil {
+		t.Fatalf("failed to get transaction item: %v", err)
+	}
+
+	return id, transactionItemID
+}
+
+// claim assigns the transaction to the user and credits its points, the way claiming a transaction does.
+func claim(t *testing.T, dbHandle *db.DB, id transactiondomain.TransactionID) {
+	t.Helper()
+
+	r := transaction.NewSQLRepository(dbHandle)
+	now := time.Now()
+
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	if ok, err := r.ClaimTransaction(id, userID, now, now.Add(-time.Hour)); err != nil || !ok {
+		t.Fatalf("failed to claim transaction: ok=%v, err=%v", ok, err)
+	}
+
+	if err := r.CreditTransactionItems(id); err != nil {
+		t.Fatalf("failed to credit transaction items: %v", err)
+	}
+
+	points, err := r.GetTransactionPoints(id)
+	if err != nil {
+		t.Fatalf("failed to get points: %v", err)
+	}
+
+	tid := id.String()
+
+	entry, err := ledgerdomain.NewEntry(userID, ledgerdomain.EntryKindEarning, points, &tid, nil, "", now)
+	if err != nil {
+		t.Fatalf("failed to create earning entry: %v", err)
+	}
+
+	if err = ledger.NewSQLRepository(dbHandle).AppendEntry(entry); err != nil {
+		t.Fatalf("failed to credit points: %v", err)
+	}
+}
+
+func TestRelabelAdjustsCreditedPoints(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(t, dbHandle)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 30)
+
+	id, transactionItemID := addCapturedItem(t, dbHandle, bottleID, 0.5)
+	claim(t, dbHandle, id)
+
+	// The catalog changing after the claim must not change what the relabel takes back.
+	if _, err := dbHandle.Exec("UPDATE items SET points = ? WHERE item_id = ?", 50, bottleID); err != nil {
+		t.Fatalf("failed to update item points: %v", err)
+	}
+
+	got, err := s.Relabel(transactionItemID, canID, reviewerID)
+	if err != nil {
+		t.Fatalf("failed to relabel: %v", err)
+	}
+
+	if got.PointsAdjustment != 20 {
+		t.Errorf("got adjustment %d, want 20", got.PointsAdjustment)
+	}
+
+	balance, err := ledger.NewSQLRepository(dbHandle).GetBalance(userID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+
+	if balance != 30 {
+		t.Errorf("got balance %d, want 30", balance)
+	}
+}
+
+func TestRelabelBeforeClaim(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(t, dbHandle)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 30)
+
+	id, transactionItemID := addCapturedItem(t, dbHandle, bottleID, 0.5)
+
+	got, err := s.Relabel(transactionItemID, canID, reviewerID)
+	if err != nil {
+		t.Fatalf("failed to relabel: %v", err)
+	}
+
+	if got.PointsAdjustment != 0 {
+		t.Errorf("got adjustment %d, want 0", got.PointsAdjustment)
+	}
+
+	claim(t, dbHandle, id)
+
+	balance, err := ledger.NewSQLRepository(dbHandle).GetBalance(userID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+
+	if balance != 30 {
+		t.Errorf("got balance %d, want 30", balance)
+	}
+}
+
+func TestRelabelRejectsItemsNotQueued(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(t, dbHandle)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 30)
+
+	_, transactionItemID := addCapturedItem(t, dbHandle, bottleID, 0.95)
+
+	if _, err := s.Relabel(transactionItemID, canID, reviewerID); !errors.Is(err, review.ErrNotQueued) {
+		t.Errorf("got error %v, want %v", err, review.ErrNotQueued)
+	}
+}
+
+func TestRelabelRejectsArchivedItems(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(t, dbHandle)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 30)
+
+	_, transactionItemID := addCapturedItem(t, dbHandle, bottleID, 0.5)
+
+	if _, err := dbHandle.Exec("UPDATE items SET is_active = ? WHERE item_id = ?", false, canID); err != nil {
+		t.Fatalf("failed to archive item: %v", err)
+	}
+
+	if _, err := s.Relabel(transactionItemID, canID, reviewerID); !errors.Is(err, review.ErrItemArchived) {
+		t.Errorf("got error %v, want %v", err, review.ErrItemArchived)
+	}
+}
+
+func TestApproveRejectsItemsNotQueued(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(t, dbHandle)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+
+	_, transactionItemID := addCapturedItem(t, dbHandle, bottleID, 0.95)
+
+	if _, err := s.Approve(transactionItemID, reviewerID); !errors.Is(err, review.ErrNotQueued) {
+		t.Errorf("got error %v, want %v", err, review.ErrNotQueued)
+	}
+}
+
+func TestApproveRejectsArchivedItems(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(t, dbHandle)
+
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+
+	_, transactionItemID := addCapturedItem(t, dbHandle, bottleID, 0.5)
+
+	if _, err := dbHandle.Exec("UPDATE items SET is_active = ? WHERE item_id = ?", false, bottleID); err != nil {
+		t.Fatalf("failed to archive item: %v", err)
+	}
+
+	if _, err := s.Approve(transactionItemID, reviewerID); !errors.Is(err, review.ErrItemArchived) {
+		t.Errorf("got error %v, want %v", err, review.ErrItemArchived)
+	}
+}
@@ -0,0 +1,299 @@
+package review
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/review/domain"
+)
+
+type queueRow struct {
+	TransactionItemID int64           `db:"transaction_item_id"`
+	TransactionID     string          `db:"transaction_id"`
+	ItemID            int             `db:"item_id"`
+	ItemName          string          `db:"item_name"`
+	CaptureRequestID  string          `db:"capture_request_id"`
+	FusedItemID       sql.NullInt64   `db:"fused_item_id"`
+	Confidence        sql.NullFloat64 `db:"confidence"`
+	CreatedAt         time.Time       `db:"created_at"`
+	Side              sql.NullString  `db:"side"`
+}
+
+type transactionItem struct {
+	TransactionItemID int64          `db:"transaction_item_id"`
+	TransactionID     string         `db:"transaction_id"`
+	ItemID            int            `db:"item_id"`
+	UserID            sql.NullString `db:"user_id"`
+	Points            sql.NullInt64  `db:"points"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (rr *SQLRepository) GetQueue(threshold float64, limit int) ([]domain.QueueEntry, error) {
+	var rows []queueRow
+	if err := rr.db.Select(&rows, `
+		SELECT
+			queue.transaction_item_id,
+			queue.transaction_id,
+			queue.item_id,
+			queue.item_name,
+			queue.capture_request_id,
+			queue.fused_item_id,
+			queue.confidence,
+			queue.created_at,
+			capture_results.side
+		FROM
+			(
+				SELECT
+					transaction_items.transaction_item_id,
+					transaction_items.transaction_id,
+					transaction_items.item_id,
+					items.name AS item_name,
+					transaction_items.capture_request_id,
+					captures.item_id AS fused_item_id,
+					captures.confidence,
+					transaction_items.created_at
+				FROM
+					transaction_items
+				INNER JOIN
+					captures ON captures.request_id = transaction_items.capture_request_id
+				INNER JOIN
+					items ON items.item_id = transaction_items.item_id
+				LEFT JOIN
+					item_reviews ON item_reviews.transaction_item_id = transaction_items.transaction_item_id
+				WHERE
+					item_reviews.transaction_item_id IS NULL
+					AND (
+						captures.item_id IS NULL
+						OR captures.confidence < ?
+						OR captures.item_id <> transaction_items.item_id
+					)
+				ORDER BY
+					transaction_items.created_at, transaction_items.transaction_item_id
+				LIMIT ?
+			) AS queue
+		LEFT JOIN
+			capture_results ON capture_results.request_id = queue.capture_request_id
+			AND capture_results.image_key IS NOT NULL
+		ORDER BY
+			queue.created_at, queue.transaction_item_id, capture_results.side
+	`, threshold, limit); err != nil {
+		return nil, fmt.Errorf("GetQueue(): failed to execute query: %w", err)
+	}
+
+	entries := make([]domain.QueueEntry, 0)
+
+	for _, row := range rows {
+		if len(entries) == 0 || entries[len(entries)-1].TransactionItemID != row.TransactionItemID {
+			entries = append(entries, newQueueEntryFromRow(row))
+		}
+
+		if row.Side.Valid {
+			last := &entries[len(entries)-1]
+			last.ImageSides = append(last.ImageSides, row.Side.String)
+		}
+	}
+
+	return entries, nil
+}
+
+func (rr *SQLRepository) NeedsReview(transactionItemID int64, threshold float64) (bool, error) {
+	var count int
+	if err := rr.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			transaction_items
+		INNER JOIN
+			captures ON captures.request_id = transaction_items.capture_request_id
+		WHERE
+			transaction_items.transaction_item_id = ?
+			AND (
+				captures.item_id IS NULL
+				OR captures.confidence < ?
+				OR captures.item_id <> transaction_items.item_id
+			)
+	`, transactionItemID, threshold); err != nil {
+		return false, fmt.Errorf("NeedsReview(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (rr *SQLRepository) GetTransactionItem(id int64) (*domain.TransactionItem, error) {
+	var raw transactionItem
+	if err := rr.db.Get(&raw, `
+		SELECT
+			transaction_items.transaction_item_id,
+			transaction_items.transaction_id,
+			transaction_items.item_id,
+			transactions.user_id,
+			transaction_items.points
+		FROM
+			transaction_items
+		INNER JOIN
+			transactions ON transactions.transaction_id = transaction_items.transaction_id
+		WHERE
+			transaction_items.transaction_item_id = ?
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransactionItemDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetTransactionItem(): failed to execute query: %w", err)
+	}
+
+	ti := domain.TransactionItem{
+		ID:            raw.TransactionItemID,
+		TransactionID: raw.TransactionID,
+		ItemID:        raw.ItemID,
+	}
+
+	if raw.UserID.Valid {
+		ti.UserID = &raw.UserID.String
+	}
+
+	if raw.Points.Valid {
+		points := int(raw.Points.Int64)
+		ti.CreditedPoints = &points
+	}
+
+	return &ti, nil
+}
+
+func (rr *SQLRepository) GetItemPoints(itemID int) (int, error) {
+	var points int
+	if err := rr.db.Get(&points, `
+		SELECT
+			points
+		FROM
+			items
+		WHERE
+			item_id = ?
+	`, itemID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrItemDoesNotExist
+		}
+
+		return 0, fmt.Errorf("GetItemPoints(): failed to execute query: %w", err)
+	}
+
+	return points, nil
+}
+
+func (rr *SQLRepository) IsItemActive(itemID int) (bool, error) {
+	var count int
+	if err := rr.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			items
+		WHERE
+			item_id = ? AND is_active = ?
+	`, itemID, true); err != nil {
+		return false, fmt.Errorf("IsItemActive(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (rr *SQLRepository) CreateReview(r domain.Review) (bool, error) {
+	// Like claiming a transaction, this only succeeds for the first of several concurrent reviews.
+	res, err := rr.db.Exec(`
+		INSERT INTO
+			item_reviews (
+				transaction_item_id,
+				outcome,
+				original_item_id,
+				item_id,
+				points_adjustment,
+				reviewed_by,
+				reviewed_at
+			)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (transaction_item_id) DO NOTHING
+	`, r.TransactionItemID, r.Outcome, r.OriginalItemID, r.ItemID, r.PointsAdjustment, r.ReviewedBy, r.ReviewedAt)
+	if err != nil {
+		return false, fmt.Errorf("CreateReview(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("CreateReview(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (rr *SQLRepository) SetTransactionItemItem(transactionItemID int64, itemID int, creditedPoints *int) error {
+	if _, err := rr.db.Exec(`
+		UPDATE
+			transaction_items
+		SET
+			item_id = ?,
+			points = ?
+		WHERE
+			transaction_item_id = ?
+	`, itemID, creditedPoints, transactionItemID); err != nil {
+		return fmt.Errorf("SetTransactionItemItem(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (rr *SQLRepository) GetImageKey(transactionItemID int64, side string) (string, error) {
+	var key string
+	if err := rr.db.Get(&key, `
+		SELECT
+			capture_results.image_key
+		FROM
+			transaction_items
+		INNER JOIN
+			capture_results ON capture_results.request_id = transaction_items.capture_request_id
+		WHERE
+			transaction_items.transaction_item_id = ?
+			AND capture_results.side = ?
+			AND capture_results.image_key IS NOT NULL
+	`, transactionItemID, side); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrImageDoesNotExist
+		}
+
+		return "", fmt.Errorf("GetImageKey(): failed to execute query: %w", err)
+	}
+
+	return key, nil
+}
+
+func newQueueEntryFromRow(row queueRow) domain.QueueEntry {
+	e := domain.QueueEntry{
+		TransactionItemID: row.TransactionItemID,
+		TransactionID:     row.TransactionID,
+		ItemID:            row.ItemID,
+		ItemName:          row.ItemName,
+		CaptureRequestID:  row.CaptureRequestID,
+		CreatedAt:         row.CreatedAt,
+	}
+
+	if row.FusedItemID.Valid {
+		fusedItemID := int(row.FusedItemID.Int64)
+		e.FusedItemID = &fusedItemID
+	}
+
+	if row.Confidence.Valid {
+		confidence := row.Confidence.Float64
+		e.Confidence = &confidence
+	}
+
+	return e
+}
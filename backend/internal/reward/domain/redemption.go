@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Redemption is a reward a user has spent points on, along with the voucher they got for it.
+type Redemption struct {
+	ID         int64
+	UserID     string
+	RewardID   int
+	RewardName string
+	// Cost is what the reward cost when it was redeemed.
+	Cost        int
+	VoucherCode VoucherCode
+	CreatedAt   time.Time
+}
+
+func NewRedemption(
+	userID string,
+	reward Reward,
+	voucherCode VoucherCode,
+	createdAt time.Time,
+) Redemption {
+	return Redemption{
+		UserID:      userID,
+		RewardID:    reward.ID,
+		RewardName:  reward.Name,
+		Cost:        reward.Cost,
+		VoucherCode: voucherCode,
+		CreatedAt:   createdAt,
+	}
+}
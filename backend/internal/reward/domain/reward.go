@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+type Reward struct {
+	ID          int
+	Name        string
+	Description string
+	// Cost is the number of points the reward takes to redeem.
+	Cost int
+	// Stock is how many more times the reward can be redeemed; nil means unlimited.
+	Stock      *int
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+}
+
+func NewReward(
+	id int,
+	name string,
+	description string,
+	cost int,
+	stock *int,
+	validFrom *time.Time,
+	validUntil *time.Time,
+) Reward {
+	return Reward{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		Cost:        cost,
+		Stock:       stock,
+		ValidFrom:   validFrom,
+		ValidUntil:  validUntil,
+	}
+}
+
+// IsValidAt reports whether the reward can be redeemed at t, regardless of its stock.
+func (r Reward) IsValidAt(t time.Time) bool {
+	if r.ValidFrom != nil && t.Before(*r.ValidFrom) {
+		return false
+	}
+
+	return r.ValidUntil == nil || t.Before(*r.ValidUntil)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// voucherCodePattern is three groups of four characters from the Crockford base32 alphabet,
+// which leaves out I, L, O and U so that codes are easy to read out and type in.
+var voucherCodePattern = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}$`)
+
+type VoucherCode string
+
+// NewVoucherCode parses a voucher code, ignoring case and surrounding whitespace.
+func NewVoucherCode(value string) (VoucherCode, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	if !voucherCodePattern.MatchString(value) {
+		return "", fmt.Errorf("invalid voucher code %q", value)
+	}
+
+	return VoucherCode(value), nil
+}
+
+func (c VoucherCode) String() string {
+	return string(c)
+}
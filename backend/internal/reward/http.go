@@ -0,0 +1,198 @@
+package reward
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/auth"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeRewardDoesNotExist = "reward_does_not_exist"
+	codeRewardNotAvailable = "reward_not_available"
+	codeRewardOutOfStock   = "reward_out_of_stock"
+	codeInsufficientPoints = "insufficient_points"
+)
+
+type rewardResponse struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Cost        int        `json:"cost"`
+	Stock       *int       `json:"stock"`
+	ValidUntil  *time.Time `json:"valid_until"`
+}
+
+type redemptionResponse struct {
+	ID          int64     `json:"id"`
+	RewardID    int       `json:"reward_id"`
+	RewardName  string    `json:"reward_name"`
+	Cost        int       `json:"cost"`
+	VoucherCode string    `json:"voucher_code"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new reward HTTP handler. It expects auth.LoggedInMiddleware to run before it.
+//   - GET /users/rewards - returns the rewards that can currently be redeemed as JSON.
+//   - POST /users/rewards/{rewardID}/redeem - spends the user's points on the reward and returns
+//     the redemption as JSON, or only its voucher code to clients that prefer text/plain.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getRewards))
+	r.Post("/{rewardID}/redeem", httputils.HandlerFunc(handler.redeem))
+
+	handler.Router = r
+	return handler
+}
+
+// NewRedemptionHTTPHandler creates a new redemption HTTP handler. It expects auth.LoggedInMiddleware to run before it.
+//   - GET /users/redemptions - returns the user's redemptions, newest first, as JSON.
+func NewRedemptionHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/", httputils.HandlerFunc(handler.getRedemptions))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) getRewards(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	rewards, err := h.s.GetRewards()
+	if err != nil {
+		oplog.Error("failed to get rewards", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]rewardResponse, 0, len(rewards))
+	for _, rw := range rewards {
+		res = append(res, rewardResponse{
+			ID:          rw.ID,
+			Name:        rw.Name,
+			Description: rw.Description,
+			Cost:        rw.Cost,
+			Stock:       rw.Stock,
+			ValidUntil:  rw.ValidUntil,
+		})
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func (h *HTTPHandler) redeem(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	uid := auth.UIDFromCtx(r.Context())
+
+	rewardID, err := strconv.Atoi(chi.URLParam(r, "rewardID"))
+	if err != nil {
+		oplog.Error("failed to convert reward id to int", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeRewardDoesNotExist, "reward not found")
+
+		return
+	}
+
+	redemption, err := h.s.Redeem(uid, rewardID)
+	if err != nil {
+		if errors.Is(err, ErrRewardDoesNotExist) {
+			oplog.Error("reward not found", slog.Int("reward_id", rewardID))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeRewardDoesNotExist, "reward not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrRewardNotAvailable) {
+			oplog.Error("reward is not available", slog.Int("reward_id", rewardID))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeRewardNotAvailable, "reward is not available")
+
+			return
+		}
+
+		if errors.Is(err, ErrRewardOutOfStock) {
+			oplog.Error("reward is out of stock", slog.Int("reward_id", rewardID))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeRewardOutOfStock, "reward is out of stock")
+
+			return
+		}
+
+		if errors.Is(err, ErrInsufficientPoints) {
+			oplog.Info("insufficient points", slog.String("user_id", uid), slog.Int("reward_id", rewardID))
+
+			w.TryWriteProblem(&oplog, http.StatusUnprocessableEntity, codeInsufficientPoints, "insufficient points")
+
+			return
+		}
+
+		oplog.Error(
+			"failed to redeem reward",
+			logging.ErrAttr(err),
+			slog.String("user_id", uid),
+			slog.Int("reward_id", rewardID),
+		)
+
+		w.TryWriteInternalError(&oplog)
+		return
+	}
+
+	w.TryWriteNegotiated(&oplog, http.StatusCreated, toRedemptionResponse(redemption), redemption.VoucherCode.String())
+}
+
+func (h *HTTPHandler) getRedemptions(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	uid := auth.UIDFromCtx(r.Context())
+
+	redemptions, err := h.s.GetRedemptions(uid)
+	if err != nil {
+		oplog.Error("failed to get redemptions", logging.ErrAttr(err), slog.String("user_id", uid))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toRedemptionResponses(redemptions))
+}
+
+func toRedemptionResponses(redemptions []domain.Redemption) []redemptionResponse {
+	res := make([]redemptionResponse, 0, len(redemptions))
+	for _, rd := range redemptions {
+		res = append(res, toRedemptionResponse(rd))
+	}
+
+	return res
+}
+
+func toRedemptionResponse(rd domain.Redemption) redemptionResponse {
+	return redemptionResponse{
+		ID:          rd.ID,
+		RewardID:    rd.RewardID,
+		RewardName:  rd.RewardName,
+		Cost:        rd.Cost,
+		VoucherCode: rd.VoucherCode.String(),
+		CreatedAt:   rd.CreatedAt,
+	}
+}
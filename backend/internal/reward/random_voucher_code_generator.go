@@ -0,0 +1,48 @@
+package reward
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+)
+
+const (
+	crockfordAlphabet  = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	voucherCodeGroups  = 3
+	voucherGroupLength = 4
+)
+
+// RandomVoucherCodeGenerator generates codes with 60 random bits. Codes are not checked for
+// uniqueness here; the unique index on redemptions rejects the astronomically unlikely collision.
+type RandomVoucherCodeGenerator struct{}
+
+func NewRandomVoucherCodeGenerator() RandomVoucherCodeGenerator {
+	return RandomVoucherCodeGenerator{}
+}
+
+func (g RandomVoucherCodeGenerator) Generate() (domain.VoucherCode, error) {
+	buf := make([]byte, voucherCodeGroups*voucherGroupLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("Generate(): failed to read random bytes: %w", err)
+	}
+
+	var sb strings.Builder
+
+	for i, b := range buf {
+		if i > 0 && i%voucherGroupLength == 0 {
+			sb.WriteByte('-')
+		}
+
+		// The alphabet has 32 characters, so every byte maps to one without bias.
+		sb.WriteByte(crockfordAlphabet[int(b)%len(crockfordAlphabet)])
+	}
+
+	code, err := domain.NewVoucherCode(sb.String())
+	if err != nil {
+		return "", fmt.Errorf("Generate(): failed to create voucher code: %w", err)
+	}
+
+	return code, nil
+}
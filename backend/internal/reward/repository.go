@@ -0,0 +1,27 @@
+package reward
+
+import (
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	"github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+)
+
+type Repository interface {
+	// GetRewards returns the rewards that are valid at the given time, including those out of stock.
+	GetRewards(at time.Time) ([]domain.Reward, error)
+	// GetReward returns ErrRewardDoesNotExist if there is no reward with the given id.
+	GetReward(id int) (*domain.Reward, error)
+	// TakeStock takes one unit of the reward's stock if there is any left, reporting whether it did.
+	// Rewards with unlimited stock always succeed.
+	TakeStock(rewardID int) (bool, error)
+	CreateRedemption(redemption domain.Redemption) error
+	// GetRedemptions returns the user's redemptions, newest first.
+	GetRedemptions(userID string) ([]domain.Redemption, error)
+}
+
+// Repositories are the repositories a Service uses inside a unit of work, all sharing its transaction.
+type Repositories struct {
+	Rewards Repository
+	Ledger  ledger.Repository
+}
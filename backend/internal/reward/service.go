@@ -0,0 +1,118 @@
+package reward
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	ledgerdomain "github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+)
+
+var (
+	ErrRewardDoesNotExist = fmt.Errorf("reward does not exist")
+	ErrRewardNotAvailable = fmt.Errorf("reward is not available")
+	ErrRewardOutOfStock   = fmt.Errorf("reward is out of stock")
+	ErrInsufficientPoints = fmt.Errorf("insufficient points")
+)
+
+type Service struct {
+	r   Repository
+	uow db.UnitOfWork[Repositories]
+	cg  VoucherCodeGenerator
+}
+
+func NewService(r Repository, uow db.UnitOfWork[Repositories], cg VoucherCodeGenerator) *Service {
+	return &Service{r: r, uow: uow, cg: cg}
+}
+
+// GetRewards returns the catalog of rewards that can currently be redeemed.
+func (s *Service) GetRewards() ([]domain.Reward, error) {
+	rewards, err := s.r.GetRewards(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("GetRewards(): failed to get rewards: %w", err)
+	}
+
+	return rewards, nil
+}
+
+// Redeem spends the user's points on the reward and returns the redemption with its voucher code.
+// Taking the stock, debiting the points and recording the redemption happen in one database
+// transaction, so a failure in any of them leaves everything as it was.
+func (s *Service) Redeem(userID string, rewardID int) (domain.Redemption, error) {
+	code, err := s.cg.Generate()
+	if err != nil {
+		return domain.Redemption{}, fmt.Errorf("Redeem(): failed to generate voucher code: %w", err)
+	}
+
+	var redemption domain.Redemption
+
+	err = s.uow.Do(func(repos Repositories) error {
+		now := time.Now()
+
+		reward, err := repos.Rewards.GetReward(rewardID)
+		if err != nil {
+			return fmt.Errorf("failed to get reward with id %d: %w", rewardID, err)
+		}
+
+		if !reward.IsValidAt(now) {
+			return fmt.Errorf("%w with id %d", ErrRewardNotAvailable, rewardID)
+		}
+
+		ok, err := repos.Rewards.TakeStock(rewardID)
+		if err != nil {
+			return fmt.Errorf("failed to take stock: %w", err)
+		}
+
+		if !ok {
+			return fmt.Errorf("%w with id %d", ErrRewardOutOfStock, rewardID)
+		}
+
+		reference := code.String()
+
+		entry, err := ledgerdomain.NewEntry(
+			userID,
+			ledgerdomain.EntryKindRedemption,
+			-reward.Cost,
+			nil,
+			&reference,
+			reward.Name,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create redemption entry: %w", err)
+		}
+
+		if err = repos.Ledger.AppendEntry(entry); err != nil {
+			if errors.Is(err, ledger.ErrInsufficientBalance) {
+				return fmt.Errorf("%w for reward with id %d", ErrInsufficientPoints, rewardID)
+			}
+
+			return fmt.Errorf("failed to debit points: %w", err)
+		}
+
+		redemption = domain.NewRedemption(userID, *reward, code, now)
+
+		if err = repos.Rewards.CreateRedemption(redemption); err != nil {
+			return fmt.Errorf("failed to create redemption: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return domain.Redemption{}, fmt.Errorf("Redeem(): %w", err)
+	}
+
+	return redemption, nil
+}
+
+func (s *Service) GetRedemptions(userID string) ([]domain.Redemption, error) {
+	redemptions, err := s.r.GetRedemptions(userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetRedemptions(): failed to get redemptions: %w", err)
+	}
+
+	return redemptions, nil
+}
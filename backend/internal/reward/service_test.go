@@ -0,0 +1,240 @@
+package reward_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	ledgerdomain "github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/reward"
+)
+
+const concurrentRedemptions = 20
+
+func newService(dbHandle *db.DB) *reward.Service {
+	return reward.NewService(
+		reward.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) reward.Repositories {
+			return reward.Repositories{
+				Rewards: reward.NewSQLRepository(q),
+				Ledger:  ledger.NewSQLRepository(q),
+			}
+		}),
+		reward.NewRandomVoucherCodeGenerator(),
+	)
+}
+
+// createReward adds a reward to the catalog and returns its id. A nil stock is unlimited.
+func createReward(t *testing.T, dbHandle *db.DB, cost int, stock *int, validFrom, validUntil *time.Time) int {
+	t.Helper()
+
+	var rewardID int
+	if err := dbHandle.Get(&rewardID, `
+		INSERT INTO
+			rewards (name, cost, stock, valid_from, valid_until)
+		VALUES
+			(?, ?, ?, ?, ?)
+		RETURNING
+			reward_id
+	`, "Test reward", cost, stock, validFrom, validUntil); err != nil {
+		t.Fatalf("failed to create reward: %v", err)
+	}
+
+	return rewardID
+}
+
+// createUserWithPoints adds a user whose balance is the given points.
+func createUserWithPoints(t *testing.T, dbHandle *db.DB, userID string, points int) {
+	t.Helper()
+
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	entry, err := ledgerdomain.NewEntry(userID, ledgerdomain.EntryKindEarning, points, nil, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("failed to create earning entry: %v", err)
+	}
+
+	if err = ledger.NewSQLRepository(dbHandle).AppendEntry(entry); err != nil {
+		t.Fatalf("failed to credit points: %v", err)
+	}
+}
+
+func getStock(t *testing.T, dbHandle *db.DB, rewardID int) int {
+	t.Helper()
+
+	var stock int
+	if err := dbHandle.Get(&stock, "SELECT stock FROM rewards WHERE reward_id = ?", rewardID); err != nil {
+		t.Fatalf("failed to get stock: %v", err)
+	}
+
+	return stock
+}
+
+func getBalance(t *testing.T, dbHandle *db.DB, userID string) int {
+	t.Helper()
+
+	balance, err := ledger.NewSQLRepository(dbHandle).GetBalance(userID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+
+	return balance
+}
+
+func TestRedeem(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+
+	one := 1
+	none := 0
+
+	for _, tc := range []struct {
+		name       string
+		points     int
+		cost       int
+		stock      *int
+		validFrom  *time.Time
+		validUntil *time.Time
+		want       error
+	}{
+		{name: "in stock", points: 100, cost: 40, stock: &one},
+		{name: "unlimited stock", points: 100, cost: 40},
+		{name: "exact balance", points: 40, cost: 40, validFrom: &yesterday, validUntil: &tomorrow},
+		{name: "insufficient points", points: 39, cost: 40, stock: &one, want: reward.ErrInsufficientPoints},
+		{name: "out of stock", points: 100, cost: 40, stock: &none, want: reward.ErrRewardOutOfStock},
+		{name: "not valid yet", points: 100, cost: 40, validFrom: &tomorrow, want: reward.ErrRewardNotAvailable},
+		{name: "no longer valid", points: 100, cost: 40, validUntil: &yesterday, want: reward.ErrRewardNotAvailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			s := newService(dbHandle)
+
+			const userID = "user-1"
+			createUserWithPoints(t, dbHandle, userID, tc.points)
+
+			rewardID := createReward(t, dbHandle, tc.cost, tc.stock, tc.validFrom, tc.validUntil)
+
+			redemption, err := s.Redeem(userID, rewardID)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			wantBalance := tc.points
+			wantStock := tc.stock
+
+			if tc.want == nil {
+				if redemption.VoucherCode == "" {
+					t.Error("got no voucher code")
+				}
+
+				wantBalance -= tc.cost
+
+				if tc.stock != nil {
+					left := *tc.stock - 1
+					wantStock = &left
+				}
+			}
+
+			if got := getBalance(t, dbHandle, userID); got != wantBalance {
+				t.Errorf("got balance %d, want %d", got, wantBalance)
+			}
+
+			if wantStock != nil {
+				if got := getStock(t, dbHandle, rewardID); got != *wantStock {
+					t.Errorf("got stock %d, want %d", got, *wantStock)
+				}
+			}
+		})
+	}
+}
+
+func TestRedeemUnknownReward(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	const userID = "user-1"
+	createUserWithPoints(t, dbHandle, userID, 100)
+
+	if _, err := s.Redeem(userID, 1); !errors.Is(err, reward.ErrRewardDoesNotExist) {
+		t.Errorf("got error %v, want %v", err, reward.ErrRewardDoesNotExist)
+	}
+}
+
+func TestRedeemLastUnitConcurrently(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	one := 1
+	rewardID := createReward(t, dbHandle, 10, &one, nil, nil)
+
+	userIDs := make([]string, concurrentRedemptions)
+	for i := range userIDs {
+		userIDs[i] = fmt.Sprintf("user-%d", i)
+		createUserWithPoints(t, dbHandle, userIDs[i], 10)
+	}
+
+	var wg sync.WaitGroup
+	redeemed := make(chan string, concurrentRedemptions)
+	errs := make(chan error, concurrentRedemptions)
+
+	start := make(chan struct{})
+
+	for _, userID := range userIDs {
+		wg.Add(1)
+
+		go func(userID string) {
+			defer wg.Done()
+			<-start
+
+			_, err := s.Redeem(userID, rewardID)
+			if err == nil {
+				redeemed <- userID
+				return
+			}
+
+			if !errors.Is(err, reward.ErrRewardOutOfStock) {
+				errs <- err
+			}
+		}(userID)
+	}
+
+	close(start)
+	wg.Wait()
+	close(redeemed)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("failed to redeem reward: %v", err)
+	}
+
+	var winners []string
+	for userID := range redeemed {
+		winners = append(winners, userID)
+	}
+
+	if len(winners) != 1 {
+		t.Fatalf("got %d successful redemptions, want 1", len(winners))
+	}
+
+	// Only the winner paid for the reward.
+	for _, userID := range userIDs {
+		want := 10
+		if userID == winners[0] {
+			want = 0
+		}
+
+		if got := getBalance(t, dbHandle, userID); got != want {
+			t.Errorf("user %s: got balance %d, want %d", userID, got, want)
+		}
+	}
+
+	if got := getStock(t, dbHandle, rewardID); got != 0 {
+		t.Errorf("got stock %d, want 0", got)
+	}
+}
@@ -0,0 +1,178 @@
+package reward
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+)
+
+type reward struct {
+	RewardID    int           `db:"reward_id"`
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	Cost        int           `db:"cost"`
+	Stock       sql.NullInt64 `db:"stock"`
+	ValidFrom   sql.NullTime  `db:"valid_from"`
+	ValidUntil  sql.NullTime  `db:"valid_until"`
+}
+
+func (r reward) toDomain() domain.Reward {
+	var stock *int
+	if r.Stock.Valid {
+		s := int(r.Stock.Int64)
+		stock = &s
+	}
+
+	var validFrom, validUntil *time.Time
+	if r.ValidFrom.Valid {
+		validFrom = &r.ValidFrom.Time
+	}
+
+	if r.ValidUntil.Valid {
+		validUntil = &r.ValidUntil.Time
+	}
+
+	return domain.NewReward(r.RewardID, r.Name, r.Description, r.Cost, stock, validFrom, validUntil)
+}
+
+type redemption struct {
+	RedemptionID int64     `db:"redemption_id"`
+	UserID       string    `db:"user_id"`
+	RewardID     int       `db:"reward_id"`
+	RewardName   string    `db:"reward_name"`
+	Cost         int       `db:"cost"`
+	VoucherCode  string    `db:"voucher_code"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (rr *SQLRepository) GetRewards(at time.Time) ([]domain.Reward, error) {
+	var raw []reward
+	if err := rr.db.Select(&raw, `
+		SELECT
+			reward_id, name, description, cost, stock, valid_from, valid_until
+		FROM
+			rewards
+		WHERE
+			(valid_from IS NULL OR valid_from <= ?)
+			AND (valid_until IS NULL OR valid_until > ?)
+		ORDER BY
+			cost, reward_id
+	`, at, at); err != nil {
+		return nil, fmt.Errorf("GetRewards(): failed to execute query: %w", err)
+	}
+
+	rewards := make([]domain.Reward, 0, len(raw))
+	for _, r := range raw {
+		rewards = append(rewards, r.toDomain())
+	}
+
+	return rewards, nil
+}
+
+func (rr *SQLRepository) GetReward(id int) (*domain.Reward, error) {
+	var raw reward
+	if err := rr.db.Get(&raw, `
+		SELECT
+			reward_id, name, description, cost, stock, valid_from, valid_until
+		FROM
+			rewards
+		WHERE
+			reward_id = ?
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRewardDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetReward(): failed to execute query: %w", err)
+	}
+
+	r := raw.toDomain()
+	return &r, nil
+}
+
+func (rr *SQLRepository) TakeStock(rewardID int) (bool, error) {
+	// Like the transaction claim, the condition makes this a compare-and-swap,
+	// so concurrent redemptions can't take the last unit twice.
+	res, err := rr.db.Exec(`
+		UPDATE
+			rewards
+		SET
+			stock = stock - 1
+		WHERE
+			reward_id = ? AND (stock IS NULL OR stock > 0)
+	`, rewardID)
+	if err != nil {
+		return false, fmt.Errorf("TakeStock(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("TakeStock(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (rr *SQLRepository) CreateRedemption(r domain.Redemption) error {
+	if _, err := rr.db.Exec(`
+		INSERT INTO
+			redemptions (user_id, reward_id, cost, voucher_code, created_at)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`, r.UserID, r.RewardID, r.Cost, r.VoucherCode, r.CreatedAt); err != nil {
+		return fmt.Errorf("CreateRedemption(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (rr *SQLRepository) GetRedemptions(userID string) ([]domain.Redemption, error) {
+	var raw []redemption
+	if err := rr.db.Select(&raw, `
+		SELECT
+			redemptions.redemption_id,
+			redemptions.user_id,
+			redemptions.reward_id,
+			rewards.name AS reward_name,
+			redemptions.cost,
+			redemptions.voucher_code,
+			redemptions.created_at
+		FROM
+			redemptions
+		INNER JOIN
+			rewards ON rewards.reward_id = redemptions.reward_id
+		WHERE
+			redemptions.user_id = ?
+		ORDER BY
+			redemptions.created_at DESC, redemptions.redemption_id DESC
+	`, userID); err != nil {
+		return nil, fmt.Errorf("GetRedemptions(): failed to execute query: %w", err)
+	}
+
+	redemptions := make([]domain.Redemption, 0, len(raw))
+	for _, r := range raw {
+		redemptions = append(redemptions, domain.Redemption{
+			ID:          r.RedemptionID,
+			UserID:      r.UserID,
+			RewardID:    r.RewardID,
+			RewardName:  r.RewardName,
+			Cost:        r.Cost,
+			VoucherCode: domain.VoucherCode(r.VoucherCode),
+			CreatedAt:   r.CreatedAt,
+		})
+	}
+
+	return redemptions, nil
+}
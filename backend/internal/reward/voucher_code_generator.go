@@ -0,0 +1,7 @@
+package reward
+
+import "github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+
+type VoucherCodeGenerator interface {
+	Generate() (domain.VoucherCode, error)
+}
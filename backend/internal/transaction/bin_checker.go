@@ -0,0 +1,6 @@
+package transaction
+
+// BinChecker tells whether a machine's bin has room for more containers.
+type BinChecker interface {
+	IsBinFull(machineID int) (bool, error)
+}
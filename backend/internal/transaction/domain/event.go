@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+type EventKind string
+
+const (
+	// EventKindItemAdded is published whenever the machine adds an item to the open transaction.
+	EventKindItemAdded EventKind = "item_added"
+	// EventKindClosed is published when the machine finishes the session and the transaction can be claimed.
+	EventKindClosed EventKind = "closed"
+	// EventKindCancelled is published when the session is aborted before anyone claimed it.
+	EventKindCancelled EventKind = "cancelled"
+	// EventKindClaimed is published when a user claims the transaction's points.
+	EventKindClaimed EventKind = "claimed"
+	// EventKindExpired is published when the transaction was left open or unclaimed for too long.
+	EventKindExpired EventKind = "expired"
+)
+
+func (k EventKind) String() string {
+	return string(k)
+}
+
+// IsTerminal reports whether no further events follow this one.
+func (k EventKind) IsTerminal() bool {
+	return k == EventKindClaimed || k == EventKindCancelled || k == EventKindExpired
+}
+
+// Event is something that happened to a transaction, for those following it as it happens.
+type Event struct {
+	Kind          EventKind
+	TransactionID TransactionID
+	// ItemID and ItemCount are set on item_added events. ItemCount includes the added item.
+	ItemID    int
+	ItemCount int
+	// UserID and Points are set on claimed events.
+	UserID     string
+	Points     int
+	OccurredAt time.Time
+}
@@ -3,15 +3,50 @@ package domain
 import "time"
 
 type Transaction struct {
-	ID        TransactionID
-	UserID    string
+	ID     TransactionID
+	UserID string
+	// MachineID is the machine that started the transaction. It is nil for transactions
+	// started before machines were registered.
+	MachineID *int
+	Status    TransactionStatus
 	CreatedAt time.Time
+	ClosedAt  *time.Time
 }
 
-func NewTransaction(id TransactionID, userID string, createdAt time.Time) Transaction {
+func NewTransaction(
+	id TransactionID,
+	userID string,
+	machineID *int,
+	status TransactionStatus,
+	createdAt time.Time,
+	closedAt *time.Time,
+) Transaction {
 	return Transaction{
 		ID:        id,
 		UserID:    userID,
+		MachineID: machineID,
+		Status:    status,
 		CreatedAt: createdAt,
+		ClosedAt:  closedAt,
 	}
 }
+
+// ExpiresAt returns the moment the transaction can no longer be claimed or added to.
+// Only open and closed transactions expire; ok is false for every other status.
+func (t Transaction) ExpiresAt(claimWindow time.Duration, sessionTimeout time.Duration) (time.Time, bool) {
+	switch t.Status {
+	case TransactionStatusOpen:
+		return t.CreatedAt.Add(sessionTimeout), true
+	case TransactionStatusClosed:
+		// Transactions closed before closing times were recorded count from their creation.
+		if t.ClosedAt == nil {
+			return t.CreatedAt.Add(claimWindow), true
+		}
+
+		return t.ClosedAt.Add(claimWindow), true
+	case TransactionStatusClaimed, TransactionStatusCancelled, TransactionStatusExpired:
+		return time.Time{}, false
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "fmt"
+
+type TransactionStatus string
+
+const (
+	// TransactionStatusOpen means the machine is still accepting items for this transaction.
+	TransactionStatusOpen TransactionStatus = "open"
+	// TransactionStatusClosed means the machine has finished the session and the transaction is waiting to be claimed.
+	TransactionStatusClosed TransactionStatus = "closed"
+	// TransactionStatusClaimed means a user has claimed the transaction's points.
+	TransactionStatusClaimed TransactionStatus = "claimed"
+	// TransactionStatusCancelled means the session was aborted before anyone claimed it.
+	TransactionStatusCancelled TransactionStatus = "cancelled"
+	// TransactionStatusExpired means nobody claimed the transaction in time.
+	TransactionStatusExpired TransactionStatus = "expired"
+)
+
+// allowedTransitions lists, for every status, the statuses it may move to.
+// Claimed, cancelled and expired are terminal.
+func allowedTransitions(from TransactionStatus) []TransactionStatus {
+	switch from {
+	case TransactionStatusOpen:
+		return []TransactionStatus{TransactionStatusClosed, TransactionStatusCancelled, TransactionStatusExpired}
+	case TransactionStatusClosed:
+		return []TransactionStatus{TransactionStatusClaimed, TransactionStatusCancelled, TransactionStatusExpired}
+	case TransactionStatusClaimed, TransactionStatusCancelled, TransactionStatusExpired:
+		return nil
+	}
+
+	return nil
+}
+
+func NewTransactionStatus(value string) (TransactionStatus, error) {
+	status := TransactionStatus(value)
+
+	switch status {
+	case TransactionStatusOpen,
+		TransactionStatusClosed,
+		TransactionStatusClaimed,
+		TransactionStatusCancelled,
+		TransactionStatusExpired:
+		return status, nil
+	}
+
+	return "", fmt.Errorf("unknown transaction status %q", value)
+}
+
+// CanTransitionTo reports whether a transaction in this status may move to next.
+func (s TransactionStatus) CanTransitionTo(next TransactionStatus) bool {
+	for _, allowed := range allowedTransitions(s) {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTerminal reports whether no further transitions are possible from this status.
+func (s TransactionStatus) IsTerminal() bool {
+	return len(allowedTransitions(s)) == 0
+}
+
+func (s TransactionStatus) String() string {
+	return string(s)
+}
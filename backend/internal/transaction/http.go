@@ -1,55 +1,131 @@
 package transaction
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
 	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	itemdomain "github.com/JosephJoshua/rvm/backend/internal/item/domain"
 	"github.com/JosephJoshua/rvm/backend/internal/logging"
 	"github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v2"
 )
 
+// keepAliveInterval is how often an idle event stream gets a comment, so that proxies don't close it.
+const keepAliveInterval = 15 * time.Second
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeBinFull                    = "bin_full"
+	codeTransactionDoesNotExist    = "transaction_does_not_exist"
+	codeTransactionNotOpen         = "transaction_not_open"
+	codeTransactionAlreadyAssigned = "transaction_already_assigned"
+	codeTransactionExpired         = "transaction_expired"
+	codeInvalidStatusTransition    = "invalid_status_transition"
+	codeItemDoesNotExist           = "item_does_not_exist"
+	codeItemArchived               = "item_archived"
+	codeUnknownBarcode             = "unknown_barcode"
+	codeInvalidBarcode             = "invalid_barcode"
+	codeCaptureDoesNotExist        = "capture_does_not_exist"
+	codeUserDoesNotExist           = "user_does_not_exist"
+)
+
+type startResponse struct {
+	TransactionID string `json:"transaction_id"`
+}
+
+type itemCountResponse struct {
+	ItemCount int `json:"item_count"`
+}
+
+type claimResponse struct {
+	Points int `json:"points"`
+}
+
+type snapshotResponse struct {
+	TransactionID string `json:"transaction_id"`
+	Status        string `json:"status"`
+	ItemCount     int    `json:"item_count"`
+}
+
+type eventResponse struct {
+	TransactionID string    `json:"transaction_id"`
+	ItemID        int       `json:"item_id,omitempty"`
+	ItemCount     int       `json:"item_count,omitempty"`
+	UserID        string    `json:"user_id,omitempty"`
+	Points        *int      `json:"points,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
 type HTTPHandler struct {
-	http.Handler
+	chi.Router
 	s *Service
 }
 
 // NewHTTPHandler creates a new transaction HTTP handler.
-//   - POST /transactions - starts a new transaction and returns the transaction code.
-//   - POST /transactions/{transactionID}/items - adds an item to the transaction.
-//     item_id is a form value or query parameter.
-//   - DELETE /transactions/{transactionID} - ends the transaction and assigns the user to the transaction.
+// It expects apitoken.ValidTokenMiddleware to run before it. Except for starting and ending a transaction,
+// the routes only see the transactions the token's machine started; other transactions get a 404.
+//   - POST /transactions - starts a new transaction for the token's machine and returns its transaction_id.
+//     Machines whose bin is full get a 409 until it is emptied.
+//   - POST /transactions/{transactionID}/items - adds an item to the transaction and returns its item_count.
+//     Either item_id or barcode (a GTIN) is a form value or query parameter.
+//     Barcodes that are invalid or don't belong to any item get a 422.
+//     capture_request_id optionally links the item to the capture it was classified from.
+//     Transactions whose session has timed out get a 410.
+//   - POST /transactions/{transactionID}/close - closes the transaction so that it can be claimed.
+//   - POST /transactions/{transactionID}/cancel - cancels a transaction that hasn't been claimed yet.
+//   - POST /transactions/{transactionID}/end - ends the transaction, assigns the user to the transaction
+//     and returns the points the user was awarded.
 //     user_id is a form value or query parameter.
+//   - GET /transactions/{transactionID}/events - streams what happens to the transaction as server-sent events.
+//     The first event, snapshot, has the transaction's status and item count. It is followed by item_added,
+//     closed, cancelled, claimed and expired events as they happen. The stream ends after the transaction
+//     is claimed, cancelled or expired; if it ends before that, the client should reconnect.
 func NewHTTPHandler(s *Service) *HTTPHandler {
 	handler := &HTTPHandler{s: s}
 
-	r := chi.NewRouter()
+	r := httputils.NewRouter()
 
 	r.Post("/", httputils.HandlerFunc(handler.startTransaction))
 	r.Post("/{transactionID}/items", httputils.HandlerFunc(handler.addItemToTransaction))
+	r.Post("/{transactionID}/close", httputils.HandlerFunc(handler.closeTransaction))
+	r.Post("/{transactionID}/cancel", httputils.HandlerFunc(handler.cancelTransaction))
 	r.Post("/{transactionID}/end", httputils.HandlerFunc(handler.endTransactionAndAssignUser))
+	r.Get("/{transactionID}/events", httputils.HandlerFunc(handler.streamEvents))
 
-	handler.Handler = r
+	handler.Router = r
 	return handler
 }
 
 func (h *HTTPHandler) startTransaction(w httputils.ResponseWriter, r *http.Request) {
 	oplog := httplog.LogEntry(r.Context())
 
-	code, err := h.s.StartTransaction()
+	machineID := apitoken.MachineIDFromCtx(r.Context())
+
+	code, err := h.s.StartTransaction(machineID)
 	if err != nil {
-		oplog.Error("failed to start transaction", logging.ErrAttr(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		if errors.Is(err, ErrBinFull) {
+			oplog.Info("machine bin is full", slog.Int("machine_id", machineID))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeBinFull, "machine bin is full")
+
+			return
+		}
+
+		oplog.Error("failed to start transaction", logging.ErrAttr(err), slog.Int("machine_id", machineID))
+		w.TryWriteInternalError(&oplog)
 
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	w.TryWrite(&oplog, []byte(code))
+	w.TryWriteNegotiated(&oplog, http.StatusCreated, startResponse{TransactionID: code.String()}, code.String())
 }
 
 func (h *HTTPHandler) addItemToTransaction(w httputils.ResponseWriter, r *http.Request) {
@@ -57,20 +133,22 @@ func (h *HTTPHandler) addItemToTransaction(w httputils.ResponseWriter, r *http.R
 
 	transactionIDStr := chi.URLParam(r, "transactionID")
 	itemIDStr := r.FormValue("item_id")
+	barcodeStr := r.FormValue("barcode")
 
-	if itemIDStr == "" {
-		oplog.Error("item_id is empty")
-
-		w.WriteHeader(http.StatusBadRequest)
-		w.TryWrite(&oplog, []byte("item_id is required"))
+	var captureRequestID *string
+	if v := r.FormValue("capture_request_id"); v != "" {
+		captureRequestID = &v
 	}
 
-	itemID, err := strconv.Atoi(itemIDStr)
-	if err != nil {
-		oplog.Error("failed to convert item_id to int", logging.ErrAttr(err))
+	if (itemIDStr == "") == (barcodeStr == "") {
+		oplog.Error("expected exactly one of item_id and barcode")
 
-		w.WriteHeader(http.StatusBadRequest)
-		w.TryWrite(&oplog, []byte("item_id has to be an integer"))
+		w.TryWriteProblem(
+			&oplog,
+			http.StatusBadRequest,
+			httputils.CodeInvalidRequest,
+			"exactly one of item_id and barcode is required",
+		)
 
 		return
 	}
@@ -78,25 +156,105 @@ func (h *HTTPHandler) addItemToTransaction(w httputils.ResponseWriter, r *http.R
 	transactionID, err := domain.NewTransactionID(transactionIDStr)
 	if err != nil {
 		oplog.Error("failed to create transaction id", logging.ErrAttr(err))
-		w.WriteHeader(http.StatusNotFound)
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
 
 		return
 	}
 
-	c, err := h.s.AddItemToTransaction(transactionID, itemID)
+	machineID := apitoken.MachineIDFromCtx(r.Context())
+
+	var c int
+	var itemAttr slog.Attr
+
+	if barcodeStr != "" {
+		itemAttr = slog.String("barcode", barcodeStr)
+
+		var barcode itemdomain.GTIN
+
+		barcode, err = itemdomain.NewGTIN(barcodeStr)
+		if err != nil {
+			oplog.Error("invalid barcode", logging.ErrAttr(err))
+
+			// The machine can't tell a misread barcode from an unknown one; it rejects the container either way.
+			w.TryWriteProblem(&oplog, http.StatusUnprocessableEntity, codeInvalidBarcode, "barcode is not a valid GTIN")
+
+			return
+		}
+
+		c, err = h.s.AddItemToTransactionByBarcode(transactionID, machineID, barcode, captureRequestID)
+	} else {
+		var itemID int
+
+		itemID, err = strconv.Atoi(itemIDStr)
+		if err != nil {
+			oplog.Error("failed to convert item_id to int", logging.ErrAttr(err))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusBadRequest,
+				httputils.CodeInvalidRequest,
+				"item_id has to be an integer",
+			)
+
+			return
+		}
+
+		itemAttr = slog.Int("item_id", itemID)
+		c, err = h.s.AddItemToTransaction(transactionID, machineID, itemID, captureRequestID)
+	}
+
 	if err != nil {
 		if errors.Is(err, ErrTransactionDoesNotExist) {
 			oplog.Error("transaction not found", slog.String("transaction_id", transactionID.String()))
-			w.WriteHeader(http.StatusNotFound)
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrTransactionExpired) {
+			oplog.Error("transaction has expired", slog.String("transaction_id", transactionID.String()))
+
+			w.TryWriteProblem(&oplog, http.StatusGone, codeTransactionExpired, "transaction has expired")
+
+			return
+		}
+
+		if errors.Is(err, ErrTransactionNotOpen) {
+			oplog.Error("transaction is not open", slog.String("transaction_id", transactionID.String()))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeTransactionNotOpen, "transaction is not open")
+
+			return
+		}
+
+		if errors.Is(err, ErrUnknownBarcode) {
+			oplog.Info("unknown barcode", itemAttr)
+
+			w.TryWriteProblem(&oplog, http.StatusUnprocessableEntity, codeUnknownBarcode, "unknown barcode")
 
 			return
 		}
 
 		if errors.Is(err, ErrItemDoesNotExist) {
-			oplog.Error("item not found", slog.Int("item_id", itemID))
+			oplog.Error("item not found", itemAttr)
+
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, codeItemDoesNotExist, "item not found")
 
-			w.WriteHeader(http.StatusBadRequest)
-			w.TryWrite(&oplog, []byte("item not found"))
+			return
+		}
+
+		if errors.Is(err, ErrCaptureDoesNotExist) {
+			oplog.Error("capture not found", slog.String("capture_request_id", *captureRequestID))
+
+			w.TryWriteProblem(&oplog, http.StatusUnprocessableEntity, codeCaptureDoesNotExist, "capture not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrItemArchived) {
+			oplog.Error("item is archived", itemAttr)
+
+			w.TryWriteProblem(&oplog, http.StatusUnprocessableEntity, codeItemArchived, "item is archived")
 
 			return
 		}
@@ -105,14 +263,73 @@ func (h *HTTPHandler) addItemToTransaction(w httputils.ResponseWriter, r *http.R
 			"failed to add item to transaction",
 			logging.ErrAttr(err),
 			slog.String("transaction_id", transactionID.String()),
-			slog.Int("item_id", itemID),
+			itemAttr,
+		)
+
+		w.TryWriteInternalError(&oplog)
+		return
+	}
+
+	w.TryWriteNegotiated(&oplog, http.StatusOK, itemCountResponse{ItemCount: c}, strconv.Itoa(c))
+}
+
+func (h *HTTPHandler) closeTransaction(w httputils.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, domain.TransactionStatusClosed, h.s.CloseTransaction)
+}
+
+func (h *HTTPHandler) cancelTransaction(w httputils.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, domain.TransactionStatusCancelled, h.s.CancelTransaction)
+}
+
+func (h *HTTPHandler) changeStatus(
+	w httputils.ResponseWriter,
+	r *http.Request,
+	to domain.TransactionStatus,
+	fn func(domain.TransactionID, int) error,
+) {
+	oplog := httplog.LogEntry(r.Context())
+
+	transactionID, err := domain.NewTransactionID(chi.URLParam(r, "transactionID"))
+	if err != nil {
+		oplog.Error("failed to create transaction id", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
+
+		return
+	}
+
+	if err = fn(transactionID, apitoken.MachineIDFromCtx(r.Context())); err != nil {
+		if errors.Is(err, ErrTransactionDoesNotExist) {
+			oplog.Error("transaction not found", slog.String("transaction_id", transactionID.String()))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrInvalidStatusTransition) {
+			oplog.Error("invalid status transition", logging.ErrAttr(err), slog.String("to", to.String()))
+
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusConflict,
+				codeInvalidStatusTransition,
+				"transaction cannot be "+to.String(),
+			)
+
+			return
+		}
+
+		oplog.Error(
+			"failed to change transaction status",
+			logging.ErrAttr(err),
+			slog.String("to", to.String()),
+			slog.String("transaction_id", transactionID.String()),
 		)
 
-		w.WriteHeader(http.StatusInternalServerError)
+		w.TryWriteInternalError(&oplog)
 		return
 	}
 
-	w.TryWrite(&oplog, []byte(strconv.Itoa(c)))
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *HTTPHandler) endTransactionAndAssignUser(w httputils.ResponseWriter, r *http.Request) {
@@ -124,8 +341,7 @@ func (h *HTTPHandler) endTransactionAndAssignUser(w httputils.ResponseWriter, r
 	if userID == "" {
 		oplog.Error("user_id is empty")
 
-		w.WriteHeader(http.StatusBadRequest)
-		w.TryWrite(&oplog, []byte("user_id is required"))
+		w.TryWriteProblem(&oplog, http.StatusBadRequest, httputils.CodeInvalidRequest, "user_id is required")
 
 		return
 	}
@@ -133,7 +349,7 @@ func (h *HTTPHandler) endTransactionAndAssignUser(w httputils.ResponseWriter, r
 	transactionID, err := domain.NewTransactionID(transactionIDStr)
 	if err != nil {
 		oplog.Error("failed to create transaction id", logging.ErrAttr(err))
-		w.WriteHeader(http.StatusNotFound)
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
 
 		return
 	}
@@ -142,14 +358,35 @@ func (h *HTTPHandler) endTransactionAndAssignUser(w httputils.ResponseWriter, r
 	if err != nil {
 		if errors.Is(err, ErrTransactionAlreadyAssigned) {
 			oplog.Error("transaction is already assigned", slog.String("transaction_id", transactionIDStr))
-			w.WriteHeader(http.StatusConflict)
+			w.TryWriteProblem(
+				&oplog,
+				http.StatusConflict,
+				codeTransactionAlreadyAssigned,
+				"transaction is already assigned",
+			)
 
 			return
 		}
 
 		if errors.Is(err, ErrTransactionDoesNotExist) {
 			oplog.Error("transaction not found", slog.String("transaction_id", transactionID.String()))
-			w.WriteHeader(http.StatusNotFound)
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrTransactionExpired) {
+			oplog.Error("transaction has expired", slog.String("transaction_id", transactionID.String()))
+
+			w.TryWriteProblem(&oplog, http.StatusGone, codeTransactionExpired, "transaction has expired")
+
+			return
+		}
+
+		if errors.Is(err, ErrInvalidStatusTransition) {
+			oplog.Error("transaction cannot be claimed", logging.ErrAttr(err))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeInvalidStatusTransition, "transaction cannot be claimed")
 
 			return
 		}
@@ -157,8 +394,7 @@ func (h *HTTPHandler) endTransactionAndAssignUser(w httputils.ResponseWriter, r
 		if errors.Is(err, ErrUserDoesNotExist) {
 			oplog.Error("user not found", slog.String("user_id", userID))
 
-			w.WriteHeader(http.StatusBadRequest)
-			w.TryWrite(&oplog, []byte("user not found"))
+			w.TryWriteProblem(&oplog, http.StatusBadRequest, codeUserDoesNotExist, "user not found")
 
 			return
 		}
@@ -170,9 +406,121 @@ func (h *HTTPHandler) endTransactionAndAssignUser(w httputils.ResponseWriter, r
 			slog.String("user_id", userID),
 		)
 
-		w.WriteHeader(http.StatusInternalServerError)
+		w.TryWriteInternalError(&oplog)
+		return
+	}
+
+	w.TryWriteNegotiated(&oplog, http.StatusOK, claimResponse{Points: c}, strconv.Itoa(c))
+}
+
+func (h *HTTPHandler) streamEvents(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	transactionID, err := domain.NewTransactionID(chi.URLParam(r, "transactionID"))
+	if err != nil {
+		oplog.Error("failed to create transaction id", logging.ErrAttr(err))
+		w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
+
+		return
+	}
+
+	sub, err := h.s.Subscribe(transactionID, apitoken.MachineIDFromCtx(r.Context()))
+	if err != nil {
+		if errors.Is(err, ErrTransactionDoesNotExist) {
+			oplog.Error("transaction not found", slog.String("transaction_id", transactionID.String()))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeTransactionDoesNotExist, "transaction not found")
+
+			return
+		}
+
+		oplog.Error("failed to subscribe to transaction", logging.ErrAttr(err))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	defer sub.Unsubscribe()
+
+	rc := http.NewResponseController(w.ResponseWriter)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, v any) bool {
+		data, err := json.Marshal(v)
+		if err != nil {
+			oplog.Error("failed to marshal event", logging.ErrAttr(err))
+			return false
+		}
+
+		return writeToStream(&oplog, w, rc, fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+	}
+
+	if !send("snapshot", snapshotResponse{
+		TransactionID: transactionID.String(),
+		Status:        sub.Transaction.Status.String(),
+		ItemCount:     sub.ItemCount,
+	}) {
 		return
 	}
 
-	w.TryWrite(&oplog, []byte(strconv.Itoa(c)))
+	if sub.Transaction.Status.IsTerminal() {
+		return
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if !writeToStream(&oplog, w, rc, ": keep-alive\n\n") {
+				return
+			}
+		case e, ok := <-sub.Events:
+			// The hub let go of us, either because we fell behind or because the server is shutting down.
+			if !ok {
+				return
+			}
+
+			if !send(e.Kind.String(), toEventResponse(e)) || e.Kind.IsTerminal() {
+				return
+			}
+		}
+	}
+}
+
+func writeToStream(oplog *slog.Logger, w httputils.ResponseWriter, rc *http.ResponseController, msg string) bool {
+	if ok, _ := w.TryWrite(oplog, []byte(msg)); !ok {
+		return false
+	}
+
+	if err := rc.Flush(); err != nil {
+		oplog.Error("failed to flush event stream", logging.ErrAttr(err))
+		return false
+	}
+
+	return true
+}
+
+func toEventResponse(e domain.Event) eventResponse {
+	res := eventResponse{
+		TransactionID: e.TransactionID.String(),
+		ItemID:        e.ItemID,
+		ItemCount:     e.ItemCount,
+		UserID:        e.UserID,
+		OccurredAt:    e.OccurredAt,
+	}
+
+	// A claim can be worth 0 points, unlike an added item's id or count, which are never 0.
+	if e.Kind == domain.EventKindClaimed {
+		res.Points = &e.Points
+	}
+
+	return res
 }
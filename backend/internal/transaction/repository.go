@@ -3,17 +3,55 @@ package transaction
 import (
 	"time"
 
+	itemdomain "github.com/JosephJoshua/rvm/backend/internal/item/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
 	"github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
 )
 
+// Repositories are the repositories a Service uses inside a unit of work, all sharing its transaction.
+type Repositories struct {
+	Transactions Repository
+	Ledger       ledger.Repository
+}
+
 type Repository interface {
-	DoesTransactionExist(id domain.TransactionID) (bool, error)
+	// GetTransaction returns ErrTransactionDoesNotExist if there is no transaction with the given id.
+	GetTransaction(id domain.TransactionID) (*domain.Transaction, error)
+	// DoesItemExist reports whether the item exists, archived or not.
 	DoesItemExist(itemID int) (bool, error)
+	IsItemActive(itemID int) (bool, error)
+	DoesCaptureExist(requestID string) (bool, error)
+	// GetItemIDByBarcode returns ErrUnknownBarcode if no item has the barcode.
+	GetItemIDByBarcode(barcode itemdomain.GTIN) (int, error)
 	DoesUserExist(userID string) (bool, error)
-	StartTransaction(id domain.TransactionID, createdAt time.Time) error
-	AddItemToTransaction(transactionID domain.TransactionID, itemID int, createdAt time.Time) error
-	EndTransactionAndAssignUser(transactionID domain.TransactionID, userID string) error
-	IsTransactionAssigned(transactionID domain.TransactionID) (bool, error)
+	StartTransaction(id domain.TransactionID, machineID int, createdAt time.Time) error
+	// AddItemToTransaction adds the item, linking it to the capture it was classified from if there is one.
+	AddItemToTransaction(
+		transactionID domain.TransactionID,
+		itemID int,
+		captureRequestID *string,
+		createdAt time.Time,
+	) error
+	// UpdateTransactionStatus moves the transaction to the given status only if it is currently in from.
+	// It reports whether the transaction was updated.
+	UpdateTransactionStatus(transactionID domain.TransactionID, from, to domain.TransactionStatus) (bool, error)
+	// CloseTransaction closes the transaction only if it is still open, reporting whether it was closed.
+	CloseTransaction(transactionID domain.TransactionID, closedAt time.Time) (bool, error)
+	// ExpireTransactions expires closed transactions closed before closedBefore
+	// and open transactions created before createdBefore, returning the ids of those it expired.
+	ExpireTransactions(closedBefore time.Time, createdBefore time.Time) ([]domain.TransactionID, error)
+	// ClaimTransaction atomically assigns the user to the transaction if it is closed, unassigned
+	// and was closed after closedAfter. It reports whether this call was the one that claimed it.
+	ClaimTransaction(
+		transactionID domain.TransactionID,
+		userID string,
+		claimedAt time.Time,
+		closedAfter time.Time,
+	) (bool, error)
 	GetTransactionItemCount(transactionID domain.TransactionID) (int, error)
+	// CreditTransactionItems records on each of the transaction's items the points it is currently worth.
+	CreditTransactionItems(transactionID domain.TransactionID) error
+	// GetTransactionPoints returns the points the transaction's items were credited with, so items that
+	// haven't been credited yet count for nothing.
 	GetTransactionPoints(transactionID domain.TransactionID) (int, error)
 }
@@ -4,81 +4,288 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	itemdomain "github.com/JosephJoshua/rvm/backend/internal/item/domain"
+	ledgerdomain "github.com/JosephJoshua/rvm/backend/internal/ledger/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/pubsub"
 	"github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
 )
 
 var (
-	ErrTransactionDoesNotExist = fmt.Errorf("transaction does not exist")
-	ErrItemDoesNotExist        = fmt.Errorf("item does not exist")
-	ErrUserDoesNotExist        = fmt.Errorf("user does not exist")
+	ErrTransactionDoesNotExist    = fmt.Errorf("transaction does not exist")
+	ErrItemDoesNotExist           = fmt.Errorf("item does not exist")
+	ErrItemArchived               = fmt.Errorf("item is archived")
+	ErrUnknownBarcode             = fmt.Errorf("no item has this barcode")
+	ErrCaptureDoesNotExist        = fmt.Errorf("capture does not exist")
+	ErrUserDoesNotExist           = fmt.Errorf("user does not exist")
 	ErrTransactionAlreadyAssigned = fmt.Errorf("transaction is already assigned")
+	ErrTransactionNotOpen         = fmt.Errorf("transaction is not open")
+	ErrInvalidStatusTransition    = fmt.Errorf("invalid transaction status transition")
+	ErrTransactionExpired         = fmt.Errorf("transaction has expired")
+	ErrBinFull                    = fmt.Errorf("machine bin is full")
 )
 
+type Config struct {
+	// ClaimWindow is how long a closed transaction can be claimed before it expires.
+	ClaimWindow time.Duration
+	// SessionTimeout is how long a transaction can stay open before it is considered abandoned.
+	SessionTimeout time.Duration
+}
+
 type Service struct {
-	r  Repository
-	ig IDGenerator
+	r      Repository
+	uow    db.UnitOfWork[Repositories]
+	ig     IDGenerator
+	bc     BinChecker
+	events *pubsub.Hub[domain.Event]
+	cfg    Config
+}
+
+// NewService creates a transaction service that publishes what happens to transactions to events,
+// with the transaction id as the topic.
+func NewService(
+	r Repository,
+	uow db.UnitOfWork[Repositories],
+	cg IDGenerator,
+	bc BinChecker,
+	events *pubsub.Hub[domain.Event],
+	cfg Config,
+) *Service {
+	return &Service{r: r, uow: uow, ig: cg, bc: bc, events: events, cfg: cfg}
 }
 
-func NewService(r Repository, cg IDGenerator) *Service {
-	return &Service{r: r, ig: cg}
+// Subscription follows the events of a transaction from the moment it was taken.
+type Subscription struct {
+	// Transaction and ItemCount are the transaction as it was right after subscribing.
+	// Events that happened in between may show up both here and in Events.
+	Transaction domain.Transaction
+	ItemCount   int
+	// Events is closed when the subscriber falls behind or the service shuts down.
+	Events <-chan domain.Event
+	// Unsubscribe must be called once the subscriber is done with Events.
+	Unsubscribe func()
+}
+
+// Subscribe starts following the events of a transaction the machine started.
+func (s *Service) Subscribe(transactionID domain.TransactionID, machineID int) (Subscription, error) {
+	// Subscribing comes first so that nothing that happens after the transaction is read is missed.
+	events, unsubscribe := s.events.Subscribe(transactionID.String())
+
+	t, err := s.getMachineTransaction(transactionID, machineID)
+	if err != nil {
+		unsubscribe()
+		return Subscription{}, fmt.Errorf("Subscribe(): failed to get transaction with id %s: %w", transactionID, err)
+	}
+
+	c, err := s.r.GetTransactionItemCount(transactionID)
+	if err != nil {
+		unsubscribe()
+		return Subscription{}, fmt.Errorf("Subscribe(): failed to get transaction item count: %w", err)
+	}
+
+	return Subscription{
+		Transaction: *t,
+		ItemCount:   c,
+		Events:      events,
+		Unsubscribe: unsubscribe,
+	}, nil
 }
 
-func (s *Service) StartTransaction() (domain.TransactionID, error) {
+// StartTransaction opens a new transaction on the machine and returns its id.
+// It returns ErrBinFull if the machine's bin has to be emptied first.
+func (s *Service) StartTransaction(machineID int) (domain.TransactionID, error) {
+	full, err := s.bc.IsBinFull(machineID)
+	if err != nil {
+		return "", fmt.Errorf("StartTransaction(): failed to check bin: %w", err)
+	}
+
+	if full {
+		return "", fmt.Errorf("StartTransaction(): machine with id %d: %w", machineID, ErrBinFull)
+	}
+
 	id, err := s.ig.Generate()
 	if err != nil {
 		return "", fmt.Errorf("StartTransaction(): failed to generate id: %w", err)
 	}
 
-	if err = s.r.StartTransaction(id, time.Now()); err != nil {
+	if err = s.r.StartTransaction(id, machineID, time.Now()); err != nil {
 		return "", fmt.Errorf("StartTransaction(): failed to create transaction: %w", err)
 	}
 
 	return id, nil
 }
 
-func (s *Service) AddItemToTransaction(transactionID domain.TransactionID, itemID int) (int, error) {
-	ok, err := s.r.DoesTransactionExist(transactionID)
+// AddItemToTransaction adds the item to the open transaction the machine started and returns how many items
+// it now has.
+// It returns ErrTransactionExpired, expiring the transaction if need be, once its session has timed out.
+// captureRequestID is the capture the machine classified the item from, if any; linking it lets
+// operators review the item if the classification was uncertain.
+func (s *Service) AddItemToTransaction(
+	transactionID domain.TransactionID,
+	machineID int,
+	itemID int,
+	captureRequestID *string,
+) (int, error) {
+	t, err := s.getMachineTransaction(transactionID, machineID)
 	if err != nil {
-		return 0, fmt.Errorf("AddItemToTransaction(): failed to check transaction existence: %w", err)
+		return 0, fmt.Errorf(
+			"AddItemToTransaction(): failed to get transaction with id %s: %w",
+			transactionID.String(),
+			err,
+		)
 	}
 
-	if !ok {
-		return 0, fmt.Errorf("AddItemToTransaction(): %w with id %s", ErrTransactionDoesNotExist, transactionID.String())
+	// Expiring has to happen outside the unit of work, which is rolled back when the item is rejected.
+	if err = s.expireAndPublishIfDue(t); err != nil {
+		return 0, fmt.Errorf("AddItemToTransaction(): %w", err)
 	}
 
-	ok, err = s.r.DoesItemExist(itemID)
+	var c int
+
+	err = s.uow.Do(func(repos Repositories) error {
+		r := repos.Transactions
+
+		t, err := r.GetTransaction(transactionID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction with id %s: %w", transactionID.String(), err)
+		}
+
+		// It may have become due since the check above; the sweeper takes care of expiring it then.
+		if t.Status == domain.TransactionStatusExpired || s.isDue(t) {
+			return fmt.Errorf("transaction with id %s: %w", transactionID.String(), ErrTransactionExpired)
+		}
+
+		if t.Status != domain.TransactionStatusOpen {
+			return fmt.Errorf("transaction with id %s is %s: %w", transactionID.String(), t.Status, ErrTransactionNotOpen)
+		}
+
+		ok, err := r.DoesItemExist(itemID)
+		if err != nil {
+			return fmt.Errorf("failed to check item existence: %w", err)
+		}
+
+		if !ok {
+			return fmt.Errorf("%w with id %v", ErrItemDoesNotExist, itemID)
+		}
+
+		active, err := r.IsItemActive(itemID)
+		if err != nil {
+			return fmt.Errorf("failed to check whether item is active: %w", err)
+		}
+
+		if !active {
+			return fmt.Errorf("%w with id %v", ErrItemArchived, itemID)
+		}
+
+		if captureRequestID != nil {
+			ok, err = r.DoesCaptureExist(*captureRequestID)
+			if err != nil {
+				return fmt.Errorf("failed to check capture existence: %w", err)
+			}
+
+			if !ok {
+				return fmt.Errorf("%w with request id %s", ErrCaptureDoesNotExist, *captureRequestID)
+			}
+		}
+
+		if err = r.AddItemToTransaction(transactionID, itemID, captureRequestID, time.Now()); err != nil {
+			return fmt.Errorf("failed to add item to transaction: %w", err)
+		}
+
+		c, err = r.GetTransactionItemCount(transactionID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction item count: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("AddItemToTransaction(): failed to check item existence: %w", err)
+		return 0, fmt.Errorf("AddItemToTransaction(): %w", err)
 	}
 
-	if !ok {
-		return 0, fmt.Errorf("AddItemToTransaction(): %w with id %v", ErrItemDoesNotExist, itemID)
-	}
+	s.publish(domain.Event{
+		Kind:          domain.EventKindItemAdded,
+		TransactionID: transactionID,
+		ItemID:        itemID,
+		ItemCount:     c,
+		OccurredAt:    time.Now(),
+	})
 
-	if err = s.r.AddItemToTransaction(transactionID, itemID, time.Now()); err != nil {
-		return 0, fmt.Errorf("AddItemToTransaction(): failed to add item to transaction: %w", err)
+	return c, nil
+}
+
+// AddItemToTransactionByBarcode adds the item the barcode belongs to, like AddItemToTransaction.
+func (s *Service) AddItemToTransactionByBarcode(
+	transactionID domain.TransactionID,
+	machineID int,
+	barcode itemdomain.GTIN,
+	captureRequestID *string,
+) (int, error) {
+	itemID, err := s.r.GetItemIDByBarcode(barcode)
+	if err != nil {
+		return 0, fmt.Errorf("AddItemToTransactionByBarcode(): failed to resolve barcode %s: %w", barcode, err)
 	}
 
-	c, err := s.r.GetTransactionItemCount(transactionID)
+	c, err := s.AddItemToTransaction(transactionID, machineID, itemID, captureRequestID)
 	if err != nil {
-		return 0, fmt.Errorf("AddItemToTransaction(): failed to get transaction item count: %w", err)
+		return 0, fmt.Errorf("AddItemToTransactionByBarcode(): %w", err)
 	}
 
 	return c, nil
 }
 
-func (s *Service) EndTransactionAndAssignUser(transactionID domain.TransactionID, userID string) (int, error) {
-	ok, err := s.r.DoesTransactionExist(transactionID)
+// CloseTransaction marks the end of the machine session; the transaction can then be claimed by a user.
+func (s *Service) CloseTransaction(transactionID domain.TransactionID, machineID int) error {
+	t, err := s.getMachineTransaction(transactionID, machineID)
 	if err != nil {
-		return 0, fmt.Errorf("EndTransactionAndAssignUser(): failed to check transaction existence: %w", err)
+		return fmt.Errorf("CloseTransaction(): failed to get transaction with id %s: %w", transactionID.String(), err)
+	}
+
+	if err = s.expireAndPublishIfDue(t); err != nil {
+		return fmt.Errorf("CloseTransaction(): %w", err)
+	}
+
+	if !t.Status.CanTransitionTo(domain.TransactionStatusClosed) {
+		return fmt.Errorf(
+			"CloseTransaction(): %w from %s to %s",
+			ErrInvalidStatusTransition,
+			t.Status,
+			domain.TransactionStatusClosed,
+		)
+	}
+
+	ok, err := s.r.CloseTransaction(transactionID, time.Now())
+	if err != nil {
+		return fmt.Errorf("CloseTransaction(): failed to close transaction: %w", err)
 	}
 
 	if !ok {
-		return 0,
-			fmt.Errorf("EndTransactionAndAssignUser(): %w with id %s", ErrTransactionDoesNotExist, transactionID.String())
+		return fmt.Errorf("CloseTransaction(): %w: status changed concurrently", ErrInvalidStatusTransition)
+	}
+
+	s.publish(domain.Event{Kind: domain.EventKindClosed, TransactionID: transactionID, OccurredAt: time.Now()})
+
+	return nil
+}
+
+// CancelTransaction aborts a transaction the machine started that has not been claimed yet.
+func (s *Service) CancelTransaction(transactionID domain.TransactionID, machineID int) error {
+	if err := s.transition(transactionID, machineID, domain.TransactionStatusCancelled); err != nil {
+		return fmt.Errorf("CancelTransaction(): %w", err)
 	}
 
-	ok, err = s.r.DoesUserExist(userID)
+	s.publish(domain.Event{Kind: domain.EventKindCancelled, TransactionID: transactionID, OccurredAt: time.Now()})
+
+	return nil
+}
+
+// EndTransactionAndAssignUser claims the transaction's points for the user and returns them.
+// The points are credited to the user's ledger in the same database transaction as the claim.
+// When several users claim the same transaction at once, exactly one succeeds and the others
+// get ErrTransactionAlreadyAssigned.
+// It returns ErrTransactionExpired, expiring the transaction if need be, once its claim window has passed.
+func (s *Service) EndTransactionAndAssignUser(transactionID domain.TransactionID, userID string) (int, error) {
+	ok, err := s.r.DoesUserExist(userID)
 	if err != nil {
 		return 0, fmt.Errorf("EndTransactionAndAssignUser(): failed to check user existence: %w", err)
 	}
@@ -87,28 +294,201 @@ func (s *Service) EndTransactionAndAssignUser(transactionID domain.TransactionID
 		return 0, fmt.Errorf("EndTransactionAndAssignUser(): %w with id %s", ErrUserDoesNotExist, userID)
 	}
 
-	ok, err = s.r.IsTransactionAssigned(transactionID)
+	t, err := s.r.GetTransaction(transactionID)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"EndTransactionAndAssignUser(): failed to get transaction with id %s: %w",
+			transactionID.String(),
+			err,
+		)
+	}
+
+	// Expiring has to happen outside the unit of work, which is rolled back when the claim fails.
+	if err = s.expireAndPublishIfDue(t); err != nil {
+		return 0, fmt.Errorf("EndTransactionAndAssignUser(): %w", err)
+	}
+
+	var points int
+
+	err = s.uow.Do(func(repos Repositories) error {
+		r := repos.Transactions
+		now := time.Now()
+
+		claimed, err := r.ClaimTransaction(transactionID, userID, now, now.Add(-s.cfg.ClaimWindow))
+		if err != nil {
+			return fmt.Errorf("failed to claim transaction: %w", err)
+		}
+
+		if !claimed {
+			return s.claimFailureReason(r, transactionID)
+		}
+
+		// Each item keeps the points it was credited with, which is what relabeling it later adjusts.
+		if err = r.CreditTransactionItems(transactionID); err != nil {
+			return fmt.Errorf("failed to credit transaction items: %w", err)
+		}
+
+		points, err = r.GetTransactionPoints(transactionID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction points: %w", err)
+		}
+
+		// The entry keeps the points the items were worth at claim time, even if they change later.
+		tid := transactionID.String()
+
+		entry, err := ledgerdomain.NewEntry(userID, ledgerdomain.EntryKindEarning, points, &tid, nil, "", now)
+		if err != nil {
+			return fmt.Errorf("failed to create earning entry: %w", err)
+		}
+
+		if err = repos.Ledger.AppendEntry(entry); err != nil {
+			return fmt.Errorf("failed to credit points: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("EndTransactionAndAssignUser(): %w", err)
+	}
+
+	s.publish(domain.Event{
+		Kind:          domain.EventKindClaimed,
+		TransactionID: transactionID,
+		UserID:        userID,
+		Points:        points,
+		OccurredAt:    time.Now(),
+	})
+
+	return points, nil
+}
+
+// ExpireStaleTransactions expires every transaction that was left open or unclaimed for too long.
+func (s *Service) ExpireStaleTransactions() (int, error) {
+	now := time.Now()
+
+	ids, err := s.r.ExpireTransactions(now.Add(-s.cfg.ClaimWindow), now.Add(-s.cfg.SessionTimeout))
+	if err != nil {
+		return 0, fmt.Errorf("ExpireStaleTransactions(): failed to expire transactions: %w", err)
+	}
+
+	for _, id := range ids {
+		s.publish(domain.Event{Kind: domain.EventKindExpired, TransactionID: id, OccurredAt: now})
+	}
+
+	return len(ids), nil
+}
+
+// expireIfDue expires the transaction right away if it is past its deadline but hasn't been swept yet,
+// updating t accordingly. It reports whether this call expired it.
+func (s *Service) expireIfDue(r Repository, t *domain.Transaction) (bool, error) {
+	if !s.isDue(t) {
+		return false, nil
+	}
+
+	expired, err := r.UpdateTransactionStatus(t.ID, t.Status, domain.TransactionStatusExpired)
 	if err != nil {
-		return 0, fmt.Errorf("EndTransactionAndAssignUser(): failed to check if transaction is already assigned: %w", err)
+		return false, fmt.Errorf("failed to expire transaction: %w", err)
 	}
 
-	if ok {
-		return 0,
-			fmt.Errorf(
-				"EndTransactionAndAssignUser(): transaction with id %s is already assigned: %w",
-				transactionID.String(),
-				ErrTransactionAlreadyAssigned,
+	t.Status = domain.TransactionStatusExpired
+	return expired, nil
+}
+
+// isDue reports whether the transaction is past its deadline, whether or not it has been swept yet.
+func (s *Service) isDue(t *domain.Transaction) bool {
+	expiresAt, ok := t.ExpiresAt(s.cfg.ClaimWindow, s.cfg.SessionTimeout)
+	return ok && !time.Now().Before(expiresAt)
+}
+
+// expireAndPublishIfDue is expireIfDue outside of a unit of work, where expiring the transaction
+// sticks even if the caller goes on to fail.
+func (s *Service) expireAndPublishIfDue(t *domain.Transaction) error {
+	expired, err := s.expireIfDue(s.r, t)
+	if err != nil {
+		return err
+	}
+
+	if expired {
+		s.publish(domain.Event{Kind: domain.EventKindExpired, TransactionID: t.ID, OccurredAt: time.Now()})
+	}
+
+	return nil
+}
+
+// publish must only be called once the change the event describes is committed.
+func (s *Service) publish(e domain.Event) {
+	s.events.Publish(e.TransactionID.String(), e)
+}
+
+// claimFailureReason explains why a claim on the transaction did not go through.
+func (s *Service) claimFailureReason(r Repository, transactionID domain.TransactionID) error {
+	t, err := r.GetTransaction(transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction with id %s: %w", transactionID.String(), err)
+	}
+
+	// It may have become due since it was checked; the sweeper takes care of expiring it then.
+	if s.isDue(t) {
+		return fmt.Errorf("transaction with id %s: %w", transactionID.String(), ErrTransactionExpired)
+	}
+
+	switch t.Status {
+	case domain.TransactionStatusClaimed:
+		return fmt.Errorf(
+			"transaction with id %s is already assigned: %w",
+			transactionID.String(),
+			ErrTransactionAlreadyAssigned,
 		)
+	case domain.TransactionStatusExpired:
+		return fmt.Errorf("transaction with id %s: %w", transactionID.String(), ErrTransactionExpired)
+	case domain.TransactionStatusOpen, domain.TransactionStatusClosed, domain.TransactionStatusCancelled:
 	}
 
-	if err = s.r.EndTransactionAndAssignUser(transactionID, userID); err != nil {
-		return 0, fmt.Errorf("EndTransactionAndAssignUser(): failed to end transaction: %w", err)
+	return fmt.Errorf("%w from %s to %s", ErrInvalidStatusTransition, t.Status, domain.TransactionStatusClaimed)
+}
+
+// transition moves the machine's transaction to the given status if its current status allows it.
+func (s *Service) transition(transactionID domain.TransactionID, machineID int, to domain.TransactionStatus) error {
+	t, err := s.getMachineTransaction(transactionID, machineID)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction with id %s: %w", transactionID.String(), err)
 	}
 
-	c, err := s.r.GetTransactionPoints(transactionID)
+	if err = s.expireAndPublishIfDue(t); err != nil {
+		return err
+	}
+
+	if !t.Status.CanTransitionTo(to) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidStatusTransition, t.Status, to)
+	}
+
+	ok, err := s.r.UpdateTransactionStatus(transactionID, t.Status, to)
 	if err != nil {
-		return 0, fmt.Errorf("EndTransactionAndAssignUser(): failed to get transaction points: %w", err)
+		return fmt.Errorf("failed to update transaction status: %w", err)
 	}
 
-	return c, nil
+	// Someone else changed the status between our read and our write.
+	if !ok {
+		return fmt.Errorf("%w from %s to %s: status changed concurrently", ErrInvalidStatusTransition, t.Status, to)
+	}
+
+	return nil
+}
+
+// getMachineTransaction returns ErrTransactionDoesNotExist for transactions started by another machine,
+// so that a machine can't act on, or even find out about, another machine's transactions.
+func (s *Service) getMachineTransaction(
+	transactionID domain.TransactionID,
+	machineID int,
+) (*domain.Transaction, error) {
+	t, err := s.r.GetTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.MachineID == nil || *t.MachineID != machineID {
+		return nil, fmt.Errorf("%w: started by another machine than %d", ErrTransactionDoesNotExist, machineID)
+	}
+
+	return t, nil
 }
@@ -0,0 +1,199 @@
+package transaction_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	itemdomain "github.com/JosephJoshua/rvm/backend/internal/item/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/ledger"
+	"github.com/JosephJoshua/rvm/backend/internal/pubsub"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
+)
+
+type emptyBins struct{}
+
+func (emptyBins) IsBinFull(int) (bool, error) {
+	return false, nil
+}
+
+func newService(dbHandle *db.DB) *transaction.Service {
+	return transaction.NewService(
+		transaction.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) transaction.Repositories {
+			return transaction.Repositories{
+				Transactions: transaction.NewSQLRepository(q),
+				Ledger:       ledger.NewSQLRepository(q),
+			}
+		}),
+		transaction.NewUUIDIDGenerator(),
+		emptyBins{},
+		pubsub.NewHub[domain.Event](1),
+		transaction.Config{
+			ClaimWindow:    time.Hour,
+			SessionTimeout: time.Hour,
+		},
+	)
+}
+
+func TestOtherMachinesCantSeeTransaction(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	ownerID := dbtest.CreateMachine(t, dbHandle)
+	otherID := dbtest.CreateMachine(t, dbHandle)
+	itemID := dbtest.CreateItem(t, dbHandle, 10)
+
+	id, err := s.StartTransaction(ownerID)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		fn   func(machineID int) error
+	}{
+		{
+			name: "add item",
+			fn: func(machineID int) error {
+				_, err := s.AddItemToTransaction(id, machineID, itemID, nil)
+				return err
+			},
+		},
+		{
+			name: "subscribe",
+			fn: func(machineID int) error {
+				sub, err := s.Subscribe(id, machineID)
+				if err == nil {
+					sub.Unsubscribe()
+				}
+
+				return err
+			},
+		},
+		{
+			name: "close",
+			fn: func(machineID int) error {
+				return s.CloseTransaction(id, machineID)
+			},
+		},
+		{
+			name: "cancel",
+			fn: func(machineID int) error {
+				return s.CancelTransaction(id, machineID)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.fn(otherID); !errors.Is(err, transaction.ErrTransactionDoesNotExist) {
+				t.Errorf("other machine: got error %v, want %v", err, transaction.ErrTransactionDoesNotExist)
+			}
+
+			if err := tc.fn(ownerID); err != nil {
+				t.Errorf("owner: got error %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestClaimExpiresTransactionPastClaimWindow(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	const userID = "user-1"
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	machineID := dbtest.CreateMachine(t, dbHandle)
+
+	id, err := s.StartTransaction(machineID)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	sub, err := s.Subscribe(id, machineID)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	defer sub.Unsubscribe()
+
+	// Closed longer ago than the claim window, but not swept yet.
+	r := transaction.NewSQLRepository(dbHandle)
+	if ok, err := r.CloseTransaction(id, time.Now().Add(-2*time.Hour)); err != nil || !ok {
+		t.Fatalf("failed to close transaction: ok=%v, err=%v", ok, err)
+	}
+
+	if _, err = s.EndTransactionAndAssignUser(id, userID); !errors.Is(err, transaction.ErrTransactionExpired) {
+		t.Errorf("got error %v, want %v", err, transaction.ErrTransactionExpired)
+	}
+
+	got, err := r.GetTransaction(id)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+
+	if got.Status != domain.TransactionStatusExpired {
+		t.Errorf("got status %s, want %s", got.Status, domain.TransactionStatusExpired)
+	}
+
+	select {
+	case e := <-sub.Events:
+		if e.Kind != domain.EventKindExpired {
+			t.Errorf("got event %s, want %s", e.Kind, domain.EventKindExpired)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("got no event, want %s", domain.EventKindExpired)
+	}
+}
+
+func TestAddItemToTransactionByBarcode(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	machineID := dbtest.CreateMachine(t, dbHandle)
+	itemID := dbtest.CreateItem(t, dbHandle, 10)
+
+	if _, err := dbHandle.Exec(`
+		INSERT INTO
+			item_barcodes (barcode, item_id, created_at)
+		VALUES
+			(?, ?, ?)
+	`, "00036000291452", itemID, time.Now()); err != nil {
+		t.Fatalf("failed to add barcode: %v", err)
+	}
+
+	id, err := s.StartTransaction(machineID)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		barcode   string
+		want      error
+		wantCount int
+	}{
+		{name: "UPC-A", barcode: "036000291452", wantCount: 1},
+		{name: "same product as EAN-13", barcode: "0036000291452", wantCount: 2},
+		{name: "unknown barcode", barcode: "4006381333931", want: transaction.ErrUnknownBarcode},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			barcode, err := itemdomain.NewGTIN(tc.barcode)
+			if err != nil {
+				t.Fatalf("failed to parse barcode: %v", err)
+			}
+
+			count, err := s.AddItemToTransactionByBarcode(id, machineID, barcode, nil)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			if tc.want == nil && count != tc.wantCount {
+				t.Errorf("got %d items, want %d", count, tc.wantCount)
+			}
+		})
+	}
+}
@@ -1,37 +1,78 @@
 package transaction
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	itemdomain "github.com/JosephJoshua/rvm/backend/internal/item/domain"
 	"github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
-	"github.com/jmoiron/sqlx"
 )
 
+type transaction struct {
+	TransactionID string         `db:"transaction_id"`
+	UserID        sql.NullString `db:"user_id"`
+	MachineID     sql.NullInt64  `db:"machine_id"`
+	Status        string         `db:"status"`
+	CreatedAt     time.Time      `db:"created_at"`
+	ClosedAt      sql.NullTime   `db:"closed_at"`
+}
+
 type SQLRepository struct {
-	db *sqlx.DB
+	db db.Queryer
 }
 
-func NewSQLRepository(db *sqlx.DB) *SQLRepository {
+func NewSQLRepository(q db.Queryer) *SQLRepository {
 	return &SQLRepository{
-		db: db,
+		db: q,
 	}
 }
 
-func (tr *SQLRepository) DoesTransactionExist(transactionID domain.TransactionID) (bool, error) {
-	var count int
-	if err := tr.db.Get(&count, `
+func (tr *SQLRepository) GetTransaction(id domain.TransactionID) (*domain.Transaction, error) {
+	var raw transaction
+	if err := tr.db.Get(&raw, `
 		SELECT
-			COUNT(*)
+			transaction_id, user_id, machine_id, status, created_at, closed_at
 		FROM
 			transactions
 		WHERE
 			transaction_id = ?
-	`, transactionID); err != nil {
-		return false, fmt.Errorf("DoesTransactionExist(): failed to execute query: %w", err)
+	`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransactionDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetTransaction(): failed to execute query: %w", err)
 	}
 
-	return count > 0, nil
+	status, err := domain.NewTransactionStatus(raw.Status)
+	if err != nil {
+		return nil, fmt.Errorf("GetTransaction(): failed to parse status: %w", err)
+	}
+
+	var closedAt *time.Time
+	if raw.ClosedAt.Valid {
+		closedAt = &raw.ClosedAt.Time
+	}
+
+	var machineID *int
+	if raw.MachineID.Valid {
+		id := int(raw.MachineID.Int64)
+		machineID = &id
+	}
+
+	t := domain.NewTransaction(
+		domain.TransactionID(raw.TransactionID),
+		raw.UserID.String,
+		machineID,
+		status,
+		raw.CreatedAt,
+		closedAt,
+	)
+
+	return &t, nil
 }
 
 func (tr *SQLRepository) DoesItemExist(itemID int) (bool, error) {
@@ -50,6 +91,58 @@ func (tr *SQLRepository) DoesItemExist(itemID int) (bool, error) {
 	return count > 0, nil
 }
 
+func (tr *SQLRepository) DoesCaptureExist(requestID string) (bool, error) {
+	var count int
+	if err := tr.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			captures
+		WHERE
+			request_id = ?
+	`, requestID); err != nil {
+		return false, fmt.Errorf("DoesCaptureExist(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (tr *SQLRepository) IsItemActive(itemID int) (bool, error) {
+	var count int
+	if err := tr.db.Get(&count, `
+		SELECT
+			COUNT(*)
+		FROM
+			items
+		WHERE
+			item_id = ? AND is_active = ?
+	`, itemID, true); err != nil {
+		return false, fmt.Errorf("IsItemActive(): failed to execute query: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (tr *SQLRepository) GetItemIDByBarcode(barcode itemdomain.GTIN) (int, error) {
+	var itemID int
+	if err := tr.db.Get(&itemID, `
+		SELECT
+			item_id
+		FROM
+			item_barcodes
+		WHERE
+			barcode = ?
+	`, barcode); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUnknownBarcode
+		}
+
+		return 0, fmt.Errorf("GetItemIDByBarcode(): failed to execute query: %w", err)
+	}
+
+	return itemID, nil
+}
+
 func (tr *SQLRepository) DoesUserExist(userID string) (bool, error) {
 	var count int
 	if err := tr.db.Get(&count, `
@@ -66,13 +159,13 @@ func (tr *SQLRepository) DoesUserExist(userID string) (bool, error) {
 	return count > 0, nil
 }
 
-func (tr *SQLRepository) StartTransaction(id domain.TransactionID, createdAt time.Time) error {
+func (tr *SQLRepository) StartTransaction(id domain.TransactionID, machineID int, createdAt time.Time) error {
 	if _, err := tr.db.Exec(`
 		INSERT INTO
-			transactions (transaction_id, created_at)
+			transactions (transaction_id, machine_id, created_at)
 		VALUES
-			(?, ?)
-	`, id, createdAt); err != nil {
+			(?, ?, ?)
+	`, id, machineID, createdAt); err != nil {
 		return fmt.Errorf("StartTransaction(): failed to execute query: %w", err)
 	}
 
@@ -82,49 +175,130 @@ func (tr *SQLRepository) StartTransaction(id domain.TransactionID, createdAt tim
 func (tr *SQLRepository) AddItemToTransaction(
 	transactionID domain.TransactionID,
 	itemID int,
+	captureRequestID *string,
 	createdAt time.Time,
 ) error {
 	if _, err := tr.db.Exec(`
 		INSERT INTO
-			transaction_items (transaction_id, item_id, created_at)
+			transaction_items (transaction_id, item_id, capture_request_id, created_at)
 		VALUES
-			(?, ?, ?)
-	`, transactionID, itemID, createdAt); err != nil {
+			(?, ?, ?, ?)
+	`, transactionID, itemID, captureRequestID, createdAt); err != nil {
 		return fmt.Errorf("AddItemToTransaction(): failed to execute query: %w", err)
 	}
 
 	return nil
 }
 
-func (tr *SQLRepository) EndTransactionAndAssignUser(transactionID domain.TransactionID, userID string) error {
-	if _, err := tr.db.Exec(`
+func (tr *SQLRepository) ClaimTransaction(
+	transactionID domain.TransactionID,
+	userID string,
+	claimedAt time.Time,
+	closedAfter time.Time,
+) (bool, error) {
+	// The conditions make this a compare-and-swap: of several concurrent claims,
+	// only the first one to reach the database matches the row.
+	res, err := tr.db.Exec(`
 		UPDATE
 			transactions
 		SET
-			user_id = ?
+			user_id = ?,
+			status = ?,
+			claimed_at = ?
 		WHERE
 			transaction_id = ?
-	`, userID, transactionID); err != nil {
-		return fmt.Errorf("AddItemToTransaction(): failed to execute query: %w", err)
+			AND status = ?
+			AND user_id IS NULL
+			AND COALESCE(closed_at, created_at) >= ?
+	`,
+		userID, domain.TransactionStatusClaimed, claimedAt,
+		transactionID,
+		domain.TransactionStatusClosed,
+		closedAfter,
+	)
+	if err != nil {
+		return false, fmt.Errorf("ClaimTransaction(): failed to execute query: %w", err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ClaimTransaction(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
 }
 
-func (tr *SQLRepository) IsTransactionAssigned(transactionID domain.TransactionID) (bool, error) {
-	var count int
-	if err := tr.db.Get(&count, `
-		SELECT
-			COUNT(*)
-		FROM
+func (tr *SQLRepository) UpdateTransactionStatus(
+	transactionID domain.TransactionID,
+	from domain.TransactionStatus,
+	to domain.TransactionStatus,
+) (bool, error) {
+	res, err := tr.db.Exec(`
+		UPDATE
 			transactions
+		SET
+			status = ?
 		WHERE
-			transaction_id = ? AND user_id IS NOT NULL
-	`, transactionID); err != nil {
-		return false, fmt.Errorf("IsTransactionAssignedToUser(): failed to execute query: %w", err)
+			transaction_id = ? AND status = ?
+	`, to, transactionID, from)
+	if err != nil {
+		return false, fmt.Errorf("UpdateTransactionStatus(): failed to execute query: %w", err)
 	}
 
-	return count > 0, nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("UpdateTransactionStatus(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (tr *SQLRepository) CloseTransaction(transactionID domain.TransactionID, closedAt time.Time) (bool, error) {
+	res, err := tr.db.Exec(`
+		UPDATE
+			transactions
+		SET
+			status = ?,
+			closed_at = ?
+		WHERE
+			transaction_id = ? AND status = ?
+	`, domain.TransactionStatusClosed, closedAt, transactionID, domain.TransactionStatusOpen)
+	if err != nil {
+		return false, fmt.Errorf("CloseTransaction(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("CloseTransaction(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (tr *SQLRepository) ExpireTransactions(
+	closedBefore time.Time,
+	createdBefore time.Time,
+) ([]domain.TransactionID, error) {
+	var ids []domain.TransactionID
+	if err := tr.db.Select(&ids, `
+		UPDATE
+			transactions
+		SET
+			status = ?
+		WHERE
+			(status = ? AND COALESCE(closed_at, created_at) < ?)
+			OR (status = ? AND created_at < ?)
+		RETURNING
+			transaction_id
+	`,
+		domain.TransactionStatusExpired,
+		domain.TransactionStatusClosed, closedBefore,
+		domain.TransactionStatusOpen, createdBefore,
+	); err != nil {
+		return nil, fmt.Errorf("ExpireTransactions(): failed to execute query: %w", err)
+	}
+
+	return ids, nil
 }
 
 func (tr *SQLRepository) GetTransactionItemCount(transactionID domain.TransactionID) (int, error) {
@@ -143,19 +317,32 @@ func (tr *SQLRepository) GetTransactionItemCount(transactionID domain.Transactio
 	return count, nil
 }
 
+func (tr *SQLRepository) CreditTransactionItems(transactionID domain.TransactionID) error {
+	if _, err := tr.db.Exec(`
+		UPDATE
+			transaction_items
+		SET
+			points = (SELECT items.points FROM items WHERE items.item_id = transaction_items.item_id)
+		WHERE
+			transaction_id = ?
+	`, transactionID); err != nil {
+		return fmt.Errorf("CreditTransactionItems(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
 func (tr *SQLRepository) GetTransactionPoints(transactionID domain.TransactionID) (int, error) {
 	var count int
 	if err := tr.db.Get(&count, `
 		SELECT
-			COALESCE(SUM(items.points), 0)
+			COALESCE(SUM(points), 0)
 		FROM
 			transaction_items
-		LEFT JOIN
-			items ON items.item_id = transaction_items.item_id
 		WHERE
 			transaction_id = ?
 	`, transactionID); err != nil {
-		return 0, fmt.Errorf("GetTransactionItemCount(): failed to execute query: %w", err)
+		return 0, fmt.Errorf("GetTransactionPoints(): failed to execute query: %w", err)
 	}
 
 	return count, nil
@@ -0,0 +1,236 @@
+package transaction_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction"
+	"github.com/JosephJoshua/rvm/backend/internal/transaction/domain"
+)
+
+const concurrentClaims = 50
+
+func TestClaimTransactionConcurrently(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	r := transaction.NewSQLRepository(dbHandle)
+
+	machineID := dbtest.CreateMachine(t, dbHandle)
+	id := domain.TransactionID("7b0f3a52-5d8e-4c0e-9d59-3f1f0b6a4c21")
+	now := time.Now()
+
+	if err := r.StartTransaction(id, machineID, now); err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	if ok, err := r.CloseTransaction(id, now); err != nil || !ok {
+		t.Fatalf("failed to close transaction: ok=%v, err=%v", ok, err)
+	}
+
+	userIDs := make([]string, concurrentClaims)
+	for i := range userIDs {
+		userIDs[i] = fmt.Sprintf("user-%d", i)
+		dbtest.CreateUser(t, dbHandle, userIDs[i])
+	}
+
+	var wg sync.WaitGroup
+	claimed := make(chan string, concurrentClaims)
+	errs := make(chan error, concurrentClaims)
+
+	start := make(chan struct{})
+
+	for _, userID := range userIDs {
+		wg.Add(1)
+
+		go func(userID string) {
+			defer wg.Done()
+			<-start
+
+			ok, err := r.ClaimTransaction(id, userID, time.Now(), now.Add(-time.Hour))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if ok {
+				claimed <- userID
+			}
+		}(userID)
+	}
+
+	close(start)
+	wg.Wait()
+	close(claimed)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("failed to claim transaction: %v", err)
+	}
+
+	var winners []string
+	for userID := range claimed {
+		winners = append(winners, userID)
+	}
+
+	if len(winners) != 1 {
+		t.Fatalf("got %d successful claims, want 1", len(winners))
+	}
+
+	got, err := r.GetTransaction(id)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+
+	if got.Status != domain.TransactionStatusClaimed {
+		t.Errorf("got status %s, want %s", got.Status, domain.TransactionStatusClaimed)
+	}
+
+	if got.UserID != winners[0] {
+		t.Errorf("got user %q, want the winning claim's %q", got.UserID, winners[0])
+	}
+}
+
+func TestTransactionLifecycle(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	r := transaction.NewSQLRepository(dbHandle)
+
+	machineID := dbtest.CreateMachine(t, dbHandle)
+	bottleID := dbtest.CreateItem(t, dbHandle, 10)
+	canID := dbtest.CreateItem(t, dbHandle, 5)
+
+	id := domain.TransactionID("0c9e5d0e-2f4b-4a57-8d0a-6b1e6f2f7a10")
+	now := time.Now()
+
+	if err := r.StartTransaction(id, machineID, now); err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+
+	got, err := r.GetTransaction(id)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+
+	if got.Status != domain.TransactionStatusOpen {
+		t.Errorf("got status %s, want %s", got.Status, domain.TransactionStatusOpen)
+	}
+
+	if got.MachineID == nil || *got.MachineID != machineID {
+		t.Errorf("got machine %v, want %d", got.MachineID, machineID)
+	}
+
+	for _, itemID := range []int{bottleID, bottleID, canID} {
+		if err = r.AddItemToTransaction(id, itemID, nil, now); err != nil {
+			t.Fatalf("failed to add item %d: %v", itemID, err)
+		}
+	}
+
+	count, err := r.GetTransactionItemCount(id)
+	if err != nil {
+		t.Fatalf("failed to get item count: %v", err)
+	}
+
+	if count != 3 {
+		t.Errorf("got %d items, want 3", count)
+	}
+
+	if err = r.CreditTransactionItems(id); err != nil {
+		t.Fatalf("failed to credit transaction items: %v", err)
+	}
+
+	// What the items were credited with stands when the catalog changes.
+	if _, err = dbHandle.Exec("UPDATE items SET points = ? WHERE item_id = ?", 50, bottleID); err != nil {
+		t.Fatalf("failed to update item points: %v", err)
+	}
+
+	points, err := r.GetTransactionPoints(id)
+	if err != nil {
+		t.Fatalf("failed to get points: %v", err)
+	}
+
+	if points != 25 {
+		t.Errorf("got %d points, want 25", points)
+	}
+
+	if ok, err := r.CloseTransaction(id, now); err != nil || !ok {
+		t.Fatalf("failed to close transaction: ok=%v, err=%v", ok, err)
+	}
+
+	if ok, err := r.CloseTransaction(id, now); err != nil || ok {
+		t.Errorf("closing a closed transaction: got ok=%v, err=%v, want ok=false", ok, err)
+	}
+
+	ok, err := r.UpdateTransactionStatus(id, domain.TransactionStatusOpen, domain.TransactionStatusCancelled)
+	if err != nil || ok {
+		t.Errorf("cancelling from the wrong status: got ok=%v, err=%v, want ok=false", ok, err)
+	}
+
+	_, err = r.GetTransaction("9d7c9b8e-0000-4000-8000-000000000000")
+	if !errors.Is(err, transaction.ErrTransactionDoesNotExist) {
+		t.Errorf("got error %v, want %v", err, transaction.ErrTransactionDoesNotExist)
+	}
+}
+
+func TestExpireTransactions(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	r := transaction.NewSQLRepository(dbHandle)
+
+	machineID := dbtest.CreateMachine(t, dbHandle)
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+
+	staleOpen := domain.TransactionID("11111111-1111-4111-8111-111111111111")
+	staleClosed := domain.TransactionID("22222222-2222-4222-8222-222222222222")
+	freshOpen := domain.TransactionID("33333333-3333-4333-8333-333333333333")
+	freshClosed := domain.TransactionID("44444444-4444-4444-8444-444444444444")
+
+	for _, tc := range []struct {
+		id        domain.TransactionID
+		createdAt time.Time
+		closedAt  *time.Time
+	}{
+		{id: staleOpen, createdAt: old},
+		{id: staleClosed, createdAt: old, closedAt: &old},
+		{id: freshOpen, createdAt: now},
+		{id: freshClosed, createdAt: old, closedAt: &now},
+	} {
+		if err := r.StartTransaction(tc.id, machineID, tc.createdAt); err != nil {
+			t.Fatalf("failed to start transaction: %v", err)
+		}
+
+		if tc.closedAt != nil {
+			if ok, err := r.CloseTransaction(tc.id, *tc.closedAt); err != nil || !ok {
+				t.Fatalf("failed to close transaction: ok=%v, err=%v", ok, err)
+			}
+		}
+	}
+
+	cutoff := now.Add(-time.Hour)
+
+	ids, err := r.ExpireTransactions(cutoff, cutoff)
+	if err != nil {
+		t.Fatalf("failed to expire transactions: %v", err)
+	}
+
+	expired := make(map[domain.TransactionID]bool, len(ids))
+	for _, id := range ids {
+		expired[id] = true
+	}
+
+	if len(ids) != 2 || !expired[staleOpen] || !expired[staleClosed] {
+		t.Errorf("got expired %v, want %s and %s", ids, staleOpen, staleClosed)
+	}
+
+	for _, id := range []domain.TransactionID{freshOpen, freshClosed} {
+		got, err := r.GetTransaction(id)
+		if err != nil {
+			t.Fatalf("failed to get transaction: %v", err)
+		}
+
+		if got.Status == domain.TransactionStatusExpired {
+			t.Errorf("transaction %s expired before its deadline", id)
+		}
+	}
+}
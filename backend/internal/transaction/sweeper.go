@@ -0,0 +1,48 @@
+package transaction
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+)
+
+// Sweeper periodically expires transactions that were abandoned or never claimed.
+type Sweeper struct {
+	s        *Service
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewSweeper(s *Service, interval time.Duration, logger *slog.Logger) *Sweeper {
+	return &Sweeper{s: s, interval: interval, logger: logger}
+}
+
+// Run sweeps once immediately and then every interval until ctx is cancelled.
+func (sw *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(sw.interval)
+	defer ticker.Stop()
+
+	for {
+		sw.sweep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (sw *Sweeper) sweep() {
+	n, err := sw.s.ExpireStaleTransactions()
+	if err != nil {
+		sw.logger.Error("failed to expire stale transactions", logging.ErrAttr(err))
+		return
+	}
+
+	if n > 0 {
+		sw.logger.Info("expired stale transactions", slog.Int("count", n))
+	}
+}
@@ -11,21 +11,25 @@ import (
 	"github.com/go-chi/httplog/v2"
 )
 
+type pointsResponse struct {
+	Points int `json:"points"`
+}
+
 type HTTPHandler struct {
-	http.Handler
+	chi.Router
 	s *Service
 }
 
 // NewHTTPHandler creates a new user HTTP handler.
-//   - GET /points - returns this user's points.
+//   - GET /points - returns this user's points as JSON, or as a bare number to clients that prefer text/plain.
 func NewHTTPHandler(s *Service) *HTTPHandler {
 	handler := &HTTPHandler{s: s}
 
-	r := chi.NewRouter()
+	r := httputils.NewRouter()
 
 	r.Get("/points", httputils.HandlerFunc(handler.getPoints))
 
-	handler.Handler = r
+	handler.Router = r
 	return handler
 }
 
@@ -37,10 +41,10 @@ func (h *HTTPHandler) getPoints(w httputils.ResponseWriter, r *http.Request) {
 	p, err := h.s.GetPoints(uid)
 	if err != nil {
 		oplog.Error("failed to get points", logging.ErrAttr(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		w.TryWriteInternalError(&oplog)
 
 		return
 	}
 
-	w.TryWrite(&oplog, []byte(strconv.Itoa(p)))
+	w.TryWriteNegotiated(&oplog, http.StatusOK, pointsResponse{Points: p}, strconv.Itoa(p))
 }
@@ -3,32 +3,27 @@ package user
 import (
 	"fmt"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
 )
 
 type SQLRepository struct {
-	db *sqlx.DB
+	db db.Queryer
 }
 
-func NewSQLRepository(db *sqlx.DB) *SQLRepository {
-	return &SQLRepository{db: db}
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
 }
 
+// GetPoints returns the user's maintained balance; users who have never earned anything have none.
 func (ur *SQLRepository) GetPoints(uid string) (int, error) {
 	var points int
 	if err := ur.db.Get(&points, `
 		SELECT
-			COALESCE(SUM(items.points), 0)
+			COALESCE(MAX(balance), 0)
 		FROM
-			users
-		LEFT JOIN
-			transactions ON transactions.user_id = users.user_id
-		LEFT JOIN
-			transaction_items ON transaction_items.transaction_id = transactions.transaction_id
-		LEFT JOIN
-			items ON items.item_id = transaction_items.item_id
+			point_balances
 		WHERE
-			users.user_id = ?
+			user_id = ?
 	`, uid); err != nil {
 		return 0, fmt.Errorf("GetPoints(): failed to execute query: %w", err)
 	}
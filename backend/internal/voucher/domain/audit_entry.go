@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditActionLookup AuditAction = "lookup"
+	AuditActionRedeem AuditAction = "redeem"
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeOK AuditOutcome = "ok"
+	// AuditOutcomeNotFound is recorded for codes that don't exist or aren't even well-formed.
+	AuditOutcomeNotFound    AuditOutcome = "not_found"
+	AuditOutcomeAlreadyUsed AuditOutcome = "already_used"
+)
+
+// maxAuditCodeLength keeps whatever a merchant typed in within the column's size.
+const maxAuditCodeLength = 32
+
+// AuditEntry records a merchant's attempt to look up or redeem a voucher.
+type AuditEntry struct {
+	ID          int64
+	MerchantID  int
+	VoucherCode string
+	Action      AuditAction
+	Outcome     AuditOutcome
+	CreatedAt   time.Time
+}
+
+func NewAuditEntry(
+	merchantID int,
+	voucherCode string,
+	action AuditAction,
+	outcome AuditOutcome,
+	createdAt time.Time,
+) AuditEntry {
+	if len(voucherCode) > maxAuditCodeLength {
+		voucherCode = voucherCode[:maxAuditCodeLength]
+	}
+
+	return AuditEntry{
+		MerchantID:  merchantID,
+		VoucherCode: voucherCode,
+		Action:      action,
+		Outcome:     outcome,
+		CreatedAt:   createdAt,
+	}
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	rewarddomain "github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+)
+
+// Voucher is what a merchant sees of a redemption: the code, what it is for and whether it was used.
+type Voucher struct {
+	Code       rewarddomain.VoucherCode
+	RewardName string
+	// Value is the number of points the user spent on the voucher.
+	Value    int
+	IssuedAt time.Time
+	UsedAt   *time.Time
+	// UsedBy is the merchant that accepted the voucher.
+	UsedBy *int
+}
+
+func (v Voucher) IsUsed() bool {
+	return v.UsedAt != nil
+}
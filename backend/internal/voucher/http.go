@@ -0,0 +1,156 @@
+package voucher
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/httputils"
+	"github.com/JosephJoshua/rvm/backend/internal/logging"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+)
+
+// Codes for the problems returned by the handlers, named after the errors they come from.
+const (
+	codeVoucherDoesNotExist = "voucher_does_not_exist"
+	codeVoucherAlreadyUsed  = "voucher_already_used"
+)
+
+type voucherResponse struct {
+	Code       string     `json:"code"`
+	RewardName string     `json:"reward_name"`
+	Value      int        `json:"value"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	UsedAt     *time.Time `json:"used_at"`
+}
+
+type auditEntryResponse struct {
+	VoucherCode string    `json:"voucher_code"`
+	Action      string    `json:"action"`
+	Outcome     string    `json:"outcome"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type HTTPHandler struct {
+	chi.Router
+	s *Service
+}
+
+// NewHTTPHandler creates a new voucher HTTP handler for merchants.
+// It expects apitoken.MerchantTokenMiddleware to run before it.
+//   - GET /vouchers/audit - returns the merchant's latest lookups and redemptions as JSON.
+//   - GET /vouchers/{code} - returns the voucher as JSON, whether or not it has been used.
+//   - POST /vouchers/{code}/redeem - uses up the voucher and returns it as JSON.
+func NewHTTPHandler(s *Service) *HTTPHandler {
+	handler := &HTTPHandler{s: s}
+
+	r := httputils.NewRouter()
+
+	r.Get("/audit", httputils.HandlerFunc(handler.getAuditTrail))
+	r.Get("/{code}", httputils.HandlerFunc(handler.lookup))
+	r.Post("/{code}/redeem", httputils.HandlerFunc(handler.redeem))
+
+	handler.Router = r
+	return handler
+}
+
+func (h *HTTPHandler) lookup(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	merchantID := apitoken.MerchantIDFromCtx(r.Context())
+	code := chi.URLParam(r, "code")
+
+	v, err := h.s.Lookup(merchantID, code)
+	if err != nil {
+		if errors.Is(err, ErrVoucherDoesNotExist) {
+			oplog.Info("voucher not found", slog.Int("merchant_id", merchantID), slog.String("code", code))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeVoucherDoesNotExist, "voucher not found")
+
+			return
+		}
+
+		oplog.Error("failed to look up voucher", logging.ErrAttr(err), slog.Int("merchant_id", merchantID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toVoucherResponse(v))
+}
+
+func (h *HTTPHandler) redeem(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	merchantID := apitoken.MerchantIDFromCtx(r.Context())
+	code := chi.URLParam(r, "code")
+
+	v, err := h.s.Redeem(merchantID, code)
+	if err != nil {
+		if errors.Is(err, ErrVoucherDoesNotExist) {
+			oplog.Info("voucher not found", slog.Int("merchant_id", merchantID), slog.String("code", code))
+			w.TryWriteProblem(&oplog, http.StatusNotFound, codeVoucherDoesNotExist, "voucher not found")
+
+			return
+		}
+
+		if errors.Is(err, ErrVoucherAlreadyUsed) {
+			oplog.Info("voucher already used", slog.Int("merchant_id", merchantID), slog.String("code", code))
+
+			w.TryWriteProblem(&oplog, http.StatusConflict, codeVoucherAlreadyUsed, "voucher has already been used")
+
+			return
+		}
+
+		oplog.Error(
+			"failed to redeem voucher",
+			logging.ErrAttr(err),
+			slog.Int("merchant_id", merchantID),
+			slog.String("code", code),
+		)
+
+		w.TryWriteInternalError(&oplog)
+		return
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, toVoucherResponse(v))
+}
+
+func (h *HTTPHandler) getAuditTrail(w httputils.ResponseWriter, r *http.Request) {
+	oplog := httplog.LogEntry(r.Context())
+
+	merchantID := apitoken.MerchantIDFromCtx(r.Context())
+
+	entries, err := h.s.GetAuditTrail(merchantID)
+	if err != nil {
+		oplog.Error("failed to get audit trail", logging.ErrAttr(err), slog.Int("merchant_id", merchantID))
+		w.TryWriteInternalError(&oplog)
+
+		return
+	}
+
+	res := make([]auditEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, auditEntryResponse{
+			VoucherCode: e.VoucherCode,
+			Action:      string(e.Action),
+			Outcome:     string(e.Outcome),
+			CreatedAt:   e.CreatedAt,
+		})
+	}
+
+	w.TryWriteJSON(&oplog, http.StatusOK, res)
+}
+
+func toVoucherResponse(v *domain.Voucher) voucherResponse {
+	return voucherResponse{
+		Code:       v.Code.String(),
+		RewardName: v.RewardName,
+		Value:      v.Value,
+		IssuedAt:   v.IssuedAt,
+		UsedAt:     v.UsedAt,
+	}
+}
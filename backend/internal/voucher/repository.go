@@ -0,0 +1,19 @@
+package voucher
+
+import (
+	"time"
+
+	rewarddomain "github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher/domain"
+)
+
+type Repository interface {
+	// GetVoucher returns ErrVoucherDoesNotExist if no redemption has the given code.
+	GetVoucher(code rewarddomain.VoucherCode) (*domain.Voucher, error)
+	// UseVoucher marks the voucher as used by the merchant only if it hasn't been used yet.
+	// It reports whether this call was the one that used it.
+	UseVoucher(code rewarddomain.VoucherCode, merchantID int, usedAt time.Time) (bool, error)
+	AddAuditEntry(entry domain.AuditEntry) error
+	// GetAuditEntries returns the merchant's audit entries, newest first.
+	GetAuditEntries(merchantID int, limit int) ([]domain.AuditEntry, error)
+}
@@ -0,0 +1,155 @@
+package voucher
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	rewarddomain "github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher/domain"
+)
+
+// auditTrailLimit is how many of the latest audit entries a merchant can see.
+const auditTrailLimit = 100
+
+var (
+	ErrVoucherDoesNotExist = fmt.Errorf("voucher does not exist")
+	ErrVoucherAlreadyUsed  = fmt.Errorf("voucher has already been used")
+
+	// errVoucherNotUsable signals from inside the unit of work that the voucher couldn't be used,
+	// without saying why; redeemFailureReason works that out afterwards.
+	errVoucherNotUsable = fmt.Errorf("voucher is not usable")
+)
+
+type Service struct {
+	r   Repository
+	uow db.UnitOfWork[Repository]
+}
+
+func NewService(r Repository, uow db.UnitOfWork[Repository]) *Service {
+	return &Service{r: r, uow: uow}
+}
+
+// Lookup returns the voucher with the given code, used or not, and records the lookup in the merchant's audit trail.
+func (s *Service) Lookup(merchantID int, rawCode string) (*domain.Voucher, error) {
+	v, err := s.getVoucher(rawCode)
+
+	outcome := domain.AuditOutcomeOK
+	if errors.Is(err, ErrVoucherDoesNotExist) {
+		outcome = domain.AuditOutcomeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("Lookup(): %w", err)
+	}
+
+	entry := domain.NewAuditEntry(merchantID, rawCode, domain.AuditActionLookup, outcome, time.Now())
+	if auditErr := s.r.AddAuditEntry(entry); auditErr != nil {
+		return nil, fmt.Errorf("Lookup(): failed to add audit entry: %w", auditErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Lookup(): %w", err)
+	}
+
+	return v, nil
+}
+
+// Redeem uses up the voucher on behalf of the merchant and returns it. A voucher can only be used once;
+// of several concurrent attempts exactly one succeeds and the others get ErrVoucherAlreadyUsed.
+// Every attempt is recorded in the merchant's audit trail.
+func (s *Service) Redeem(merchantID int, rawCode string) (*domain.Voucher, error) {
+	code, err := rewarddomain.NewVoucherCode(rawCode)
+	if err != nil {
+		return nil, s.failRedeem(merchantID, rawCode, domain.AuditOutcomeNotFound, ErrVoucherDoesNotExist)
+	}
+
+	var v *domain.Voucher
+
+	// The successful audit entry is written in the same database transaction as the use,
+	// so a voucher is never used without a record of who used it.
+	err = s.uow.Do(func(r Repository) error {
+		now := time.Now()
+
+		ok, err := r.UseVoucher(code, merchantID, now)
+		if err != nil {
+			return fmt.Errorf("failed to use voucher: %w", err)
+		}
+
+		if !ok {
+			return errVoucherNotUsable
+		}
+
+		entry := domain.NewAuditEntry(merchantID, code.String(), domain.AuditActionRedeem, domain.AuditOutcomeOK, now)
+		if err = r.AddAuditEntry(entry); err != nil {
+			return fmt.Errorf("failed to add audit entry: %w", err)
+		}
+
+		v, err = r.GetVoucher(code)
+		if err != nil {
+			return fmt.Errorf("failed to get voucher: %w", err)
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errVoucherNotUsable) {
+		return nil, s.redeemFailureReason(merchantID, code)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Redeem(): %w", err)
+	}
+
+	return v, nil
+}
+
+// GetAuditTrail returns the merchant's latest voucher lookups and redemptions, newest first.
+func (s *Service) GetAuditTrail(merchantID int) ([]domain.AuditEntry, error) {
+	entries, err := s.r.GetAuditEntries(merchantID, auditTrailLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetAuditTrail(): failed to get audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *Service) getVoucher(rawCode string) (*domain.Voucher, error) {
+	code, err := rewarddomain.NewVoucherCode(rawCode)
+	if err != nil {
+		return nil, ErrVoucherDoesNotExist
+	}
+
+	v, err := s.r.GetVoucher(code)
+	if err != nil {
+		if errors.Is(err, ErrVoucherDoesNotExist) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("failed to get voucher: %w", err)
+	}
+
+	return v, nil
+}
+
+// redeemFailureReason explains why a voucher couldn't be used and records the failed attempt.
+func (s *Service) redeemFailureReason(merchantID int, code rewarddomain.VoucherCode) error {
+	_, err := s.r.GetVoucher(code)
+	if errors.Is(err, ErrVoucherDoesNotExist) {
+		return s.failRedeem(merchantID, code.String(), domain.AuditOutcomeNotFound, ErrVoucherDoesNotExist)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Redeem(): failed to get voucher: %w", err)
+	}
+
+	return s.failRedeem(merchantID, code.String(), domain.AuditOutcomeAlreadyUsed, ErrVoucherAlreadyUsed)
+}
+
+func (s *Service) failRedeem(merchantID int, rawCode string, outcome domain.AuditOutcome, reason error) error {
+	entry := domain.NewAuditEntry(merchantID, rawCode, domain.AuditActionRedeem, outcome, time.Now())
+	if err := s.r.AddAuditEntry(entry); err != nil {
+		return fmt.Errorf("Redeem(): failed to add audit entry: %w", err)
+	}
+
+	return fmt.Errorf("Redeem(): %w", reason)
+}
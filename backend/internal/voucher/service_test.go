@@ -0,0 +1,197 @@
+package voucher_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/apitoken"
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	"github.com/JosephJoshua/rvm/backend/internal/db/dbtest"
+	"github.com/JosephJoshua/rvm/backend/internal/merchant"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher/domain"
+)
+
+const (
+	voucherCode           = "7K2M-9XQ4-HT3V"
+	unknownVoucherCode    = "0000-0000-0000"
+	concurrentRedemptions = 20
+)
+
+func newService(dbHandle *db.DB) *voucher.Service {
+	return voucher.NewService(
+		voucher.NewSQLRepository(dbHandle),
+		db.NewSQLUnitOfWork(dbHandle, func(q db.Queryer) voucher.Repository {
+			return voucher.NewSQLRepository(q)
+		}),
+	)
+}
+
+// createVoucher redeems a reward for a new user, issuing voucherCode.
+func createVoucher(t *testing.T, dbHandle *db.DB) {
+	t.Helper()
+
+	const userID = "user-1"
+	dbtest.CreateUser(t, dbHandle, userID)
+
+	var rewardID int
+	if err := dbHandle.Get(&rewardID, `
+		INSERT INTO
+			rewards (name, cost)
+		VALUES
+			(?, ?)
+		RETURNING
+			reward_id
+	`, "Coffee", 50); err != nil {
+		t.Fatalf("failed to create reward: %v", err)
+	}
+
+	if _, err := dbHandle.Exec(`
+		INSERT INTO
+			redemptions (user_id, reward_id, cost, voucher_code, created_at)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`, userID, rewardID, 50, voucherCode, time.Now()); err != nil {
+		t.Fatalf("failed to create redemption: %v", err)
+	}
+}
+
+func createMerchant(t *testing.T, dbHandle *db.DB) int {
+	t.Helper()
+
+	s := merchant.NewService(merchant.NewSQLRepository(dbHandle), apitoken.NewRandomTokenGenerator())
+
+	m, err := s.CreateMerchant("Corner shop")
+	if err != nil {
+		t.Fatalf("failed to create merchant: %v", err)
+	}
+
+	return m.ID
+}
+
+func TestRedeem(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		code string
+		// usedBefore redeems the voucher once before the attempt.
+		usedBefore  bool
+		want        error
+		wantOutcome domain.AuditOutcome
+	}{
+		{name: "unused voucher", code: voucherCode, wantOutcome: domain.AuditOutcomeOK},
+		{name: "lower case with whitespace", code: " 7k2m-9xq4-ht3v\n", wantOutcome: domain.AuditOutcomeOK},
+		{
+			name:        "used voucher",
+			code:        voucherCode,
+			usedBefore:  true,
+			want:        voucher.ErrVoucherAlreadyUsed,
+			wantOutcome: domain.AuditOutcomeAlreadyUsed,
+		},
+		{
+			name:        "unknown code",
+			code:        unknownVoucherCode,
+			want:        voucher.ErrVoucherDoesNotExist,
+			wantOutcome: domain.AuditOutcomeNotFound,
+		},
+		{
+			name:        "malformed code",
+			code:        "not a code",
+			want:        voucher.ErrVoucherDoesNotExist,
+			wantOutcome: domain.AuditOutcomeNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbHandle := dbtest.Open(t)
+			s := newService(dbHandle)
+
+			createVoucher(t, dbHandle)
+			merchantID := createMerchant(t, dbHandle)
+
+			if tc.usedBefore {
+				if _, err := s.Redeem(merchantID, voucherCode); err != nil {
+					t.Fatalf("failed to redeem voucher beforehand: %v", err)
+				}
+			}
+
+			v, err := s.Redeem(merchantID, tc.code)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			if tc.want == nil && (!v.IsUsed() || v.UsedBy == nil || *v.UsedBy != merchantID) {
+				t.Errorf("got voucher used by %v, want merchant %d", v.UsedBy, merchantID)
+			}
+
+			entries, err := s.GetAuditTrail(merchantID)
+			if err != nil {
+				t.Fatalf("failed to get audit trail: %v", err)
+			}
+
+			if len(entries) == 0 || entries[0].Outcome != tc.wantOutcome {
+				t.Errorf("got audit trail %+v, want the latest entry to be %s", entries, tc.wantOutcome)
+			}
+		})
+	}
+}
+
+func TestRedeemConcurrently(t *testing.T) {
+	dbHandle := dbtest.Open(t)
+	s := newService(dbHandle)
+
+	createVoucher(t, dbHandle)
+	merchantID := createMerchant(t, dbHandle)
+
+	var wg sync.WaitGroup
+	redeemed := make(chan struct{}, concurrentRedemptions)
+	errs := make(chan error, concurrentRedemptions)
+
+	start := make(chan struct{})
+
+	for i := 0; i < concurrentRedemptions; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := s.Redeem(merchantID, voucherCode)
+			if err == nil {
+				redeemed <- struct{}{}
+				return
+			}
+
+			if !errors.Is(err, voucher.ErrVoucherAlreadyUsed) {
+				errs <- err
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+	close(redeemed)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("failed to redeem voucher: %v", err)
+	}
+
+	if len(redeemed) != 1 {
+		t.Fatalf("got %d successful redemptions, want 1", len(redeemed))
+	}
+
+	entries, err := s.GetAuditTrail(merchantID)
+	if err != nil {
+		t.Fatalf("failed to get audit trail: %v", err)
+	}
+
+	outcomes := make(map[domain.AuditOutcome]int)
+	for _, e := range entries {
+		outcomes[e.Outcome]++
+	}
+
+	if outcomes[domain.AuditOutcomeOK] != 1 || outcomes[domain.AuditOutcomeAlreadyUsed] != concurrentRedemptions-1 {
+		t.Errorf("got audit outcomes %v, want one ok and the rest already used", outcomes)
+	}
+}
@@ -0,0 +1,149 @@
+package voucher
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JosephJoshua/rvm/backend/internal/db"
+	rewarddomain "github.com/JosephJoshua/rvm/backend/internal/reward/domain"
+	"github.com/JosephJoshua/rvm/backend/internal/voucher/domain"
+)
+
+type voucher struct {
+	VoucherCode   string        `db:"voucher_code"`
+	RewardName    string        `db:"reward_name"`
+	Cost          int           `db:"cost"`
+	CreatedAt     time.Time     `db:"created_at"`
+	VoucherUsedAt sql.NullTime  `db:"voucher_used_at"`
+	VoucherUsedBy sql.NullInt64 `db:"voucher_used_by"`
+}
+
+type auditEntry struct {
+	VoucherAuditLogID int64     `db:"voucher_audit_log_id"`
+	MerchantID        int       `db:"merchant_id"`
+	VoucherCode       string    `db:"voucher_code"`
+	Action            string    `db:"action"`
+	Outcome           string    `db:"outcome"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+type SQLRepository struct {
+	db db.Queryer
+}
+
+func NewSQLRepository(q db.Queryer) *SQLRepository {
+	return &SQLRepository{db: q}
+}
+
+func (vr *SQLRepository) GetVoucher(code rewarddomain.VoucherCode) (*domain.Voucher, error) {
+	var raw voucher
+	if err := vr.db.Get(&raw, `
+		SELECT
+			redemptions.voucher_code,
+			rewards.name AS reward_name,
+			redemptions.cost,
+			redemptions.created_at,
+			redemptions.voucher_used_at,
+			redemptions.voucher_used_by
+		FROM
+			redemptions
+		INNER JOIN
+			rewards ON rewards.reward_id = redemptions.reward_id
+		WHERE
+			redemptions.voucher_code = ?
+	`, code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVoucherDoesNotExist
+		}
+
+		return nil, fmt.Errorf("GetVoucher(): failed to execute query: %w", err)
+	}
+
+	v := domain.Voucher{
+		Code:       rewarddomain.VoucherCode(raw.VoucherCode),
+		RewardName: raw.RewardName,
+		Value:      raw.Cost,
+		IssuedAt:   raw.CreatedAt,
+	}
+
+	if raw.VoucherUsedAt.Valid {
+		v.UsedAt = &raw.VoucherUsedAt.Time
+	}
+
+	if raw.VoucherUsedBy.Valid {
+		usedBy := int(raw.VoucherUsedBy.Int64)
+		v.UsedBy = &usedBy
+	}
+
+	return &v, nil
+}
+
+func (vr *SQLRepository) UseVoucher(code rewarddomain.VoucherCode, merchantID int, usedAt time.Time) (bool, error) {
+	// As with claiming transactions, the condition makes this a compare-and-swap
+	// so that a voucher can't be spent twice.
+	res, err := vr.db.Exec(`
+		UPDATE
+			redemptions
+		SET
+			voucher_used_at = ?,
+			voucher_used_by = ?
+		WHERE
+			voucher_code = ? AND voucher_used_at IS NULL
+	`, usedAt, merchantID, code)
+	if err != nil {
+		return false, fmt.Errorf("UseVoucher(): failed to execute query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("UseVoucher(): failed to get affected rows: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (vr *SQLRepository) AddAuditEntry(entry domain.AuditEntry) error {
+	if _, err := vr.db.Exec(`
+		INSERT INTO
+			voucher_audit_log (merchant_id, voucher_code, action, outcome, created_at)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`, entry.MerchantID, entry.VoucherCode, entry.Action, entry.Outcome, entry.CreatedAt); err != nil {
+		return fmt.Errorf("AddAuditEntry(): failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (vr *SQLRepository) GetAuditEntries(merchantID int, limit int) ([]domain.AuditEntry, error) {
+	var raw []auditEntry
+	if err := vr.db.Select(&raw, `
+		SELECT
+			voucher_audit_log_id, merchant_id, voucher_code, action, outcome, created_at
+		FROM
+			voucher_audit_log
+		WHERE
+			merchant_id = ?
+		ORDER BY
+			created_at DESC, voucher_audit_log_id DESC
+		LIMIT ?
+	`, merchantID, limit); err != nil {
+		return nil, fmt.Errorf("GetAuditEntries(): failed to execute query: %w", err)
+	}
+
+	entries := make([]domain.AuditEntry, 0, len(raw))
+	for _, e := range raw {
+		entries = append(entries, domain.AuditEntry{
+			ID:          e.VoucherAuditLogID,
+			MerchantID:  e.MerchantID,
+			VoucherCode: e.VoucherCode,
+			Action:      domain.AuditAction(e.Action),
+			Outcome:     domain.AuditOutcome(e.Outcome),
+			CreatedAt:   e.CreatedAt,
+		})
+	}
+
+	return entries, nil
+}
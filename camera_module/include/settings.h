@@ -9,19 +9,26 @@
 #define CAMERA_SIDE "top"
 
 #define THING_NAME "ESP32CAM-Camera_Module-Top"
+#define FIRMWARE_VERSION "0.3.0"
 #define INITIAL_AP_PASSWORD "123456789"
 
 /**
  * Configuration version number for this device.
  * Note: should be modified when the config structure changes.
  */
-#define CONFIG_VERSION "0.2"
+#define CONFIG_VERSION "0.3"
 #define CONFIG_STRING_MAX_LENGTH 256
 
 #define WIFI_STATUS_PIN LED_BUILTIN
 
 #define IMAGE_UPLOAD_CHUNK_SIZE 1024
 
+/**
+ * How often the module tells the backend it is still running.
+ * Note: the backend's MACHINE_HEARTBEAT_GRACE_PERIOD should be a few times longer.
+ */
+#define HEARTBEAT_INTERVAL_MS (60 * 1000)
+
 #define DATA_CAPTURE_MAX_ATTEMPTS 5
 #define MQTT_CONNECTION_ATTEMPT_INTERVAL_MS 1000
 #define MQTT_CAPTURE_REQUEST_TOPIC "capture/request"
@@ -57,6 +57,18 @@ iotwebconf::TextTParameter<CONFIG_STRING_MAX_LENGTH> image_classification_endpoi
     defaultValue("/image-classification").
     build();
 
+iotwebconf::TextTParameter<CONFIG_STRING_MAX_LENGTH> heartbeat_endpoint_param =
+    iotwebconf::Builder<iotwebconf::TextTParameter<CONFIG_STRING_MAX_LENGTH>>("heartbeat_endpoint").
+    label("Heartbeat endpoint").
+    defaultValue("/machines/heartbeat").
+    build();
+
+iotwebconf::PasswordTParameter<CONFIG_STRING_MAX_LENGTH> machine_token_param =
+    iotwebconf::Builder<iotwebconf::PasswordTParameter<CONFIG_STRING_MAX_LENGTH>>("machine_token").
+    label("Machine token (leave empty to disable heartbeats)").
+    defaultValue("").
+    build();
+
 iotwebconf::ParameterGroup mqtt_param_group = iotwebconf::ParameterGroup("mqtt_group", "MQTT");
 
 iotwebconf::TextTParameter<CONFIG_STRING_MAX_LENGTH> mqtt_host_param =
@@ -93,6 +105,7 @@ void handle_root();
 void initialize_camera();
 void update_camera_settings();
 void classify_image();
+void send_heartbeat();
 camera_fb_t *get_camera_snapshot();
 
 void initialize_mqtt();
@@ -158,6 +171,8 @@ void loop()
         is_builtin_led_on = false;
     }
 
+    send_heartbeat();
+
     std::visit(overloaded{
         [](const action::None &) { },
         [](const action::ClassifyImage &)
@@ -259,6 +274,50 @@ void classify_image()
     }
 }
 
+unsigned long last_heartbeat = 0;
+bool has_sent_heartbeat = false;
+
+void send_heartbeat()
+{
+    if (strlen(machine_token_param.value()) == 0)
+    {
+        return;
+    }
+
+    if (has_sent_heartbeat && millis() - last_heartbeat < HEARTBEAT_INTERVAL_MS)
+    {
+        return;
+    }
+
+    // Counted as an attempt even if it fails, so an unreachable backend doesn't stall the loop.
+    last_heartbeat = millis();
+    has_sent_heartbeat = true;
+
+    if (!http_client.connect(backend_server_hostname_param.value(), backend_server_port_param.value()))
+    {
+        log_e("[Heartbeat] Failed to connect to backend server at %s:%d", backend_server_hostname_param.value(), backend_server_port_param.value());
+        return;
+    }
+
+    String body = "firmware_version=" FIRMWARE_VERSION;
+    body += "&uptime_seconds=" + String(millis() / 1000);
+    body += "&free_heap_bytes=" + String(ESP.getFreeHeap());
+    body += "&wifi_rssi=" + String(WiFi.RSSI());
+
+    http_client.printf("POST %s HTTP/1.1\r\n", heartbeat_endpoint_param.value());
+    http_client.printf("Host: %s\r\n", backend_server_hostname_param.value());
+    http_client.printf("Authorization: Bearer %s\r\n", machine_token_param.value());
+    http_client.println("Content-Length: " + String(body.length()));
+    http_client.println("Content-Type: application/x-www-form-urlencoded");
+    http_client.println("User-Agent: ESP32CAM/Camera-Module");
+    http_client.println("Connection: close");
+    http_client.println();
+    http_client.print(body);
+
+    String response = read_http_response(&http_client, backend_server_timeout_param.value());
+    log_i("[Heartbeat] Response from server: %s", response.c_str());
+}
+
 void initialize_camera()
 {
     log_v("[Camera] Initializing..");
@@ -343,6 +402,8 @@ void initialize_iot_web_conf()
     backend_server_param_group.addItem(&backend_server_port_param);
     backend_server_param_group.addItem(&backend_server_timeout_param);
     backend_server_param_group.addItem(&image_classification_endpoint_param);
+    backend_server_param_group.addItem(&heartbeat_endpoint_param);
+    backend_server_param_group.addItem(&machine_token_param);
 
     mqtt_param_group.addItem(&mqtt_host_param);
     mqtt_param_group.addItem(&mqtt_port_param);
@@ -445,6 +506,12 @@ void handle_root()
 
     s += image_classification_endpoint_param.value();
 
+    s +=
+        "</li>"
+        "<li>Heartbeat endpoint: ";
+
+    s += heartbeat_endpoint_param.value();
+
     s +=
         "</li>"
         "<li>MQTT broker host: ";
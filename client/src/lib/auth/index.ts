@@ -5,8 +5,9 @@ import {
   createUserWithEmailAndPassword,
   getIdToken,
 } from 'firebase/auth';
-import axios, { AxiosError } from 'axios';
+import axios from 'axios';
 import { auth } from '../firebase';
+import { getProblem } from '../problem';
 
 const googleProvider = new GoogleAuthProvider();
 
@@ -22,11 +23,11 @@ export const registerUser = async () => {
   const url = new URL('/auth/register', import.meta.env.VITE_BACKEND_URL);
 
   return axios
-    .postForm(url.href, {
+    .post(url.href, {
       id_token: idToken,
     })
-    .catch((err: AxiosError) => {
-      if (err.status === 409) return;
+    .catch((err: unknown) => {
+      if (getProblem(err)?.code === 'user_already_exists') return;
       throw err;
     });
 };
@@ -0,0 +1,29 @@
+import { isAxiosError } from 'axios';
+
+/**
+ * An RFC 7807 problem, which the backend responds with whenever a request
+ * fails. `code` identifies the problem and never changes, unlike `detail`.
+ */
+export type Problem = {
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  code: string;
+};
+
+const isProblem = (data: unknown): data is Problem =>
+  typeof data === 'object' &&
+  data !== null &&
+  typeof (data as Problem).code === 'string';
+
+/**
+ * @returns the problem the backend responded with, or null if the request
+ * failed for another reason, e.g. the network being down.
+ */
+export const getProblem = (e: unknown): Problem | null => {
+  if (!isAxiosError(e)) return null;
+
+  const data = e.response?.data;
+  return isProblem(data) ? data : null;
+};
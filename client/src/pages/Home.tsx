@@ -17,8 +17,7 @@ const Home = () => {
       },
     });
 
-    const points = response.data;
-    setPoints(points);
+    setPoints(response.data.points);
   };
 
   const handleLogout = () => {
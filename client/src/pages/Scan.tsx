@@ -1,12 +1,50 @@
 import { Show, createSignal, onCleanup, onMount } from 'solid-js';
 import { BarcodeDetector } from 'barcode-detector';
-import axios, { AxiosError } from 'axios';
+import axios from 'axios';
 import LoadingIndicator from '../components/LoadingIndicator';
 import Modal from '../components/Modal';
 import { auth } from '../lib/firebase';
+import { getProblem } from '../lib/problem';
 
 const QR_CODE_PREFIX = 'greenwaste-rvm/transaction/';
 
+type ScanError = {
+  title: string;
+  message: string;
+};
+
+const getScanError = (e: unknown): ScanError => {
+  switch (getProblem(e)?.code) {
+    case 'transaction_does_not_exist':
+      return {
+        title: 'Invalid QR Code',
+        message: 'The QR code you provided is invalid. Please try again.',
+      };
+    case 'transaction_expired':
+      return {
+        title: 'Transaction expired',
+        message:
+          'This transaction has expired. Please start a new one at the machine.',
+      };
+    case 'transaction_already_assigned':
+      return {
+        title: 'Transaction already claimed',
+        message: 'The points for this transaction have already been claimed.',
+      };
+    case 'invalid_status_transition':
+      return {
+        title: 'Transaction not finished',
+        message:
+          'Please finish the transaction at the machine, then scan the QR code again.',
+      };
+    default:
+      return {
+        title: 'Something went wrong',
+        message: "We couldn't complete your transaction. Please try again.",
+      };
+  }
+};
+
 const Scan = () => {
   const barcodeDetector = new BarcodeDetector({ formats: ['qr_code'] });
 
@@ -14,7 +52,7 @@ const Scan = () => {
   let detectionInterval: number | undefined;
 
   const [isLoading, setIsLoading] = createSignal(false);
-  const [isInvalidCode, setIsInvalidCode] = createSignal(false);
+  const [scanError, setScanError] = createSignal<ScanError | null>(null);
 
   const [transactionPoints, setTransactionPoints] = createSignal<
     number | undefined
@@ -32,7 +70,7 @@ const Scan = () => {
         import.meta.env.VITE_BACKEND_URL,
       );
 
-      const response = await axios.postForm(
+      const response = await axios.post(
         url.href,
         {
           user_id: uid,
@@ -44,15 +82,10 @@ const Scan = () => {
         },
       );
 
-      const points = response.data;
-
-      setTransactionPoints(points);
+      setTransactionPoints(response.data.points);
       clearInterval(detectionInterval);
     } catch (e) {
-      if (e instanceof AxiosError) {
-        setIsInvalidCode(true);
-      }
-
+      setScanError(getScanError(e));
       throw e;
     } finally {
       setIsLoading(false);
@@ -61,7 +94,7 @@ const Scan = () => {
 
   const handleDetect = () => {
     if (video?.srcObject == null) return;
-    if (isLoading() || isInvalidCode()) return;
+    if (isLoading() || scanError() !== null) return;
 
     barcodeDetector.detect(video).then((codes) => {
       for (const code of codes) {
@@ -76,7 +109,7 @@ const Scan = () => {
   };
 
   const handleTryAgain = () => {
-    setIsInvalidCode(false);
+    setScanError(null);
   };
 
   onMount(() => {
@@ -119,13 +152,11 @@ const Scan = () => {
         </a>
       </div>
 
-      <Modal show={isInvalidCode()}>
+      <Modal show={scanError() !== null}>
         <div class="p-8">
-          <h2 class="text-2xl font-semibold mb-4">Invalid QR Code</h2>
+          <h2 class="text-2xl font-semibold mb-4">{scanError()?.title}</h2>
 
-          <p class="mb-6">
-            The QR code you provided is invalid. Please try again.
-          </p>
+          <p class="mb-6">{scanError()?.message}</p>
 
           <div class="flex justify-center items-center">
             <button
@@ -9,11 +9,21 @@ import {
   onMount,
 } from 'solid-js';
 import QrCode from './components/QrCode';
+import {
+  TransactionEvent,
+  subscribeToTransaction,
+} from './lib/transaction-events';
+
+const CLAIMED_MESSAGE_DURATION_MS = 5000;
 
 const App = () => {
   const [transaction, setTransaction] = createSignal<Transaction | null>(null);
   const [showQrCode, setShowQrCode] = createSignal<boolean>(false);
   const [isLoading, setIsLoading] = createSignal<boolean>(false);
+  const [claimedPoints, setClaimedPoints] = createSignal<number | null>(null);
+
+  let unsubscribe: (() => void) | undefined;
+  let resetTimeout: number | undefined;
 
   const transactionStarted = () => transaction() !== null;
   const transactionEmpty = () => transaction()?.itemCount === 0;
@@ -34,7 +44,7 @@ const App = () => {
       import.meta.env.VITE_BACKEND_URL,
     );
 
-    const response = await axios.postForm(
+    const response = await axios.post(
       url.href,
       {
         item_id: 1,
@@ -46,13 +56,50 @@ const App = () => {
       },
     );
 
-    const newCount = response.data;
     setTransaction({
       id: transactionId,
-      itemCount: newCount,
+      itemCount: response.data.item_count,
     });
   };
 
+  const resetTransaction = () => {
+    unsubscribe?.();
+    unsubscribe = undefined;
+
+    clearTimeout(resetTimeout);
+
+    setShowQrCode(false);
+    setTransaction(null);
+    setClaimedPoints(null);
+  };
+
+  const handleTransactionEvent = (
+    transactionId: string,
+    event: TransactionEvent,
+  ) => {
+    if (transaction()?.id !== transactionId) return;
+
+    switch (event.type) {
+      case 'snapshot':
+      case 'item_added':
+        setTransaction({ id: transactionId, itemCount: event.itemCount });
+        break;
+      case 'claimed':
+        setClaimedPoints(event.points);
+        resetTimeout = setTimeout(
+          resetTransaction,
+          CLAIMED_MESSAGE_DURATION_MS,
+        );
+        break;
+      case 'cancelled':
+      case 'expired':
+        resetTransaction();
+        break;
+      case 'closed':
+        break;
+    }
+  };
+
   const handleKeypress = (e: KeyboardEvent) => {
     if (e.ctrlKey && e.key === 'b') {
       handleAddItem();
@@ -71,19 +118,45 @@ const App = () => {
         },
       });
 
-      const transactionId = response.data;
+      const transactionId = response.data.transaction_id;
       setTransaction({
         id: transactionId,
         itemCount: 0,
       });
+
+      unsubscribe = subscribeToTransaction(transactionId, (event) =>
+        handleTransactionEvent(transactionId, event),
+      );
     } finally {
       setIsLoading(false);
     }
   };
 
-  const handleCancelTransaction = () => {
-    setShowQrCode(false);
-    setTransaction(null);
+  const changeTransactionStatus = async (
+    transactionId: string,
+    action: 'close' | 'cancel',
+  ) => {
+    const url = new URL(
+      `/transactions/${transactionId}/${action}`,
+      import.meta.env.VITE_BACKEND_URL,
+    );
+
+    await axios.post(url.href, null, {
+      headers: {
+        Authorization: `Bearer ${import.meta.env.VITE_BACKEND_TOKEN}`,
+      },
+    });
+  };
+
+  const handleCancelTransaction = async () => {
+    const tr = transaction();
+    if (tr === null) return;
+
+    try {
+      await changeTransactionStatus(tr.id, 'cancel');
+    } finally {
+      resetTransaction();
+    }
   };
 
   const handleEndTransaction = async () => {
@@ -91,10 +164,11 @@ const App = () => {
     if (tr === null) return;
 
     if (tr.itemCount === 0) {
-      setTransaction(null);
+      await handleCancelTransaction();
       return;
     }
 
+    await changeTransactionStatus(tr.id, 'close');
     setShowQrCode(true);
   };
 
@@ -104,6 +178,7 @@ const App = () => {
 
   onCleanup(() => {
     document.removeEventListener('keypress', handleKeypress);
+    resetTransaction();
   });
 
   return (
@@ -113,9 +188,20 @@ const App = () => {
       </h1>
 
       <div class="flex flex-col items-center gap-6">
+        <Show when={claimedPoints() !== null}>
+          <div class="font-medium text-lg text-center">
+            Claimed! <strong>{claimedPoints()} points</strong> have been added
+            to the user's account. Thank you for recycling.
+          </div>
+        </Show>
+
         <Show
           when={!showQrCode()}
-          fallback={<QrCode text={transactionQrText()} />}
+          fallback={
+            <Show when={claimedPoints() === null}>
+              <QrCode text={transactionQrText()} />
+            </Show>
+          }
         >
           <div
             class="font-medium text-lg"
@@ -157,7 +243,11 @@ const App = () => {
             </button>
           </Match>
 
-          <Match when={transactionStarted() && showQrCode()}>
+          <Match
+            when={
+              transactionStarted() && showQrCode() && claimedPoints() === null
+            }
+          >
             <button
               type="button"
               class="transition duration-300 bg-red-600 text-white px-12 py-2 rounded-md font-medium text-lg hover:-translate-y-0.5"
@@ -0,0 +1,145 @@
+export type TransactionStatus =
+  | 'open'
+  | 'closed'
+  | 'claimed'
+  | 'cancelled'
+  | 'expired';
+
+export type TransactionEvent =
+  | { type: 'snapshot'; status: TransactionStatus; itemCount: number }
+  | { type: 'item_added'; itemId: number; itemCount: number }
+  | { type: 'closed' }
+  | { type: 'cancelled' }
+  | { type: 'claimed'; userId: string; points: number }
+  | { type: 'expired' };
+
+const RECONNECT_DELAY_MS = 1000;
+
+const TERMINAL_STATUSES: TransactionStatus[] = [
+  'claimed',
+  'cancelled',
+  'expired',
+];
+
+const isTerminal = (event: TransactionEvent) => {
+  if (event.type === 'snapshot') {
+    return TERMINAL_STATUSES.includes(event.status);
+  }
+
+  return TERMINAL_STATUSES.includes(event.type as TransactionStatus);
+};
+
+const parseEvent = (type: string, data: string): TransactionEvent | null => {
+  const payload = JSON.parse(data);
+
+  switch (type) {
+    case 'snapshot':
+      return {
+        type,
+        status: payload.status,
+        itemCount: payload.item_count,
+      };
+    case 'item_added':
+      return {
+        type,
+        itemId: payload.item_id,
+        itemCount: payload.item_count,
+      };
+    case 'claimed':
+      return { type, userId: payload.user_id, points: payload.points };
+    case 'closed':
+    case 'cancelled':
+    case 'expired':
+      return { type };
+    default:
+      return null;
+  }
+};
+
+/**
+ * Follows what happens to the transaction as it happens, reconnecting until the
+ * transaction is claimed, cancelled or expired. EventSource can't send the
+ * machine token, so the stream is read with fetch instead.
+ *
+ * @returns a function that stops following the transaction.
+ */
+export const subscribeToTransaction = (
+  transactionId: string,
+  onEvent: (event: TransactionEvent) => void,
+) => {
+  const controller = new AbortController();
+
+  const url = new URL(
+    `/transactions/${transactionId}/events`,
+    import.meta.env.VITE_BACKEND_URL,
+  );
+
+  // Reports whether the transaction reached a terminal status.
+  const readStream = async () => {
+    const response = await fetch(url.href, {
+      headers: {
+        Accept: 'text/event-stream',
+        Authorization: `Bearer ${import.meta.env.VITE_BACKEND_TOKEN}`,
+      },
+      signal: controller.signal,
+    });
+
+    if (!response.ok || response.body === null) {
+      throw new Error(`failed to follow transaction: ${response.status}`);
+    }
+
+    const reader = response.body
+      .pipeThrough(new TextDecoderStream())
+      .getReader();
+
+    let buffer = '';
+
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) return false;
+
+      buffer += value;
+
+      let end = buffer.indexOf('\n\n');
+      while (end !== -1) {
+        const message = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+        end = buffer.indexOf('\n\n');
+
+        let type = '';
+        let data = '';
+
+        for (const line of message.split('\n')) {
+          if (line.startsWith('event:')) type = line.slice(6).trim();
+          if (line.startsWith('data:')) data = line.slice(5).trim();
+        }
+
+        // Keep-alive comments have neither.
+        if (type === '' || data === '') continue;
+
+        const event = parseEvent(type, data);
+        if (event === null) continue;
+
+        onEvent(event);
+        if (isTerminal(event)) return true;
+      }
+    }
+  };
+
+  const follow = async () => {
+    while (!controller.signal.aborted) {
+      try {
+        if (await readStream()) return;
+      } catch (e) {
+        if (controller.signal.aborted) return;
+        console.error(e);
+      }
+
+      await new Promise((resolve) => setTimeout(resolve, RECONNECT_DELAY_MS));
+    }
+  };
+
+  follow();
+
+  return () => controller.abort();
+};